@@ -0,0 +1,128 @@
+package azure
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Field is a customMetrics column that holds a metric's value
+// Application Insights pre-aggregates metrics so a row may summarize several values
+type Field string
+
+// Fields
+const (
+	// FieldValue is the (single) value of a metric (the default)
+	FieldValue Field = "value"
+	FieldSum   Field = "valueSum"
+	FieldCount Field = "valueCount"
+	FieldMin   Field = "valueMin"
+	FieldMax   Field = "valueMax"
+)
+
+// defaultWindow allows for Application Insights' ingestion latency
+const defaultWindow = 5 * time.Minute
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from Application Insights' customMetrics
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+// The App ID and API key default to the values of environment variables APPINSIGHTS_APP_ID and APPINSIGHTS_API_KEY
+func NewImporter(o Options) (*Importer, error) {
+	if o.AppID == "" {
+		o.AppID = os.Getenv("APPINSIGHTS_APP_ID")
+	}
+	if o.APIKey == "" {
+		o.APIKey = os.Getenv("APPINSIGHTS_API_KEY")
+	}
+	if o.AppID == "" || (o.APIKey == "" && o.Token == "") {
+		return nil, errors.New("Expect Application Insights App ID and either an API key or a token")
+	}
+	switch o.Field {
+	case "":
+		o.Field = FieldValue
+	case FieldValue, FieldSum, FieldCount, FieldMin, FieldMax:
+	default:
+		return nil, errors.New("Unknown Field")
+	}
+	return &Importer{
+		name:    "azure",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+// The View's label names are matched against the metric's custom dimensions
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return Point{}, errors.New("Inconsistency between labels and values")
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+
+	q := NewQuery(i.options.MetricPrefix + v.Name)
+	for j, labelName := range v.LabelNames {
+		q.AddDimension(labelName, labelValues[j])
+	}
+	q.SetField(i.options.Field)
+	q.SetTimespan(t.Add(-window), t)
+	log.Println(q.String())
+
+	tables, err := i.query(q)
+	if err != nil {
+		return Point{}, err
+	}
+	points, err := toPoints(tables[0], i.options.Field)
+	if err != nil {
+		return Point{}, err
+	}
+	if len(points) == 0 {
+		return Point{}, errors.New("No metrics match the query")
+	}
+	// The query returns the most recent row
+	return points[0], nil
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// AppID identifies the Application Insights resource; defaults to environment variable APPINSIGHTS_APP_ID
+	AppID string
+	// APIKey authenticates with the query API; defaults to environment variable APPINSIGHTS_API_KEY
+	APIKey string
+	// Token is an Azure AD bearer token; when set, it's used instead of the APIKey
+	Token string
+	// MetricPrefix is prepended to the View's name to give the customMetrics name
+	MetricPrefix string
+	// Field is the customMetrics column that's returned; defaults to FieldValue
+	Field Field
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 5 minutes
+	Window time.Duration
+	// BaseURL overrides the query API endpoint; defaults to environment variable APPINSIGHTS_ENDPOINT and then https://api.applicationinsights.io
+	BaseURL string
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
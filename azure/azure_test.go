@@ -0,0 +1,169 @@
+package azure
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/azure/azuretest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	appID  = "app"
+	apiKey = "key"
+)
+
+// newTestImporter creates an Importer that talks to an azuretest.Server
+func newTestImporter(t *testing.T, s *azuretest.Server, o Options) *Importer {
+	o.AppID = appID
+	o.APIKey = apiKey
+	o.BaseURL = s.URL()
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("No App ID", func(t *testing.T) {
+		os.Unsetenv("APPINSIGHTS_APP_ID")
+		os.Unsetenv("APPINSIGHTS_API_KEY")
+		if _, err := NewImporter(Options{APIKey: apiKey}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Credentials", func(t *testing.T) {
+		os.Unsetenv("APPINSIGHTS_API_KEY")
+		if _, err := NewImporter(Options{AppID: appID}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unknown Field", func(t *testing.T) {
+		if _, err := NewImporter(Options{AppID: appID, APIKey: apiKey, Field: "X"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("With Options", func(t *testing.T) {
+		i, err := NewImporter(Options{AppID: appID, Token: "token"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "azure"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Field, FieldValue; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := azuretest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		t       time.Time
+		query   string
+		rows    []azuretest.Row
+		want    float64
+	}{
+		{
+			"Latest",
+			Options{},
+			now,
+			"customMetrics\n| where timestamp between (datetime(2019-01-02T02:59:05Z) .. datetime(2019-01-02T03:04:05Z))\n| where name == 'counter0'\n| where tostring(customDimensions['key1']) == 'value1'\n| where tostring(customDimensions['key2']) == 'value2'\n| top 1 by timestamp desc\n| project timestamp, value",
+			[]azuretest.Row{{Timestamp: now.Add(-time.Minute), Value: 2}},
+			2,
+		},
+		{
+			"MetricPrefix",
+			Options{MetricPrefix: "namespace/"},
+			now,
+			"customMetrics\n| where timestamp between (datetime(2019-01-02T02:59:05Z) .. datetime(2019-01-02T03:04:05Z))\n| where name == 'namespace/counter0'\n| where tostring(customDimensions['key1']) == 'value1'\n| where tostring(customDimensions['key2']) == 'value2'\n| top 1 by timestamp desc\n| project timestamp, value",
+			[]azuretest.Row{{Timestamp: now.Add(-30 * time.Second), Value: 5}},
+			5,
+		},
+		{
+			// The stand-in doesn't evaluate the query so the window is checked by the query's between (...) text
+			"Window",
+			Options{Window: time.Minute},
+			now.Add(-90 * time.Second),
+			"customMetrics\n| where timestamp between (datetime(2019-01-02T03:01:35Z) .. datetime(2019-01-02T03:02:35Z))\n| where name == 'counter0'\n| where tostring(customDimensions['key1']) == 'value1'\n| where tostring(customDimensions['key2']) == 'value2'\n| top 1 by timestamp desc\n| project timestamp, value",
+			[]azuretest.Row{{Timestamp: now.Add(-100 * time.Second), Value: 3}},
+			3,
+		},
+		{
+			"Field",
+			Options{Field: FieldCount},
+			now,
+			"customMetrics\n| where timestamp between (datetime(2019-01-02T02:59:05Z) .. datetime(2019-01-02T03:04:05Z))\n| where name == 'counter0'\n| where tostring(customDimensions['key1']) == 'value1'\n| where tostring(customDimensions['key2']) == 'value2'\n| top 1 by timestamp desc\n| project timestamp, valueCount",
+			[]azuretest.Row{{Timestamp: now.Add(-time.Minute), Value: 1}},
+			1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			s.SetResult(test.query, string(i.options.Field), test.rows...)
+			p, err := i.Point(v, []string{"value1", "value2"}, test.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Value; got != test.want {
+				t.Errorf("got %f; want %f", got, test.want)
+			}
+			queries := s.Queries()
+			if got := queries[len(queries)-1]; got != test.query {
+				t.Errorf("got %s; want %s", got, test.query)
+			}
+		})
+	}
+	t.Run("No Metrics", func(t *testing.T) {
+		s.SetResult("customMetrics\n| where timestamp between (datetime(2019-01-02T02:59:05Z) .. datetime(2019-01-02T03:04:05Z))\n| where name == 'counter0'\n| where tostring(customDimensions['key1']) == 'value1'\n| where tostring(customDimensions['key2']) == 'value9'\n| top 1 by timestamp desc\n| project timestamp, value", "value")
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value9"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Inconsistent Labels", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Credentials", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+			t.Fatal(err)
+		}
+		headers := s.Headers()
+		if got, want := headers[len(headers)-1].Get("x-api-key"), apiKey; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		i = newTestImporter(t, s, Options{Token: "token"})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+			t.Fatal(err)
+		}
+		headers = s.Headers()
+		if got, want := headers[len(headers)-1].Get("Authorization"), "Bearer token"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusServiceUnavailable)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package azuretest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for the Application Insights query API (/v1/apps/[app]/query)
+// It returns the results seeded for each exact Kusto query
+type Server struct {
+	*standin.Server
+}
+
+// NewServer creates and starts a new Server with no results
+func NewServer() *Server {
+	return &Server{standin.NewServer(query)}
+}
+
+// query returns the request's Kusto query: the JSON body's "query" (POST) or the "query" parameter (GET)
+func query(r *http.Request) (string, error) {
+	if !strings.HasSuffix(r.URL.Path, "/query") {
+		return "", errors.New("Path not found")
+	}
+	if r.Method == http.MethodPost {
+		var body struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return "", err
+		}
+		return body.Query, nil
+	}
+	if q := r.URL.Query().Get("query"); q != "" {
+		return q, nil
+	}
+	return "", errors.New("Missing required parameter 'query'")
+}
+
+// Row represents a customMetrics row projected to its timestamp and a field
+type Row struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// SetResult seeds the result of a query; its table has columns "timestamp" and the field
+func (s *Server) SetResult(query, field string, rows ...Row) {
+	type column struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	table := struct {
+		Name    string          `json:"name"`
+		Columns []column        `json:"columns"`
+		Rows    [][]interface{} `json:"rows"`
+	}{
+		Name:    "PrimaryResult",
+		Columns: []column{{"timestamp", "datetime"}, {field, "real"}},
+		Rows:    [][]interface{}{},
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, []interface{}{row.Timestamp.UTC().Format(time.RFC3339Nano), row.Value})
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"tables": []interface{}{table},
+	})
+	s.SetResponse(query, "application/json", string(b))
+}
@@ -0,0 +1,47 @@
+package azuretest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_SetResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	query := "customMetrics\n| where name == 'counter0'\n| project timestamp, value"
+	s.SetResult(query, "value", Row{time.Unix(1546398185, 0), 1.5})
+	post := func(body string) (int, string) {
+		resp, err := s.Client().Post(s.URL()+"/v1/apps/app/query", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	t.Run("Result", func(t *testing.T) {
+		code, body := post(`{"query":"customMetrics\n| where name == 'counter0'\n| project timestamp, value"}`)
+		if got, want := code, http.StatusOK; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := body, `{"tables":[{"name":"PrimaryResult","columns":[{"name":"timestamp","type":"datetime"},{"name":"value","type":"real"}],"rows":[["2019-01-02T03:03:05Z",1.5]]}]}`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Unexpected Query", func(t *testing.T) {
+		code, _ := post(`{"query":"customMetrics"}`)
+		if got, want := code, http.StatusBadRequest; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	if got, want := strings.Join(s.Queries(), "\n"), query+"\ncustomMetrics"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// defaultBaseURL is the Application Insights query API
+	defaultBaseURL = "https://api.applicationinsights.io"
+)
+
+// Table represents a table in a query API response
+type Table struct {
+	Name    string          `json:"name"`
+	Columns []Column        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Column represents a column of a Table
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// response represents the query API's response
+type response struct {
+	Tables []Table `json:"tables"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// baseURL returns the query API's base URL; BaseURL takes precedence over environment variable APPINSIGHTS_ENDPOINT
+func baseURL(o Options) string {
+	if o.BaseURL != "" {
+		return strings.TrimSuffix(o.BaseURL, "/")
+	}
+	if endpoint := os.Getenv("APPINSIGHTS_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+	return defaultBaseURL
+}
+
+// query sends the Query to the Application Insights query API and returns the result's tables
+func (i *Importer) query(q *Query) ([]Table, error) {
+	body, err := json.Marshal(map[string]string{
+		"query":    q.String(),
+		"timespan": q.Timespan(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/v1/apps/%s/query", baseURL(i.options), i.options.AppID)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if i.options.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+i.options.Token)
+	} else {
+		req.Header.Set("x-api-key", i.options.APIKey)
+	}
+
+	client := i.options.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("Unable to decode response (%s): %s", resp.Status, err)
+	}
+	if r.Error != nil {
+		return nil, fmt.Errorf("Query failed (%s): %s: %s", resp.Status, r.Error.Code, r.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Query failed (%s)", resp.Status)
+	}
+	if len(r.Tables) == 0 {
+		return nil, errors.New("Query returned no tables")
+	}
+	return r.Tables, nil
+}
@@ -0,0 +1,55 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Point represents a customMetrics value and its timestamp
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// toPoints converts the rows of a Table (with "timestamp" and field columns) to Points, skipping null values
+func toPoints(t Table, field Field) ([]Point, error) {
+	ts, value := -1, -1
+	for j, column := range t.Columns {
+		switch column.Name {
+		case "timestamp":
+			ts = j
+		case string(field):
+			value = j
+		}
+	}
+	if ts < 0 || value < 0 {
+		return nil, fmt.Errorf("Table '%s' requires columns 'timestamp' and '%s'", t.Name, field)
+	}
+	points := []Point{}
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return nil, errors.New("Inconsistency between columns and row")
+		}
+		if row[value] == nil {
+			continue
+		}
+		s, ok := row[ts].(string)
+		if !ok {
+			return nil, fmt.Errorf("Invalid timestamp '%v'", row[ts])
+		}
+		when, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := row[value].(float64)
+		if !ok {
+			return nil, fmt.Errorf("Invalid value '%v'", row[value])
+		}
+		points = append(points, Point{
+			Time:  when,
+			Value: f,
+		})
+	}
+	return points, nil
+}
@@ -0,0 +1,46 @@
+package azure
+
+import (
+	"testing"
+)
+
+func Test_toPoints(t *testing.T) {
+	table := Table{
+		Name: "PrimaryResult",
+		Columns: []Column{
+			{Name: "timestamp", Type: "datetime"},
+			{Name: "valueSum", Type: "real"},
+		},
+		Rows: [][]interface{}{
+			{"2019-01-02T03:04:05.5Z", 42.0},
+			{"2019-01-02T03:03:05Z", nil},
+		},
+	}
+	t.Run("Points", func(t *testing.T) {
+		points, err := toPoints(table, FieldSum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(points), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := points[0].Value, 42.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := points[0].Time.Nanosecond(), 500000000; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Missing Column", func(t *testing.T) {
+		if _, err := toPoints(table, FieldValue); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Invalid Timestamp", func(t *testing.T) {
+		invalid := table
+		invalid.Rows = [][]interface{}{{"yesterday", 1.0}}
+		if _, err := toPoints(invalid, FieldSum); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
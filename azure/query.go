@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query represents a Kusto query for the most recent value of a metric (with a set of custom dimensions) in Application Insights' customMetrics table
+type Query struct {
+	metric     string
+	dimensions map[string]string
+	field      Field
+	from       time.Time
+	to         time.Time
+}
+
+// NewQuery creates a Query for the metric's value
+func NewQuery(metric string) *Query {
+	if metric == "" {
+		log.Fatal("[NewQuery] Unable to create query for a metric with no name (\"\")")
+	}
+	return &Query{
+		metric:     metric,
+		dimensions: make(map[string]string),
+		field:      FieldValue,
+	}
+}
+
+// AddDimension restricts the Query to metrics with the custom dimension's value
+func (q *Query) AddDimension(key, value string) {
+	if key != "" {
+		q.dimensions[key] = value
+	}
+}
+
+// SetField sets the customMetrics column that's returned
+func (q *Query) SetField(field Field) {
+	if field != "" {
+		q.field = field
+	}
+}
+
+// SetTimespan restricts the Query to metrics with timestamps between from and to
+func (q *Query) SetTimespan(from, to time.Time) {
+	q.from = from
+	q.to = to
+}
+
+// Timespan returns the Query's timespan in ISO 8601 form ("[from]/[to]") or "" if none has been set
+func (q *Query) Timespan() string {
+	if q.from.IsZero() || q.to.IsZero() {
+		return ""
+	}
+	return q.from.UTC().Format(time.RFC3339) + "/" + q.to.UTC().Format(time.RFC3339)
+}
+
+// String returns the Query in Kusto Query Language
+// Custom dimensions are sorted so that the Query is deterministic
+func (q *Query) String() string {
+	lines := []string{"customMetrics"}
+	if !q.from.IsZero() && !q.to.IsZero() {
+		lines = append(lines, "where timestamp between (datetime("+q.from.UTC().Format(time.RFC3339)+") .. datetime("+q.to.UTC().Format(time.RFC3339)+"))")
+	}
+	lines = append(lines, "where name == "+Quote(q.metric))
+	keys := make([]string, 0, len(q.dimensions))
+	for key := range q.dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, "where tostring(customDimensions["+Quote(key)+"]) == "+Quote(q.dimensions[key]))
+	}
+	lines = append(lines,
+		"top 1 by timestamp desc",
+		"project timestamp, "+string(q.field),
+	)
+	return strings.Join(lines, "\n| ")
+}
+
+// Quote returns the string as a (single-quoted) Kusto string literal
+func Quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+}
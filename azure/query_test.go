@@ -0,0 +1,51 @@
+package azure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuery_String(t *testing.T) {
+	t.Run("Metric", func(t *testing.T) {
+		q := NewQuery("counter0")
+		want := "customMetrics\n| where name == 'counter0'\n| top 1 by timestamp desc\n| project timestamp, value"
+		if got := q.String(); got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Dimensions and Timespan", func(t *testing.T) {
+		q := NewQuery("counter0")
+		q.AddDimension("key2", "value2")
+		q.AddDimension("key1", "value1")
+		q.SetField(FieldSum)
+		to := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+		q.SetTimespan(to.Add(-time.Minute), to)
+		want := "customMetrics" +
+			"\n| where timestamp between (datetime(2019-01-02T03:03:05Z) .. datetime(2019-01-02T03:04:05Z))" +
+			"\n| where name == 'counter0'" +
+			"\n| where tostring(customDimensions['key1']) == 'value1'" +
+			"\n| where tostring(customDimensions['key2']) == 'value2'" +
+			"\n| top 1 by timestamp desc" +
+			"\n| project timestamp, valueSum"
+		if got := q.String(); got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := q.Timespan(), "2019-01-02T03:03:05Z/2019-01-02T03:04:05Z"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func Test_Quote(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want string
+	}{
+		{"value1", `'value1'`},
+		{"it's", `'it\'s'`},
+		{`a\b`, `'a\\b'`},
+	} {
+		if got := Quote(test.s); got != test.want {
+			t.Errorf("got %s; want %s", got, test.want)
+		}
+	}
+}
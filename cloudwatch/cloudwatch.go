@@ -0,0 +1,143 @@
+package cloudwatch
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from CloudWatch
+type Importer struct {
+	name    string
+	options Options
+	client  cloudwatchiface.CloudWatchAPI
+}
+
+// NewImporter creates a new importer using the Options provided
+// Unless Options say otherwise, the region and credentials are those of the default AWS session (e.g. AWS_REGION, AWS_PROFILE)
+func NewImporter(o Options) (*Importer, error) {
+	if o.Namespace == "" {
+		return nil, errors.New("Expect a CloudWatch Namespace")
+	}
+	if o.Statistic == "" {
+		o.Statistic = cloudwatch.StatisticAverage
+	}
+	if !validStatistic(o.Statistic) {
+		return nil, errors.New("Unknown Statistic")
+	}
+	if o.Period == 0 {
+		o.Period = defaultPeriod
+	}
+	if !validPeriod(o.Period) {
+		return nil, errors.New("Period must be 1, 5, 10 or 30 seconds or a multiple of 60 seconds")
+	}
+
+	config := aws.NewConfig()
+	if o.Region != "" {
+		config = config.WithRegion(o.Region)
+	}
+	if o.Endpoint != "" {
+		config = config.WithEndpoint(o.Endpoint)
+	}
+	if o.Credentials != nil {
+		config = config.WithCredentials(o.Credentials)
+	}
+	if o.HTTPClient != nil {
+		config = config.WithHTTPClient(o.HTTPClient)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Importer{
+		name:    "cloudwatch",
+		options: o,
+		client:  cloudwatch.New(sess),
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+// CloudWatch identifies metrics by all of their dimensions so the View's label names must match the dimensions exactly
+// A result that failed (e.g. InternalError) is an error; PartialData results are completed by reading every page
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	input, err := i.input(v, labelValues, t)
+	if err != nil {
+		return Point{}, err
+	}
+	log.Println(input.String())
+
+	points := []Point{}
+	var resultErr error
+	err = i.client.GetMetricDataPages(input, func(output *cloudwatch.GetMetricDataOutput, last bool) bool {
+		for _, r := range output.MetricDataResults {
+			ps, err := toPoints(r)
+			if err != nil {
+				// Stop paging; the first error is returned
+				resultErr = err
+				return false
+			}
+			points = append(points, ps...)
+		}
+		return true
+	})
+	if err != nil {
+		return Point{}, err
+	}
+	if resultErr != nil {
+		return Point{}, resultErr
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No data points match the query")
+	}
+	return points[j], nil
+}
+
+// defaultPeriod is CloudWatch's standard resolution
+const defaultPeriod = time.Minute
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// Namespace is the CloudWatch namespace of the metrics (required)
+	Namespace string
+	// MetricPrefix is prepended to the View's name to give the CloudWatch metric name
+	MetricPrefix string
+	// Statistic is the statistic that's read (Average, Sum, Minimum, Maximum or SampleCount); defaults to Average
+	Statistic string
+	// Period is the granularity of the statistic; defaults to 60 seconds
+	Period time.Duration
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 5 Periods
+	Window time.Duration
+	// Region, Endpoint, Credentials and HTTPClient override those of the default AWS session (e.g. to use a test server)
+	Region      string
+	Endpoint    string
+	Credentials *credentials.Credentials
+	HTTPClient  *http.Client
+}
@@ -0,0 +1,182 @@
+package cloudwatch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/dazwilkin/opencensus/cloudwatch/cloudwatchtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	namespace = "OpenCensus"
+)
+
+// newTestImporter creates an Importer that talks to a cloudwatchtest.Server
+func newTestImporter(t *testing.T, s *cloudwatchtest.Server, o Options) *Importer {
+	o.Namespace = namespace
+	o.Region = "us-east-1"
+	o.Endpoint = s.URL()
+	o.Credentials = credentials.NewStaticCredentials("id", "secret", "")
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("No Namespace", func(t *testing.T) {
+		if _, err := NewImporter(Options{}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unknown Statistic", func(t *testing.T) {
+		if _, err := NewImporter(Options{Namespace: namespace, Statistic: "p99"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Invalid Period", func(t *testing.T) {
+		if _, err := NewImporter(Options{Namespace: namespace, Period: 90 * time.Second}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("With Options", func(t *testing.T) {
+		i, err := NewImporter(Options{Namespace: namespace, Region: "us-east-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "cloudwatch"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Statistic, cloudwatch.StatisticAverage; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Period, time.Minute; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := cloudwatchtest.NewServer()
+	defer s.Close()
+
+	now := time.Now().Truncate(time.Minute)
+	dimensions := map[string]string{"key1": "value1", "key2": "value2"}
+	s.AddDatum(
+		cloudwatchtest.NewDatum(namespace, "counter0", dimensions, now.Add(-3*time.Minute), 1),
+		cloudwatchtest.NewDatum(namespace, "counter0", dimensions, now.Add(-2*time.Minute), 2),
+		cloudwatchtest.NewDatum(namespace, "counter0", dimensions, now.Add(-2*time.Minute+10*time.Second), 4),
+		cloudwatchtest.NewDatum(namespace, "counter0", map[string]string{"key1": "value1"}, now.Add(-time.Minute), 9),
+	)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		statistic string
+		want      float64
+	}{
+		{cloudwatch.StatisticAverage, 3},
+		{cloudwatch.StatisticSum, 6},
+		{cloudwatch.StatisticMinimum, 2},
+		{cloudwatch.StatisticMaximum, 4},
+		{cloudwatch.StatisticSampleCount, 2},
+	} {
+		t.Run(test.statistic, func(t *testing.T) {
+			i := newTestImporter(t, s, Options{Statistic: test.statistic})
+			got, err := i.Value(v, []string{"value1", "value2"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %f; want %f", got, test.want)
+			}
+		})
+	}
+	t.Run("Period", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{Statistic: cloudwatch.StatisticSum, Period: 5 * time.Minute})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+			t.Fatal(err)
+		}
+		requests := s.Requests()
+		if got, want := requests[len(requests)-1].Get("MetricDataQueries.member.1.MetricStat.Period"), "300"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Dimensions", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		got, err := i.Value(&view.View{Name: "counter0", LabelNames: []string{"key1"}}, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 9.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("No Data", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value9"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("InternalError", func(t *testing.T) {
+		s.SetResultStatus(cloudwatch.StatusCodeInternalError)
+		defer s.SetResultStatus("")
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("PartialData", func(t *testing.T) {
+		s.SetResultStatus(cloudwatch.StatusCodePartialData)
+		defer s.SetResultStatus("")
+		i := newTestImporter(t, s, Options{Statistic: cloudwatch.StatisticSum})
+		got, err := i.Value(v, []string{"value1", "value2"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 6.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusBadRequest)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_RoundTrip(t *testing.T) {
+	s := cloudwatchtest.NewServer()
+	defer s.Close()
+	i := newTestImporter(t, s, Options{MetricPrefix: "namespace_", Statistic: cloudwatch.StatisticMaximum})
+
+	now := time.Now()
+	_, err := i.client.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace: aws.String(namespace),
+		MetricData: []*cloudwatch.MetricDatum{{
+			MetricName: aws.String("namespace_counter0"),
+			Dimensions: []*cloudwatch.Dimension{{Name: aws.String("key1"), Value: aws.String("value1")}},
+			Timestamp:  aws.Time(now.Add(-time.Minute)),
+			Value:      aws.Float64(42),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := i.Value(&view.View{Name: "counter0", LabelNames: []string{"key1"}}, []string{"value1"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 42.0; got != want {
+		t.Errorf("got %f; want %f", got, want)
+	}
+}
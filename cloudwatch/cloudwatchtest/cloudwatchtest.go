@@ -0,0 +1,452 @@
+package cloudwatchtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Datum represents a value (or a set of values summarized by statistics) published to CloudWatch
+type Datum struct {
+	Namespace   string
+	MetricName  string
+	Dimensions  map[string]string
+	Timestamp   time.Time
+	SampleCount float64
+	Sum         float64
+	Minimum     float64
+	Maximum     float64
+}
+
+// NewDatum creates a Datum that represents a single value
+func NewDatum(namespace, metricName string, dimensions map[string]string, t time.Time, value float64) Datum {
+	return Datum{
+		Namespace:   namespace,
+		MetricName:  metricName,
+		Dimensions:  dimensions,
+		Timestamp:   t,
+		SampleCount: 1,
+		Sum:         value,
+		Minimum:     value,
+		Maximum:     value,
+	}
+}
+
+// Server is a stand-in for the CloudWatch API's PutMetricData and GetMetricData actions (AWS query protocol)
+// Point an AWS SDK client at it using URL() as the endpoint; request signatures aren't checked
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	data     []Datum
+	requests []url.Values
+	status   int
+	// resultStatus overrides the StatusCode of every result
+	resultStatus string
+}
+
+// NewServer creates and starts a new Server with no data
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the Server's base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns an HTTP client configured to talk to the Server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddDatum seeds the Server with data
+func (s *Server) AddDatum(data ...Datum) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append(s.data, data...)
+}
+
+// SetStatus makes the Server fail every subsequent request with the HTTP status code
+// Use http.StatusOK (or 0) to return to normal
+func (s *Server) SetStatus(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+}
+
+// SetResultStatus makes the Server return results with the StatusCode (e.g. InternalError) instead of computing it
+// Use "" to return to normal
+func (s *Server) SetResultStatus(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resultStatus = code
+}
+
+// Requests returns the (form-encoded) GetMetricData requests the Server has received
+func (s *Server) Requests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values{}, s.requests...)
+}
+
+const xmlns = "http://monitoring.amazonaws.com/doc/2010-08-01/"
+
+// errorResponse represents an AWS query protocol error
+type errorResponse struct {
+	XMLName xml.Name `xml:"ErrorResponse"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Error   struct {
+		Type    string `xml:"Type"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+	RequestID string `xml:"RequestId"`
+}
+
+// fail writes an AWS query protocol error response
+func fail(w http.ResponseWriter, code int, errorCode, message string) {
+	var r errorResponse
+	r.Xmlns = xmlns
+	r.Error.Type = "Sender"
+	if code >= http.StatusInternalServerError {
+		r.Error.Type = "Receiver"
+	}
+	r.Error.Code = errorCode
+	r.Error.Message = message
+	r.RequestID = "cloudwatchtest"
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(code)
+	xml.NewEncoder(w).Encode(r)
+}
+
+// handle dispatches requests by their Action
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	code := s.status
+	s.mu.Unlock()
+	if code != 0 && code != http.StatusOK {
+		fail(w, code, "InternalServiceError", http.StatusText(code))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		fail(w, http.StatusBadRequest, "MalformedQueryString", err.Error())
+		return
+	}
+	switch action := r.Form.Get("Action"); action {
+	case "PutMetricData":
+		s.putMetricData(w, r.Form)
+	case "GetMetricData":
+		s.getMetricData(w, r.Form)
+	default:
+		fail(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("Action '%s' is not supported", action))
+	}
+}
+
+// members returns the values of a list parameter e.g. "MetricData.member.N" for N=1,2,...
+func members(form url.Values, prefix string) []string {
+	prefixes := []string{}
+	for j := 1; ; j++ {
+		p := fmt.Sprintf("%s.member.%d", prefix, j)
+		found := false
+		for key := range form {
+			if key == p || strings.HasPrefix(key, p+".") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return prefixes
+		}
+		prefixes = append(prefixes, p)
+	}
+}
+
+// dimensions returns the dimensions of the list parameter with the prefix
+func dimensions(form url.Values, prefix string) map[string]string {
+	m := map[string]string{}
+	for _, p := range members(form, prefix+".Dimensions") {
+		m[form.Get(p+".Name")] = form.Get(p + ".Value")
+	}
+	return m
+}
+
+// parseFloat parses a float parameter; missing parameters are zero
+func parseFloat(form url.Values, key string) (float64, error) {
+	if form.Get(key) == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(form.Get(key), 64)
+}
+
+// putMetricData implements the PutMetricData action
+func (s *Server) putMetricData(w http.ResponseWriter, form url.Values) {
+	namespace := form.Get("Namespace")
+	if namespace == "" {
+		fail(w, http.StatusBadRequest, "MissingParameter", "The parameter Namespace is required.")
+		return
+	}
+	data := []Datum{}
+	for _, p := range members(form, "MetricData") {
+		t := time.Now()
+		if form.Get(p+".Timestamp") != "" {
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, form.Get(p+".Timestamp")); err != nil {
+				fail(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+				return
+			}
+		}
+		d := Datum{
+			Namespace:  namespace,
+			MetricName: form.Get(p + ".MetricName"),
+			Dimensions: dimensions(form, p),
+			Timestamp:  t,
+		}
+		if form.Get(p+".StatisticValues.SampleCount") != "" {
+			var err [4]error
+			d.SampleCount, err[0] = parseFloat(form, p+".StatisticValues.SampleCount")
+			d.Sum, err[1] = parseFloat(form, p+".StatisticValues.Sum")
+			d.Minimum, err[2] = parseFloat(form, p+".StatisticValues.Minimum")
+			d.Maximum, err[3] = parseFloat(form, p+".StatisticValues.Maximum")
+			for _, e := range err {
+				if e != nil {
+					fail(w, http.StatusBadRequest, "InvalidParameterValue", e.Error())
+					return
+				}
+			}
+		} else {
+			value, err := parseFloat(form, p+".Value")
+			if err != nil {
+				fail(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+				return
+			}
+			d = NewDatum(namespace, d.MetricName, d.Dimensions, t, value)
+		}
+		if d.MetricName == "" {
+			fail(w, http.StatusBadRequest, "MissingParameter", "The parameter MetricName is required.")
+			return
+		}
+		data = append(data, d)
+	}
+	s.AddDatum(data...)
+
+	type response struct {
+		XMLName   xml.Name `xml:"PutMetricDataResponse"`
+		Xmlns     string   `xml:"xmlns,attr"`
+		RequestID string   `xml:"ResponseMetadata>RequestId"`
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(response{Xmlns: xmlns, RequestID: "cloudwatchtest"})
+}
+
+// query represents a GetMetricData MetricStat query
+type query struct {
+	id         string
+	namespace  string
+	metricName string
+	dimensions map[string]string
+	period     time.Duration
+	stat       string
+}
+
+// result represents a query's result
+type result struct {
+	ID         string    `xml:"Id"`
+	Label      string    `xml:"Label"`
+	StatusCode string    `xml:"StatusCode"`
+	Timestamps []string  `xml:"Timestamps>member"`
+	Values     []float64 `xml:"Values>member"`
+}
+
+// getMetricData implements the GetMetricData action for MetricStat queries (metric math expressions aren't supported)
+func (s *Server) getMetricData(w http.ResponseWriter, form url.Values) {
+	start, err := time.Parse(time.RFC3339Nano, form.Get("StartTime"))
+	if err != nil {
+		fail(w, http.StatusBadRequest, "InvalidParameterValue", "The parameter StartTime is invalid")
+		return
+	}
+	end, err := time.Parse(time.RFC3339Nano, form.Get("EndTime"))
+	if err != nil {
+		fail(w, http.StatusBadRequest, "InvalidParameterValue", "The parameter EndTime is invalid")
+		return
+	}
+	queries := []query{}
+	for _, p := range members(form, "MetricDataQueries") {
+		if form.Get(p+".Expression") != "" {
+			fail(w, http.StatusBadRequest, "InvalidParameterValue", "Expressions are not supported")
+			return
+		}
+		period, err := strconv.Atoi(form.Get(p + ".MetricStat.Period"))
+		if err != nil || period <= 0 {
+			fail(w, http.StatusBadRequest, "InvalidParameterValue", "The parameter Period is invalid")
+			return
+		}
+		q := query{
+			id:         form.Get(p + ".Id"),
+			namespace:  form.Get(p + ".MetricStat.Metric.Namespace"),
+			metricName: form.Get(p + ".MetricStat.Metric.MetricName"),
+			dimensions: dimensions(form, p+".MetricStat.Metric"),
+			period:     time.Duration(period) * time.Second,
+			stat:       form.Get(p + ".MetricStat.Stat"),
+		}
+		switch q.stat {
+		case "Average", "Sum", "Minimum", "Maximum", "SampleCount":
+		default:
+			fail(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("Statistic '%s' is not supported", q.stat))
+			return
+		}
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		fail(w, http.StatusBadRequest, "MissingParameter", "The parameter MetricDataQueries is required.")
+		return
+	}
+	ascending := form.Get("ScanBy") == "TimestampAscending"
+
+	s.mu.Lock()
+	s.requests = append(s.requests, form)
+	results := []result{}
+	for _, q := range queries {
+		r := s.evaluate(q, start, end, ascending)
+		if s.resultStatus != "" {
+			r.StatusCode = s.resultStatus
+		}
+		results = append(results, r)
+	}
+	s.mu.Unlock()
+
+	// Pages hold at most MaxDatapoints points; NextToken is the offset of the page's first point
+	offset, _ := strconv.Atoi(form.Get("NextToken"))
+	size, _ := strconv.Atoi(form.Get("MaxDatapoints"))
+	if size <= 0 {
+		size = math.MaxInt32
+	}
+	page := []result{}
+	next := ""
+	position := 0
+	for _, r := range results {
+		pr := r
+		pr.Timestamps, pr.Values = nil, nil
+		for j := range r.Values {
+			if position >= offset && position < offset+size {
+				pr.Timestamps = append(pr.Timestamps, r.Timestamps[j])
+				pr.Values = append(pr.Values, r.Values[j])
+			}
+			position++
+		}
+		page = append(page, pr)
+	}
+	if position > offset+size {
+		next = strconv.Itoa(offset + size)
+		for j := range page {
+			if page[j].StatusCode == "Complete" {
+				page[j].StatusCode = "PartialData"
+			}
+		}
+	}
+
+	type response struct {
+		XMLName   xml.Name `xml:"GetMetricDataResponse"`
+		Xmlns     string   `xml:"xmlns,attr"`
+		Results   []result `xml:"GetMetricDataResult>MetricDataResults>member"`
+		NextToken string   `xml:"GetMetricDataResult>NextToken,omitempty"`
+		RequestID string   `xml:"ResponseMetadata>RequestId"`
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(response{
+		Xmlns:     xmlns,
+		Results:   page,
+		NextToken: next,
+		RequestID: "cloudwatchtest",
+	})
+}
+
+// evaluate computes the query's statistic for each period (aligned to the epoch) between start and end
+// Data only match a query if their dimensions are identical
+// The Server's lock must be held
+func (s *Server) evaluate(q query, start, end time.Time, ascending bool) result {
+	type bucket struct {
+		count, sum, min, max float64
+	}
+	buckets := map[time.Time]*bucket{}
+	for _, d := range s.data {
+		if d.Namespace != q.namespace || d.MetricName != q.metricName || !equal(d.Dimensions, q.dimensions) {
+			continue
+		}
+		t := d.Timestamp.Truncate(q.period)
+		if t.Before(start) || !t.Before(end) {
+			continue
+		}
+		b, ok := buckets[t]
+		if !ok {
+			b = &bucket{min: math.Inf(1), max: math.Inf(-1)}
+			buckets[t] = b
+		}
+		b.count += d.SampleCount
+		b.sum += d.Sum
+		b.min = math.Min(b.min, d.Minimum)
+		b.max = math.Max(b.max, d.Maximum)
+	}
+	times := make([]time.Time, 0, len(buckets))
+	for t := range buckets {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool {
+		if ascending {
+			return times[i].Before(times[j])
+		}
+		return times[i].After(times[j])
+	})
+	r := result{
+		ID:         q.id,
+		Label:      q.metricName,
+		StatusCode: "Complete",
+	}
+	for _, t := range times {
+		b := buckets[t]
+		var value float64
+		switch q.stat {
+		case "Average":
+			value = b.sum / b.count
+		case "Sum":
+			value = b.sum
+		case "Minimum":
+			value = b.min
+		case "Maximum":
+			value = b.max
+		case "SampleCount":
+			value = b.count
+		}
+		r.Timestamps = append(r.Timestamps, t.UTC().Format(time.RFC3339))
+		r.Values = append(r.Values, value)
+	}
+	return r
+}
+
+// equal returns true if the dimensions are identical
+func equal(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if v, ok := b[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
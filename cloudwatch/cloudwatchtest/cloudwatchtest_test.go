@@ -0,0 +1,96 @@
+package cloudwatchtest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// post sends a form-encoded (AWS query protocol) request to the Server
+func post(t *testing.T, s *Server, form url.Values) (int, string) {
+	resp, err := s.Client().Post(s.URL(), "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var b strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		b.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	return resp.StatusCode, b.String()
+}
+func TestServer_GetMetricData(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	now := time.Now().Truncate(time.Minute)
+	s.AddDatum(
+		NewDatum("OpenCensus", "counter0", map[string]string{"key1": "value1"}, now.Add(-2*time.Minute), 1),
+		NewDatum("OpenCensus", "counter0", map[string]string{"key1": "value1"}, now.Add(-time.Minute), 2),
+	)
+	form := url.Values{
+		"Action":                        {"GetMetricData"},
+		"Version":                       {"2010-08-01"},
+		"StartTime":                     {now.Add(-5 * time.Minute).UTC().Format(time.RFC3339)},
+		"EndTime":                       {now.UTC().Format(time.RFC3339)},
+		"MetricDataQueries.member.1.Id": {"m0"},
+		"MetricDataQueries.member.1.MetricStat.Metric.Namespace":                 {"OpenCensus"},
+		"MetricDataQueries.member.1.MetricStat.Metric.MetricName":                {"counter0"},
+		"MetricDataQueries.member.1.MetricStat.Metric.Dimensions.member.1.Name":  {"key1"},
+		"MetricDataQueries.member.1.MetricStat.Metric.Dimensions.member.1.Value": {"value1"},
+		"MetricDataQueries.member.1.MetricStat.Period":                           {"60"},
+		"MetricDataQueries.member.1.MetricStat.Stat":                             {"Sum"},
+	}
+	t.Run("Newest First", func(t *testing.T) {
+		code, body := post(t, s, form)
+		if got, want := code, http.StatusOK; got != want {
+			t.Fatalf("got %d; want %d (%s)", got, want, body)
+		}
+		var r struct {
+			Results []result `xml:"GetMetricDataResult>MetricDataResults>member"`
+		}
+		if err := xml.Unmarshal([]byte(body), &r); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(r.Results[0].Values), 2; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := r.Results[0].Values[0], 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Pages", func(t *testing.T) {
+		paged := url.Values{}
+		for key, values := range form {
+			paged[key] = values
+		}
+		paged.Set("MaxDatapoints", "1")
+		_, body := post(t, s, paged)
+		var r struct {
+			Results   []result `xml:"GetMetricDataResult>MetricDataResults>member"`
+			NextToken string   `xml:"GetMetricDataResult>NextToken"`
+		}
+		if err := xml.Unmarshal([]byte(body), &r); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.NextToken, "1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := len(r.Results[0].Values), 1; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Unknown Action", func(t *testing.T) {
+		code, _ := post(t, s, url.Values{"Action": {"ListMetrics"}})
+		if got, want := code, http.StatusBadRequest; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package cloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// Point represents a CloudWatch data point: a statistic's value for the period beginning at Time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// toPoints converts a GetMetricData result to Points
+// Results with status InternalError are errors; PartialData is not because the remaining pages are read
+func toPoints(r *cloudwatch.MetricDataResult) ([]Point, error) {
+	switch status := aws.StringValue(r.StatusCode); status {
+	case cloudwatch.StatusCodeComplete, cloudwatch.StatusCodePartialData, "":
+	default:
+		return nil, fmt.Errorf("Result '%s' has status %s", aws.StringValue(r.Id), status)
+	}
+	if len(r.Timestamps) != len(r.Values) {
+		return nil, errors.New("Inconsistency between timestamps and values")
+	}
+	points := make([]Point, len(r.Values))
+	for j := range r.Values {
+		points[j] = Point{
+			Time:  aws.TimeValue(r.Timestamps[j]),
+			Value: aws.Float64Value(r.Values[j]),
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,42 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+func Test_toPoints(t *testing.T) {
+	now := time.Now()
+	t.Run("Complete", func(t *testing.T) {
+		points, err := toPoints(&cloudwatch.MetricDataResult{
+			Id:         aws.String(queryID),
+			StatusCode: aws.String(cloudwatch.StatusCodeComplete),
+			Timestamps: aws.TimeSlice([]time.Time{now, now.Add(-time.Minute)}),
+			Values:     aws.Float64Slice([]float64{2, 1}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(points), 2; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("InternalError", func(t *testing.T) {
+		if _, err := toPoints(&cloudwatch.MetricDataResult{
+			Id:         aws.String(queryID),
+			StatusCode: aws.String(cloudwatch.StatusCodeInternalError),
+		}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Inconsistent", func(t *testing.T) {
+		if _, err := toPoints(&cloudwatch.MetricDataResult{
+			Timestamps: aws.TimeSlice([]time.Time{now}),
+		}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package cloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// queryID identifies the (only) metric data query in a GetMetricData request
+const queryID = "m0"
+
+// validStatistic returns true if the statistic is one of CloudWatch's standard statistics
+func validStatistic(statistic string) bool {
+	for _, s := range cloudwatch.Statistic_Values() {
+		if s == statistic {
+			return true
+		}
+	}
+	return false
+}
+
+// validPeriod returns true if the period is a high-resolution period (1, 5, 10 or 30 seconds) or a multiple of 60 seconds
+func validPeriod(period time.Duration) bool {
+	switch period {
+	case time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second:
+		return true
+	}
+	return period > 0 && period%time.Minute == 0
+}
+
+// window returns the duration of the interval that's read
+func (i *Importer) window() time.Duration {
+	if i.options.Window != 0 {
+		return i.options.Window
+	}
+	return 5 * i.options.Period
+}
+
+// input returns the GetMetricData request for the View, with the label values, in the window ending at the time specified
+// Results are requested newest first
+func (i *Importer) input(v *view.View, labelValues []string, t time.Time) (*cloudwatch.GetMetricDataInput, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return nil, errors.New("Inconsistency between labels and values")
+	}
+	dimensions := []*cloudwatch.Dimension{}
+	for j, labelName := range v.LabelNames {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String(labelName),
+			Value: aws.String(labelValues[j]),
+		})
+	}
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(t.Add(-i.window())),
+		EndTime:   aws.Time(t),
+		ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+		MetricDataQueries: []*cloudwatch.MetricDataQuery{{
+			Id: aws.String(queryID),
+			MetricStat: &cloudwatch.MetricStat{
+				Metric: &cloudwatch.Metric{
+					Namespace:  aws.String(i.options.Namespace),
+					MetricName: aws.String(i.options.MetricPrefix + v.Name),
+					Dimensions: dimensions,
+				},
+				Period: aws.Int64(int64(i.options.Period / time.Second)),
+				Stat:   aws.String(i.options.Statistic),
+			},
+			ReturnData: aws.Bool(true),
+		}},
+	}
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid GetMetricData request: %s", err)
+	}
+	return input, nil
+}
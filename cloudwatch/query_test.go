@@ -0,0 +1,65 @@
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+func Test_validPeriod(t *testing.T) {
+	for _, test := range []struct {
+		period time.Duration
+		want   bool
+	}{
+		{time.Second, true},
+		{10 * time.Second, true},
+		{20 * time.Second, false},
+		{time.Minute, true},
+		{90 * time.Second, false},
+		{5 * time.Minute, true},
+		{0, false},
+	} {
+		if got := validPeriod(test.period); got != test.want {
+			t.Errorf("%s: got %t; want %t", test.period, got, test.want)
+		}
+	}
+}
+func TestImporter_input(t *testing.T) {
+	i := &Importer{options: Options{
+		Namespace:    namespace,
+		MetricPrefix: "namespace_",
+		Statistic:    "Sum",
+		Period:       time.Minute,
+	}}
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}
+	now := time.Now()
+	t.Run("Input", func(t *testing.T) {
+		input, err := i.input(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat := input.MetricDataQueries[0].MetricStat
+		if got, want := aws.StringValue(stat.Metric.MetricName), "namespace_counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := aws.StringValue(stat.Metric.Dimensions[0].Value), "value1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := aws.Int64Value(stat.Period), int64(60); got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := now.Sub(aws.TimeValue(input.StartTime)), 5*time.Minute; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Inconsistent Labels", func(t *testing.T) {
+		if _, err := i.input(v, nil, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package datadog
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// Datadog sites
+// See: https://docs.datadoghq.com/getting_started/site/
+const (
+	SiteUS1 = "datadoghq.com"
+	SiteUS3 = "us3.datadoghq.com"
+	SiteUS5 = "us5.datadoghq.com"
+	SiteEU1 = "datadoghq.eu"
+	SiteAP1 = "ap1.datadoghq.com"
+	SiteGov = "ddog-gov.com"
+)
+
+// baseURL returns the Datadog API endpoint for the Options
+// BaseURL takes precedence over Site; Site defaults to environment variable DD_SITE and then to "datadoghq.com"
+func baseURL(o Options) string {
+	if o.BaseURL != "" {
+		return strings.TrimSuffix(o.BaseURL, "/")
+	}
+	site := o.Site
+	if site == "" {
+		site = os.Getenv("DD_SITE")
+	}
+	if site == "" {
+		site = SiteUS1
+	}
+	return "https://api." + site
+}
+
+// httpClient returns the HTTP client for the Options
+// A Proxy or Timeout is applied to a copy of the HTTPClient (or of http.DefaultClient) so that the original is unchanged
+func httpClient(o Options) (*http.Client, error) {
+	c := http.DefaultClient
+	if o.HTTPClient != nil {
+		c = o.HTTPClient
+	}
+	if o.Proxy == "" && o.Timeout == 0 {
+		return c, nil
+	}
+	clone := *c
+	if o.Proxy != "" {
+		proxy, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse proxy '%s': %s", o.Proxy, err)
+		}
+		var transport *http.Transport
+		switch t := c.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return nil, fmt.Errorf("Unable to configure a proxy for HTTP client transport %T", t)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+		clone.Transport = transport
+	}
+	if o.Timeout != 0 {
+		clone.Timeout = o.Timeout
+	}
+	return &clone, nil
+}
+
+// newClient creates a Datadog API client for the Options
+func newClient(o Options) (*datadog.Client, error) {
+	c, err := httpClient(o)
+	if err != nil {
+		return nil, err
+	}
+	client := datadog.NewClient(o.APIKey, o.AppKey)
+	client.SetBaseUrl(baseURL(o))
+	client.HttpClient = c
+	return client, nil
+}
@@ -0,0 +1,89 @@
+package datadog
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_baseURL(t *testing.T) {
+	os.Unsetenv("DD_SITE")
+	for _, test := range []struct {
+		name string
+		o    Options
+		want string
+	}{
+		{"Default", Options{}, "https://api.datadoghq.com"},
+		{"EU", Options{Site: SiteEU1}, "https://api.datadoghq.eu"},
+		{"US5", Options{Site: SiteUS5}, "https://api.us5.datadoghq.com"},
+		{"Gov", Options{Site: SiteGov}, "https://api.ddog-gov.com"},
+		{"BaseURL", Options{Site: SiteEU1, BaseURL: "http://localhost:8080/"}, "http://localhost:8080"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := baseURL(test.o); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("DD_SITE", SiteUS3)
+		defer os.Unsetenv("DD_SITE")
+		if got, want := baseURL(Options{}), "https://api.us3.datadoghq.com"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func Test_httpClient(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		c, err := httpClient(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c, http.DefaultClient; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		original := &http.Client{}
+		c, err := httpClient(Options{
+			HTTPClient: original,
+			Timeout:    5 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := c.Timeout, 5*time.Second; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := original.Timeout, time.Duration(0); got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Proxy", func(t *testing.T) {
+		c, err := httpClient(Options{
+			Proxy: "http://proxy.example.com:3128",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport, ok := c.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("got %T; want *http.Transport", c.Transport)
+		}
+		req := &http.Request{URL: &url.URL{Scheme: "https", Host: "api.datadoghq.com"}}
+		proxy, err := transport.Proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := proxy.Host, "proxy.example.com:3128"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Invalid Proxy", func(t *testing.T) {
+		if _, err := httpClient(Options{Proxy: "://"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
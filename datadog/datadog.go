@@ -1,29 +1,48 @@
 package datadog
 
 import (
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dazwilkin/opencensus/stats/view"
 	datadog "gopkg.in/zorkian/go-datadog-api.v2"
 )
 
-var (
-	client *datadog.Client
-)
-
 type Importer struct {
 	name    string
 	options Options
+	client  *datadog.Client
+
+	mu       sync.Mutex
+	metadata map[string]*Metadata
 }
 
 // NewImporter creates a new importer using the Options provided
+// The API and App keys default to the values of environment variables DD_API and DD_APP
 func NewImporter(o Options) (*Importer, error) {
+	if o.APIKey == "" {
+		o.APIKey = os.Getenv("DD_API")
+	}
+	if o.AppKey == "" {
+		o.AppKey = os.Getenv("DD_APP")
+	}
+	if o.APIKey == "" || o.AppKey == "" {
+		return nil, errors.New("Expect Datadog API and App values")
+	}
+	client, err := newClient(o)
+	if err != nil {
+		return nil, err
+	}
 	return &Importer{
-		name:    "datadog",
-		options: o,
+		name:     "datadog",
+		options:  o,
+		client:   client,
+		metadata: make(map[string]*Metadata),
 	}, nil
 }
 
@@ -39,11 +58,21 @@ func (i *Importer) Stop() {
 
 // Value returns the Importer's value for the View, with the label values and the time specified
 func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the Point chosen by the Importer's Selector for the View, with the label values and the time specified
+// The Point's Time is the timestamp of the chosen data point
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
 
 	from := t.Add(time.Minute * -1)
 
 	//TODO(dazwilkin) Datadog appears to append label names to the metric name, try it out
-	query := NewQuery(func(v *view.View) string {
+	metric := func(v *view.View) string {
 		// Name
 		name := v.Name
 		// Prefix Namespace, if one exists
@@ -55,7 +84,8 @@ func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float
 			name = name + "_" + strings.Join(v.LabelNames, "_")
 		}
 		return name
-	}(v))
+	}(v)
+	query := NewQuery(metric)
 
 	host, _ := os.Hostname()
 	query.AddHostname(host)
@@ -65,39 +95,60 @@ func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float
 	}
 	log.Println(query.String())
 
-	ss, err := client.QueryMetrics(from.Unix(), t.Unix(), query.String())
+	ss, err := i.client.QueryMetrics(from.Unix(), t.Unix(), query.String())
 	if err != nil {
-		log.Fatal(err)
+		return Point{}, err
+	}
+	if len(ss) == 0 {
+		return Point{}, errors.New("No timeseries match the query")
+	}
+
+	m, err := i.Metadata(metric)
+	if err != nil {
+		return Point{}, err
 	}
 
-	// If there is a time-series, grab the most recent one
-	if len(ss) >= 1 {
-		s := ss[0]
-		log.Printf("Metric: %v", *s.Metric)
-		// If the time-series contains any data points, grab the most recent one
-		if len(s.Points) >= 1 {
-			p := s.Points[0]
-			// *p[0] == Unix epoch timestamp in ms
-			// *p[1] == data
-			log.Printf("[%v] %v", time.Unix(0, int64(*p[0])*int64(time.Millisecond)), *p[1])
-			return *p[1], nil
+	// Consider the (non-null) data points from every time-series
+	points := []Point{}
+	for _, s := range ss {
+		if s.Metric != nil {
+			log.Printf("Metric: %v", *s.Metric)
+		}
+		interval := time.Duration(0)
+		if s.Interval != nil {
+			interval = time.Duration(*s.Interval) * time.Second
 		}
-		return 0.0, nil
+		points = append(points, m.Interpret(toPoints(s.Points), interval)...)
+	}
+
+	selector := i.options.Selector
+	if selector == Auto {
+		selector = m.Selector()
+	}
+	p, err := selector.Select(points, t)
+	if err != nil {
+		return Point{}, err
 	}
-	return 0.0, nil
+	log.Printf("[%v] %v", p.Time, p.Value)
+	return p, nil
 }
 
 // Options represents the configuration of an OpenCensus Importer
 type Options struct {
 	Namespace string
-}
-
-//TODO(dazwilkin) Instead of package init should this by a type func or helper?
-func init() {
-	API := os.Getenv("DD_API")
-	App := os.Getenv("DD_APP")
-	if API == "" || App == "" {
-		log.Fatal("Expect Datadog API and App values")
-	}
-	client = datadog.NewClient(API, App)
+	// Selector chooses the data point to return from the query window; defaults to Auto
+	Selector Selector
+	// APIKey and AppKey authenticate with Datadog; they default to environment variables DD_API and DD_APP
+	APIKey string
+	AppKey string
+	// Site is the Datadog site (e.g. "datadoghq.eu"); defaults to environment variable DD_SITE and then "datadoghq.com"
+	Site string
+	// BaseURL overrides the Datadog API endpoint derived from Site (e.g. to use a test server)
+	BaseURL string
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+	// Proxy is the URL of an HTTP(S) proxy; by default, proxy environment variables (HTTPS_PROXY etc.) are used
+	Proxy string
+	// Timeout limits the time taken by each request; by default, there's no limit
+	Timeout time.Duration
 }
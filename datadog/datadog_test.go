@@ -1 +1,180 @@
 package datadog
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/datadog/datadogtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	namespace = "namespace"
+)
+
+// newTestImporter creates an Importer that talks to a datadogtest.Server
+func newTestImporter(t *testing.T, s *datadogtest.Server) *Importer {
+	i, err := NewImporter(Options{
+		Namespace:  namespace,
+		APIKey:     "api",
+		AppKey:     "app",
+		BaseURL:    s.URL(),
+		HTTPClient: s.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client retries 5xx responses with exponential backoff until RetryTimeout; fail after the first attempt
+	i.client.RetryTimeout = time.Millisecond
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("No Keys", func(t *testing.T) {
+		os.Unsetenv("DD_API")
+		os.Unsetenv("DD_APP")
+		if _, err := NewImporter(Options{}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("With Options", func(t *testing.T) {
+		i, err := NewImporter(Options{
+			Namespace: namespace,
+			APIKey:    "api",
+			AppKey:    "app",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "datadog"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Namespace, namespace; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := datadogtest.NewServer()
+	defer s.Close()
+	i := newTestImporter(t, s)
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	ms := now.UnixNano() / int64(time.Millisecond)
+	s.AddSeries("namespace.counter0_key1_key2", map[string]string{
+		"host": host,
+		"key1": "value1",
+		"key2": "value2",
+	}, datadogtest.NewPoint(ms-10000, 1.5))
+
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+
+	t.Run("Matching Series", func(t *testing.T) {
+		got, err := i.Value(v, []string{"value1", "value2"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1.5; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		queries := s.Queries()
+		metric, tags, err := datadogtest.ParseQuery(queries[len(queries)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := metric, "namespace.counter0_key1_key2"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := tags["host"], host; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("No Matching Series", func(t *testing.T) {
+		if _, err := i.Value(v, []string{"value1", "X"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Outside Window", func(t *testing.T) {
+		if _, err := i.Value(v, []string{"value1", "value2"}, now.Add(time.Hour)); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("API Error", func(t *testing.T) {
+		s.SetStatus(http.StatusForbidden)
+		defer s.SetStatus(http.StatusOK)
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Value_Fixture(t *testing.T) {
+	s := datadogtest.NewServer()
+	defer s.Close()
+	if err := s.LoadFixture("datadogtest/testdata/fixture.json"); err != nil {
+		t.Fatal(err)
+	}
+	i := newTestImporter(t, s)
+
+	got, err := i.Value(&view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}, []string{"value1", "value2"}, time.Unix(1545340030, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixture's metadata makes it a gauge so the latest (non-null) point is chosen
+	if want := 2.5; got != want {
+		t.Errorf("got %f; want %f", got, want)
+	}
+}
+func TestImporter_Point(t *testing.T) {
+	s := datadogtest.NewServer()
+	defer s.Close()
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	ms := now.UnixNano() / int64(time.Millisecond)
+	s.AddSeries("namespace.counter1", map[string]string{
+		"host": host,
+	},
+		datadogtest.NewPoint(ms-40000, 1.0),
+		datadogtest.NewPoint(ms-20000, 3.0),
+		datadogtest.NewPoint(ms-30000, 2.0),
+		datadogtest.NewNullPoint(ms-5000),
+	)
+
+	v := &view.View{
+		Name: "counter1",
+	}
+
+	t.Run("Latest", func(t *testing.T) {
+		i := newTestImporter(t, s)
+		p, err := i.Point(v, []string{}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := p.Value, 3.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := p.Time.UnixNano()/int64(time.Millisecond), ms-20000; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Sum", func(t *testing.T) {
+		i := newTestImporter(t, s)
+		i.options.Selector = Sum
+		got, err := i.Value(v, []string{}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 6.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+}
@@ -0,0 +1,315 @@
+package datadogtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Point represents a Datadog data point: a timestamp (Unix epoch in ms) and a value
+// A nil value represents a null point
+type Point [2]*float64
+
+// NewPoint creates a Point from a timestamp (Unix epoch in ms) and a value
+func NewPoint(ms int64, value float64) Point {
+	t := float64(ms)
+	return Point{&t, &value}
+}
+
+// NewNullPoint creates a Point with a timestamp (Unix epoch in ms) and a null value
+func NewNullPoint(ms int64) Point {
+	t := float64(ms)
+	return Point{&t, nil}
+}
+
+// Series represents a time-series for a metric with a set of tags
+type Series struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags"`
+	Points []Point           `json:"points"`
+}
+
+// Metadata represents Datadog's metric metadata
+type Metadata struct {
+	Type           string `json:"type,omitempty"`
+	Description    string `json:"description,omitempty"`
+	ShortName      string `json:"short_name,omitempty"`
+	Unit           string `json:"unit,omitempty"`
+	PerUnit        string `json:"per_unit,omitempty"`
+	StatsdInterval int    `json:"statsd_interval,omitempty"`
+}
+
+// Fixture represents the JSON used to seed a Server from a file
+// A tag value of "$HOST" is replaced by the local hostname, which the importer includes in its queries
+type Fixture struct {
+	Series   []Series            `json:"series"`
+	Metadata map[string]Metadata `json:"metadata"`
+}
+
+// Server is a stand-in for the Datadog API's query and metric metadata endpoints
+// Point a Datadog client at it using URL()
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	series   []Series
+	metadata map[string]Metadata
+	queries  []string
+	status   int
+}
+
+// NewServer creates and starts a new Server with no data
+func NewServer() *Server {
+	s := &Server{
+		metadata: make(map[string]Metadata),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query", s.handleQuery)
+	mux.HandleFunc("/api/v1/metrics/", s.handleMetadata)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the Server's base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns an HTTP client configured to talk to the Server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddSeries seeds the Server with a time-series
+func (s *Server) AddSeries(metric string, tags map[string]string, points ...Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series = append(s.series, Series{
+		Metric: metric,
+		Tags:   tags,
+		Points: points,
+	})
+}
+
+// SetMetadata seeds the Server with the metadata for a metric
+func (s *Server) SetMetadata(metric string, m Metadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata[metric] = m
+}
+
+// LoadFixture seeds the Server from a JSON file (see Fixture)
+func (s *Server) LoadFixture(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var fixture Fixture
+	if err := json.NewDecoder(f).Decode(&fixture); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	for _, series := range fixture.Series {
+		for k, v := range series.Tags {
+			if v == "$HOST" {
+				series.Tags[k] = host
+			}
+		}
+		s.AddSeries(series.Metric, series.Tags, series.Points...)
+	}
+	for metric, m := range fixture.Metadata {
+		s.SetMetadata(metric, m)
+	}
+	return nil
+}
+
+// SetStatus makes the Server fail every subsequent request with the HTTP status code
+// Use http.StatusOK (or 0) to return to normal
+func (s *Server) SetStatus(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+}
+
+// Queries returns the queries the Server has received
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.queries...)
+}
+
+// fail writes a Datadog-style error response
+func fail(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string][]string{
+		"errors": {message},
+	})
+}
+
+// failed returns true if the Server has been told to fail requests
+func (s *Server) failed(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	code := s.status
+	s.mu.Unlock()
+	if code == 0 || code == http.StatusOK {
+		return false
+	}
+	fail(w, code, http.StatusText(code))
+	return true
+}
+
+// handleQuery implements GET /api/v1/query?from=[s]&to=[s]&query=[query]
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if s.failed(w) {
+		return
+	}
+	from, err := strconv.ParseInt(r.FormValue("from"), 10, 64)
+	if err != nil {
+		fail(w, http.StatusBadRequest, "Invalid 'from' parameter")
+		return
+	}
+	to, err := strconv.ParseInt(r.FormValue("to"), 10, 64)
+	if err != nil {
+		fail(w, http.StatusBadRequest, "Invalid 'to' parameter")
+		return
+	}
+	query := r.FormValue("query")
+	metric, tags, err := ParseQuery(query)
+	if err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	type series struct {
+		Metric      string  `json:"metric"`
+		DisplayName string  `json:"display_name"`
+		Scope       string  `json:"scope"`
+		Expression  string  `json:"expression"`
+		Points      []Point `json:"pointlist"`
+		Start       float64 `json:"start"`
+		End         float64 `json:"end"`
+		Length      int     `json:"length"`
+	}
+	result := []series{}
+	for _, ss := range s.series {
+		if ss.Metric != metric || !matches(ss.Tags, tags) {
+			continue
+		}
+		points := []Point{}
+		for _, p := range ss.Points {
+			if p[0] == nil {
+				continue
+			}
+			if ms := int64(*p[0]); ms >= from*1000 && ms <= to*1000 {
+				points = append(points, p)
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+		result = append(result, series{
+			Metric:      ss.Metric,
+			DisplayName: ss.Metric,
+			Scope:       scope(ss.Tags),
+			Expression:  query,
+			Points:      points,
+			Start:       *points[0][0],
+			End:         *points[len(points)-1][0],
+			Length:      len(points),
+		})
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"res_type":  "time_series",
+		"from_date": from * 1000,
+		"to_date":   to * 1000,
+		"query":     query,
+		"series":    result,
+	})
+}
+
+// handleMetadata implements GET /api/v1/metrics/[metric]
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	if s.failed(w) {
+		return
+	}
+	metric := strings.TrimPrefix(r.URL.Path, "/api/v1/metrics/")
+	s.mu.Lock()
+	m, ok := s.metadata[metric]
+	s.mu.Unlock()
+	if !ok {
+		fail(w, http.StatusNotFound, fmt.Sprintf("Metric '%s' not found", metric))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// ParseQuery parses a (simple) Datadog metric query of the form "[aggregator:]metric{tag:value,...}"
+func ParseQuery(query string) (string, map[string]string, error) {
+	tags := map[string]string{}
+	// Drop any space aggregator (e.g. "avg:")
+	if i := strings.Index(query, ":"); i >= 0 && (strings.Index(query, "{") < 0 || i < strings.Index(query, "{")) {
+		query = query[i+1:]
+	}
+	metric := query
+	if i := strings.Index(query, "{"); i >= 0 {
+		if !strings.HasSuffix(query, "}") {
+			return "", nil, fmt.Errorf("Unable to parse query '%s'", query)
+		}
+		metric = query[:i]
+		for _, tag := range strings.Split(query[i+1:len(query)-1], ",") {
+			if tag == "*" || tag == "" {
+				continue
+			}
+			j := strings.Index(tag, ":")
+			if j <= 0 {
+				return "", nil, fmt.Errorf("Unable to parse tag '%s'", tag)
+			}
+			tags[tag[:j]] = tag[j+1:]
+		}
+	}
+	if metric == "" {
+		return "", nil, fmt.Errorf("Unable to parse query '%s'", query)
+	}
+	return metric, tags, nil
+}
+
+// matches returns true if every one of the wanted tags is present
+func matches(tags, want map[string]string) bool {
+	for key, value := range want {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// scope returns the tags as Datadog's comma-separated scope string
+func scope(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "*"
+	}
+	ss := make([]string, 0, len(tags))
+	for key, value := range tags {
+		ss = append(ss, key+":"+value)
+	}
+	return strings.Join(ss, ",")
+}
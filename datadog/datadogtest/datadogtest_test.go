@@ -0,0 +1,127 @@
+package datadogtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// get sends a GET request to the Server and decodes the JSON response
+func get(t *testing.T, s *Server, path string, out interface{}) int {
+	resp, err := s.Client().Get(s.URL() + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+func Test_ParseQuery(t *testing.T) {
+	t.Run("Metric", func(t *testing.T) {
+		metric, tags, err := ParseQuery("avg:namespace.counter0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := metric, "namespace.counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := len(tags), 0; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Metric with Tags", func(t *testing.T) {
+		metric, tags, err := ParseQuery("namespace.counter0{key1:value1,key2:value2}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := metric, "namespace.counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := tags["key2"], "value2"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, query := range []string{"", "{key1:value1}", "namespace.counter0{key1", "namespace.counter0{key1}"} {
+			if _, _, err := ParseQuery(query); err == nil {
+				t.Errorf("'%s' got nil; want error", query)
+			}
+		}
+	})
+}
+func TestServer_LoadFixture(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	if err := s.LoadFixture("testdata/fixture.json"); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Query", func(t *testing.T) {
+		v := url.Values{}
+		v.Set("from", "1545339990")
+		v.Set("to", "1545340030")
+		v.Set("query", "namespace.counter0_key1_key2{key1:value1}")
+		var out struct {
+			Status string `json:"status"`
+			Series []struct {
+				Points []Point `json:"pointlist"`
+			} `json:"series"`
+		}
+		if got, want := get(t, s, "/api/v1/query?"+v.Encode(), &out), http.StatusOK; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := len(out.Series), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := len(out.Series[0].Points), 3; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got := out.Series[0].Points[1][1]; got != nil {
+			t.Errorf("got %f; want null", *got)
+		}
+		if got, want := s.Queries(), []string{"namespace.counter0_key1_key2{key1:value1}"}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Query outside Window", func(t *testing.T) {
+		v := url.Values{}
+		v.Set("from", "1545330000")
+		v.Set("to", "1545330060")
+		v.Set("query", "namespace.counter0_key1_key2")
+		var out struct {
+			Series []interface{} `json:"series"`
+		}
+		get(t, s, "/api/v1/query?"+v.Encode(), &out)
+		if got, want := len(out.Series), 0; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		var m Metadata
+		if got, want := get(t, s, "/api/v1/metrics/namespace.counter0_key1_key2", &m), http.StatusOK; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := m.Type, "gauge"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.StatsdInterval, 10; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Missing Metadata", func(t *testing.T) {
+		var out interface{}
+		if got, want := get(t, s, "/api/v1/metrics/X", &out), http.StatusNotFound; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("SetStatus", func(t *testing.T) {
+		s.SetStatus(http.StatusForbidden)
+		defer s.SetStatus(http.StatusOK)
+		var out interface{}
+		if got, want := get(t, s, "/api/v1/metrics/namespace.counter0_key1_key2", &out), http.StatusForbidden; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
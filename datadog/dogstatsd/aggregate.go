@@ -0,0 +1,82 @@
+package dogstatsd
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Aggregate represents the values received for a metric with a specific set of tags
+type Aggregate struct {
+	Type Type
+	// Count is the number of values received, scaled up by the sample rate
+	Count float64
+	// Sum is the total of the values received, scaled up by the sample rate
+	Sum  float64
+	Last float64
+	Min  float64
+	Max  float64
+	// Updated is the time the most recent value was received
+	Updated time.Time
+
+	unique map[string]struct{}
+}
+
+// newAggregate creates an empty Aggregate for the metric type
+func newAggregate(t Type) *Aggregate {
+	return &Aggregate{
+		Type:   t,
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+		unique: map[string]struct{}{},
+	}
+}
+
+// add includes a parsed metric in the Aggregate
+// Metrics must have been validated by Parse
+func (a *Aggregate) add(m *Metric, t time.Time) {
+	for _, value := range m.Values {
+		if a.Type == Set {
+			a.unique[value] = struct{}{}
+			a.Count += 1.0 / m.SampleRate
+			continue
+		}
+		f, _ := strconv.ParseFloat(value, 64)
+		a.Count += 1.0 / m.SampleRate
+		a.Sum += f / m.SampleRate
+		a.Last = f
+		a.Min = math.Min(a.Min, f)
+		a.Max = math.Max(a.Max, f)
+	}
+	a.Updated = t
+}
+
+// merge combines another Aggregate (of the same Type) with this one
+func (a *Aggregate) merge(b *Aggregate) {
+	a.Count += b.Count
+	a.Sum += b.Sum
+	a.Min = math.Min(a.Min, b.Min)
+	a.Max = math.Max(a.Max, b.Max)
+	for value := range b.unique {
+		a.unique[value] = struct{}{}
+	}
+	if b.Updated.After(a.Updated) {
+		a.Last = b.Last
+		a.Updated = b.Updated
+	}
+}
+
+// Value returns the Aggregate as a single value
+// Counters, histograms, timers and distributions return their (sample rate adjusted) sum
+// Gauges return their most recent value
+// Sets return the number of unique values
+func (a *Aggregate) Value() float64 {
+	switch a.Type {
+	case Gauge:
+		return a.Last
+	case Set:
+		return float64(len(a.unique))
+	default:
+		return a.Sum
+	}
+}
@@ -0,0 +1,212 @@
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	"github.com/golang/glog"
+)
+
+const (
+	defaultNetwork = "udp"
+	defaultAddr    = "localhost:8125"
+	// DogStatsD clients limit datagrams to 8KB when using Unix Domain Sockets
+	maxPacketSize = 8192
+)
+
+// Importer represents an OpenCensus Importer that reads values from the DogStatsD datagrams
+// sent by the OpenCensus Datadog Exporter, without requiring a Datadog agent or account
+type Importer struct {
+	name    string
+	options Options
+
+	conn net.PacketConn
+
+	mu         sync.RWMutex
+	aggregates map[string]*aggregateEntry
+	received   int
+
+	done chan struct{}
+}
+
+// aggregateEntry keeps an Aggregate together with the name and tags it was keyed by
+type aggregateEntry struct {
+	name      string
+	tags      map[string]bool
+	aggregate *Aggregate
+}
+
+// NewImporter creates a new importer using the Options provided
+// The importer listens for DogStatsD datagrams until Stop is called
+func NewImporter(o Options) (*Importer, error) {
+	if o.Network == "" {
+		o.Network = defaultNetwork
+	}
+	if o.Addr == "" {
+		o.Addr = defaultAddr
+	}
+	conn, err := net.ListenPacket(o.Network, o.Addr)
+	if err != nil {
+		return nil, err
+	}
+	i := &Importer{
+		name:       "dogstatsd",
+		options:    o,
+		conn:       conn,
+		aggregates: make(map[string]*aggregateEntry),
+		done:       make(chan struct{}),
+	}
+	go i.listen()
+	return i, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Addr returns the address that the Importer is listening on
+// This is useful when Options.Addr requests an ephemeral port (e.g. "localhost:0")
+func (i *Importer) Addr() net.Addr {
+	return i.conn.LocalAddr()
+}
+
+// Stop closes the Importer's socket
+func (i *Importer) Stop() {
+	i.conn.Close()
+	<-i.done
+	if i.options.Network == "unixgram" {
+		os.Remove(i.options.Addr)
+	}
+}
+
+// Reset discards all of the values received so far
+func (i *Importer) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.aggregates = make(map[string]*aggregateEntry)
+}
+
+// Received returns the number of datagrams that have been recorded
+// Datagrams are recorded asynchronously; this is useful to wait for those that have been sent
+func (i *Importer) Received() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.received
+}
+
+// listen reads datagrams until the socket is closed
+func (i *Importer) listen() {
+	defer close(i.done)
+	b := make([]byte, maxPacketSize)
+	for {
+		n, _, err := i.conn.ReadFrom(b)
+		if err != nil {
+			// Closing the socket is the only way to stop listening
+			return
+		}
+		metrics, err := ParsePacket(b[:n])
+		if err != nil {
+			glog.Warningf("[listen] %s", err)
+		}
+		i.record(metrics, time.Now())
+	}
+}
+
+// record adds a datagram's parsed metrics to their aggregates
+// Metrics are keyed by type too so a metric received as another type doesn't replace the aggregate; Aggregate reports it
+func (i *Importer) record(metrics []*Metric, t time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.received++
+	for _, m := range metrics {
+		k := m.Key()
+		entry, ok := i.aggregates[k]
+		if !ok {
+			tags := map[string]bool{}
+			for _, tag := range m.Tags {
+				tags[tag] = true
+			}
+			entry = &aggregateEntry{
+				name:      m.Name,
+				tags:      tags,
+				aggregate: newAggregate(m.Type),
+			}
+			i.aggregates[k] = entry
+		}
+		entry.aggregate.add(m, t)
+	}
+}
+
+// Aggregate returns the combined Aggregate of every metric for the View that has the label values as tags
+// Metrics may carry additional tags (e.g. the exporter's GlobalTags); these are merged together
+// Values are captured as they arrive so the time is ignored; use Reset to discard earlier values
+func (i *Importer) Aggregate(v *view.View, labelValues []string, t time.Time) (*Aggregate, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return nil, errors.New("Inconsistency between labels and values")
+	}
+	name := v.Name
+	// Prefix Namespace, if one exists, in the same way as the exporter
+	if i.options.Namespace != "" {
+		name = i.options.Namespace + "." + name
+	}
+	want := make([]string, len(v.LabelNames))
+	for j, labelName := range v.LabelNames {
+		want[j] = labelName + ":" + labelValues[j]
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var result *Aggregate
+	for _, entry := range i.aggregates {
+		if entry.name != name || !hasTags(entry.tags, want) {
+			continue
+		}
+		if result == nil {
+			result = newAggregate(entry.aggregate.Type)
+		}
+		if result.Type != entry.aggregate.Type {
+			return nil, fmt.Errorf("Metric '%s' has been received as both '%s' and '%s'", name, result.Type, entry.aggregate.Type)
+		}
+		result.merge(entry.aggregate)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("No metric '%s' received with tags '%s'", name, strings.Join(want, ","))
+	}
+	return result, nil
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	a, err := i.Aggregate(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return a.Value(), nil
+}
+
+// hasTags returns true if every one of the wanted tags is present
+func hasTags(tags map[string]bool, want []string) bool {
+	for _, tag := range want {
+		if !tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// Namespace should match the Namespace used by the OpenCensus Datadog Exporter
+	Namespace string
+	// Network is either "udp" (default) or "unixgram"
+	Network string
+	// Addr is the address (or socket path for "unixgram") to listen on; defaults to "localhost:8125"
+	Addr string
+}
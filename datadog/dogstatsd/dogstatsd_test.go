@@ -0,0 +1,173 @@
+package dogstatsd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	namespace = "namespace"
+)
+
+// send writes the datagram to the Importer and waits for it to be recorded
+func send(t *testing.T, i *Importer, datagram string) {
+	conn, err := net.Dial(i.Addr().Network(), i.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	want := i.Received() + 1
+	if _, err := conn.Write([]byte(datagram)); err != nil {
+		t.Fatal(err)
+	}
+	// Datagrams are processed asynchronously
+	for deadline := time.Now().Add(5 * time.Second); i.Received() < want; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d datagrams; want %d", i.Received(), want)
+		}
+	}
+}
+func Test_NewImporter(t *testing.T) {
+	i, err := NewImporter(Options{
+		Namespace: namespace,
+		Addr:      "localhost:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Stop()
+	if got, want := i.Name(), "dogstatsd"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := i.options.Network, "udp"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestImporter_Value(t *testing.T) {
+	i, err := NewImporter(Options{
+		Namespace: namespace,
+		Addr:      "localhost:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Stop()
+
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	labelValues := []string{"value1", "value2"}
+
+	t.Run("No Values", func(t *testing.T) {
+		if _, err := i.Value(v, labelValues, time.Now()); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Gauge", func(t *testing.T) {
+		send(t, i, "namespace.counter0:1|g|#key1:value1,key2:value2\nnamespace.counter0:2|g|#key2:value2,key1:value1")
+		got, err := i.Value(v, labelValues, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Other Label Values", func(t *testing.T) {
+		if _, err := i.Value(v, []string{"value1", "X"}, time.Now()); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	i.Reset()
+	t.Run("Counter with Sample Rate and Global Tags", func(t *testing.T) {
+		send(t, i, "namespace.counter0:1|c|@0.5|#key1:value1,key2:value2,env:test\nnamespace.counter0:3|c|#key1:value1,key2:value2,env:prod")
+		got, err := i.Value(v, labelValues, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 5.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	i.Reset()
+	t.Run("Distribution", func(t *testing.T) {
+		send(t, i, "namespace.counter0:1:2:3|d|#key1:value1,key2:value2")
+		a, err := i.Aggregate(v, labelValues, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := a.Value(), 6.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := a.Count, 3.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := a.Min, 1.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := a.Max, 3.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	i.Reset()
+	t.Run("Set", func(t *testing.T) {
+		send(t, i, "namespace.counter0:a|s|#key1:value1,key2:value2\nnamespace.counter0:b|s|#key1:value1,key2:value2\nnamespace.counter0:a|s|#key1:value1,key2:value2")
+		got, err := i.Value(v, labelValues, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	i.Reset()
+	t.Run("Type Change", func(t *testing.T) {
+		send(t, i, "namespace.counter0:1|c|#key1:value1,key2:value2\nnamespace.counter0:2|g|#key1:value1,key2:value2")
+		if _, err := i.Value(v, labelValues, time.Now()); err == nil {
+			t.Errorf("got nil; want error")
+		}
+		if got, want := len(i.aggregates), 2; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
+func TestImporter_Unixgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dogstatsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dsd.socket")
+	i, err := NewImporter(Options{
+		Namespace: namespace,
+		Network:   "unixgram",
+		Addr:      path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send(t, i, "namespace.counter0:1|g|#key1:value1,key2:value2")
+	got, err := i.Value(&view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}, []string{"value1", "value2"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1.0; got != want {
+		t.Errorf("got %f; want %f", got, want)
+	}
+
+	i.Stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("got %v; want the socket to be removed", err)
+	}
+}
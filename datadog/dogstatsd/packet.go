@@ -0,0 +1,126 @@
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type represents the type of a DogStatsD metric
+type Type string
+
+// DogStatsD metric types
+const (
+	Counter      Type = "c"
+	Gauge        Type = "g"
+	Histogram    Type = "h"
+	Timer        Type = "ms"
+	Set          Type = "s"
+	Distribution Type = "d"
+)
+
+// Metric represents a single DogStatsD metric line
+// e.g. "namespace.name:1.5|g|@0.5|#key1:value1,key2:value2"
+// Lines using the multiple-value format ("name:1:2:3|d") produce one Metric with several Values
+type Metric struct {
+	Name       string
+	Values     []string
+	Type       Type
+	SampleRate float64
+	Tags       []string
+}
+
+// Key returns the metric name combined with its type and (sorted) tags
+// Metrics with the same Key are aggregated together
+func (m *Metric) Key() string {
+	return key(m.Name, m.Type, m.Tags)
+}
+
+// key combines a metric name, type and set of tags into a single (order-independent) string
+func key(name string, typ Type, tags []string) string {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	return name + "|" + string(typ) + "|#" + strings.Join(sorted, ",")
+}
+
+// ParsePacket parses a DogStatsD datagram containing one or more newline-separated metric lines
+// Events ("_e{...}") and service checks ("_sc|...") are not metrics and are skipped
+// Invalid lines are skipped too; the metrics of the other lines are returned with an error that describes every invalid line
+func ParsePacket(b []byte) ([]*Metric, error) {
+	metrics := []*Metric{}
+	errs := []string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+			continue
+		}
+		m, err := Parse(line)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	if len(errs) > 0 {
+		return metrics, errors.New(strings.Join(errs, "; "))
+	}
+	return metrics, nil
+}
+
+// Parse parses a single DogStatsD metric line
+func Parse(line string) (*Metric, error) {
+	// name:value[:value...]|type[|@rate][|#tags][|c:container][|Ttimestamp]
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("[Parse] Expected '<name>:<value>|<type>', got '%s'", line)
+	}
+	i := strings.Index(parts[0], ":")
+	if i <= 0 {
+		return nil, fmt.Errorf("[Parse] Expected '<name>:<value>', got '%s'", parts[0])
+	}
+	m := &Metric{
+		Name:       parts[0][:i],
+		Values:     strings.Split(parts[0][i+1:], ":"),
+		Type:       Type(parts[1]),
+		SampleRate: 1.0,
+		Tags:       []string{},
+	}
+	switch m.Type {
+	case Counter, Gauge, Histogram, Timer, Set, Distribution:
+	default:
+		return nil, fmt.Errorf("[Parse] Unsupported metric type '%s'", m.Type)
+	}
+	for _, value := range m.Values {
+		if value == "" {
+			return nil, fmt.Errorf("[Parse] Metric '%s' has an empty value", m.Name)
+		}
+		// Sets count unique values of any kind; every other type requires a number
+		if m.Type != Set {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("[Parse] Metric '%s' has a non-numeric value '%s'", m.Name, value)
+			}
+		}
+	}
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("[Parse] Metric '%s' has an invalid sample rate '%s'", m.Name, part)
+			}
+			m.SampleRate = rate
+		case strings.HasPrefix(part, "#"):
+			for _, tag := range strings.Split(part[1:], ",") {
+				if tag != "" {
+					m.Tags = append(m.Tags, tag)
+				}
+			}
+		default:
+			// Container IDs ("c:...") and timestamps ("T...") don't affect aggregation
+		}
+	}
+	return m, nil
+}
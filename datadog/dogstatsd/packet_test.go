@@ -0,0 +1,111 @@
+package dogstatsd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Run("Gauge", func(t *testing.T) {
+		m, err := Parse("namespace.counter0:1.5|g")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Name, "namespace.counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.Values, []string{"1.5"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := m.Type, Gauge; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.SampleRate, 1.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Sample Rate and Tags", func(t *testing.T) {
+		m, err := Parse("counter0:1|c|@0.5|#key1:value1,key2:value2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.SampleRate, 0.5; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := m.Tags, []string{"key1:value1", "key2:value2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Multiple Values", func(t *testing.T) {
+		m, err := Parse("latency:1:2:3|d|c:container|T1656581400")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Values, []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Set", func(t *testing.T) {
+		if _, err := Parse("users:alice|s"); err != nil {
+			t.Errorf("got %s; want nil", err)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, line := range []string{
+			"counter0",
+			"counter0:1",
+			":1|c",
+			"counter0:|c",
+			"counter0:x|c",
+			"counter0:1|x",
+			"counter0:1|c|@2",
+		} {
+			if _, err := Parse(line); err == nil {
+				t.Errorf("'%s' got nil; want error", line)
+			}
+		}
+	})
+}
+func Test_ParsePacket(t *testing.T) {
+	b := []byte("a:1|c\n_e{5,4}:title|text\n_sc|check|0\nb:2|g\n")
+	metrics, err := ParsePacket(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(metrics), 2; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := metrics[1].Name, "b"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	t.Run("Invalid Lines", func(t *testing.T) {
+		b := []byte("a:1|c\nb:x|g\nc:3|g\nd|c\ne:5|ms\n")
+		metrics, err := ParsePacket(b)
+		if err == nil {
+			t.Errorf("got nil; want error")
+		}
+		if got, want := len(metrics), 3; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		for j, want := range []string{"a", "c", "e"} {
+			if got := metrics[j].Name; got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		}
+		if got, want := strings.Count(err.Error(), "[Parse]"), 2; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
+func TestMetric_Key(t *testing.T) {
+	m1, _ := Parse("a:1|c|#x:1,y:2")
+	m2, _ := Parse("a:1|c|#y:2,x:1")
+	if got, want := m1.Key(), m2.Key(); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	m3, _ := Parse("a:1|g|#x:1,y:2")
+	if m1.Key() == m3.Key() {
+		t.Errorf("got %s; want a different key for another type", m3.Key())
+	}
+}
@@ -0,0 +1,109 @@
+package datadog
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// Datadog metric types
+const (
+	TypeCount        = "count"
+	TypeRate         = "rate"
+	TypeGauge        = "gauge"
+	TypeDistribution = "distribution"
+)
+
+// Metadata represents the subset of Datadog's metric metadata that's needed to interpret data points
+type Metadata struct {
+	Type    string
+	Unit    string
+	PerUnit string
+	// Interval is the flush interval of the metric; it's needed to convert rates (per second) into counts
+	Interval time.Duration
+}
+
+// Metadata returns the metric's metadata, fetching it from Datadog the first time it's requested
+// Metrics without metadata (Datadog responds 404) return (and cache) an empty Metadata; other failures are errors
+// because, without the metric's type, rates can't be distinguished from gauges
+func (i *Importer) Metadata(metric string) (*Metadata, error) {
+	i.mu.Lock()
+	m, ok := i.metadata[metric]
+	i.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	mm, err := i.client.ViewMetricMetadata(metric)
+	if err != nil {
+		if !notFound(err) {
+			return nil, fmt.Errorf("Unable to get metadata for '%s': %s", metric, err)
+		}
+		log.Printf("[Metadata] No metadata for '%s'", metric)
+		mm = &datadog.MetricMetadata{}
+	}
+	m = &Metadata{}
+	if mm.Type != nil {
+		m.Type = *mm.Type
+	}
+	if mm.Unit != nil {
+		m.Unit = *mm.Unit
+	}
+	if mm.PerUnit != nil {
+		m.PerUnit = *mm.PerUnit
+	}
+	if mm.StatsdInterval != nil {
+		m.Interval = time.Duration(*mm.StatsdInterval) * time.Second
+	}
+
+	i.mu.Lock()
+	i.metadata[metric] = m
+	i.mu.Unlock()
+	return m, nil
+}
+
+// notFoundError is the start of the client's error for a 404 response
+// The client doesn't expose the status code but includes the status in its errors e.g. "API error 404 Not Found: ..."
+const notFoundError = "API error 404 "
+
+// notFound returns true if the client's error is a 404
+func notFound(err error) bool {
+	return strings.HasPrefix(err.Error(), notFoundError)
+}
+
+// Selector returns the Selector appropriate for the metric's type
+func (m *Metadata) Selector() Selector {
+	switch m.Type {
+	case TypeCount, TypeRate:
+		return Sum
+	default:
+		return Latest
+	}
+}
+
+// Interpret converts points into values that may be compared with those recorded by OpenCensus
+// Rates (per second) are multiplied by the interval to give counts; other types are unchanged
+// interval is used when the metadata doesn't include one
+func (m *Metadata) Interpret(points []Point, interval time.Duration) []Point {
+	if m.Type != TypeRate {
+		return points
+	}
+	if m.Interval != 0 {
+		interval = m.Interval
+	}
+	if interval == 0 {
+		log.Printf("[Interpret] Unable to convert rate to count without an interval")
+		return points
+	}
+	counts := make([]Point, len(points))
+	for j, p := range points {
+		counts[j] = Point{
+			Time:  p.Time,
+			Value: p.Value * interval.Seconds(),
+		}
+	}
+	return counts
+}
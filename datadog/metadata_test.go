@@ -0,0 +1,160 @@
+package datadog
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/datadog/datadogtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+func TestImporter_Metadata(t *testing.T) {
+	s := datadogtest.NewServer()
+	defer s.Close()
+	i := newTestImporter(t, s)
+
+	s.SetMetadata("namespace.counter0", datadogtest.Metadata{
+		Type:           TypeRate,
+		Unit:           "request",
+		StatsdInterval: 10,
+	})
+
+	t.Run("Fetch", func(t *testing.T) {
+		m, err := i.Metadata("namespace.counter0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Type, TypeRate; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.Interval, 10*time.Second; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Cached", func(t *testing.T) {
+		if _, err := i.Metadata("namespace.counter0"); err != nil {
+			t.Fatal(err)
+		}
+		s.SetStatus(http.StatusInternalServerError)
+		defer s.SetStatus(http.StatusOK)
+		m, err := i.Metadata("namespace.counter0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Type, TypeRate; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("No Metadata", func(t *testing.T) {
+		m, err := i.Metadata("X")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Selector(), Latest; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		// The absence of metadata is cached too
+		s.SetStatus(http.StatusInternalServerError)
+		defer s.SetStatus(http.StatusOK)
+		if _, err := i.Metadata("X"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, code := range []int{http.StatusForbidden, http.StatusInternalServerError} {
+		t.Run(http.StatusText(code), func(t *testing.T) {
+			s.SetStatus(code)
+			defer s.SetStatus(http.StatusOK)
+			if _, err := i.Metadata("namespace.counter1"); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func Test_notFound(t *testing.T) {
+	for _, test := range []struct {
+		err  string
+		want bool
+	}{
+		// As returned by the client for Datadog's responses
+		{`API error 404 Not Found: {"errors": ["Metric not found"]}`, true},
+		{`API error 403 Forbidden: {"errors": ["Forbidden"]}`, false},
+		{`API error 400 Bad Request: {"errors": ["API error 404 Not Found"]}`, false},
+		{"Received HTTP status code 500", false},
+	} {
+		if got := notFound(errors.New(test.err)); got != test.want {
+			t.Errorf("'%s' got %t; want %t", test.err, got, test.want)
+		}
+	}
+}
+func TestMetadata_Selector(t *testing.T) {
+	for _, test := range []struct {
+		metricType string
+		want       Selector
+	}{
+		{TypeCount, Sum},
+		{TypeRate, Sum},
+		{TypeGauge, Latest},
+		{TypeDistribution, Latest},
+		{"", Latest},
+	} {
+		m := &Metadata{Type: test.metricType}
+		if got := m.Selector(); got != test.want {
+			t.Errorf("'%s' got %s; want %s", test.metricType, got, test.want)
+		}
+	}
+}
+func TestMetadata_Interpret(t *testing.T) {
+	points := []Point{
+		{Time: time.Unix(10, 0), Value: 0.5},
+		{Time: time.Unix(20, 0), Value: 1.5},
+	}
+	t.Run("Gauge", func(t *testing.T) {
+		m := &Metadata{Type: TypeGauge, Interval: 10 * time.Second}
+		if got, want := m.Interpret(points, 0)[1].Value, 1.5; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Rate", func(t *testing.T) {
+		m := &Metadata{Type: TypeRate, Interval: 10 * time.Second}
+		if got, want := m.Interpret(points, 0)[1].Value, 15.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Rate using Series Interval", func(t *testing.T) {
+		m := &Metadata{Type: TypeRate}
+		if got, want := m.Interpret(points, 20*time.Second)[0].Value, 10.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+}
+func TestImporter_Value_Rate(t *testing.T) {
+	s := datadogtest.NewServer()
+	defer s.Close()
+	i := newTestImporter(t, s)
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	ms := now.UnixNano() / int64(time.Millisecond)
+	s.SetMetadata("namespace.counter2", datadogtest.Metadata{
+		Type:           TypeRate,
+		StatsdInterval: 10,
+	})
+	s.AddSeries("namespace.counter2", map[string]string{
+		"host": host,
+	},
+		datadogtest.NewPoint(ms-20000, 0.1),
+		datadogtest.NewPoint(ms-10000, 0.2),
+	)
+
+	got, err := i.Value(&view.View{Name: "counter2"}, []string{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (0.1 + 0.2) per second * 10 seconds
+	if want := 3.0; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("got %f; want %f", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package datadog
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+// Point represents a single (non-null) Datadog data point
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Selector determines how a Point is chosen from the data points in the query window
+type Selector int
+
+// Selectors
+const (
+	// Auto selects according to the metric's type: Sum for counts and rates, otherwise Latest (the default)
+	Auto Selector = iota
+	// Latest selects the most recent point
+	Latest
+	// Earliest selects the oldest point
+	Earliest
+	// Nearest selects the point closest to the time of the read
+	Nearest
+	// Max selects the point with the largest value
+	Max
+	// Sum adds the values of every point; the result has the time of the most recent point
+	Sum
+)
+
+// String returns the Selector's name
+func (s Selector) String() string {
+	switch s {
+	case Auto:
+		return "auto"
+	case Latest:
+		return "latest"
+	case Earliest:
+		return "earliest"
+	case Nearest:
+		return "nearest"
+	case Max:
+		return "max"
+	case Sum:
+		return "sum"
+	default:
+		return "unknown"
+	}
+}
+
+// Select chooses a Point from the points using the Selector
+// t is the time of the read and is used by Nearest
+// Auto is resolved by the Importer using the metric's metadata; here it behaves as Latest
+func (s Selector) Select(points []Point, t time.Time) (Point, error) {
+	if len(points) == 0 {
+		return Point{}, errors.New("No data points in the timeseries")
+	}
+	result := points[0]
+	for _, p := range points[1:] {
+		switch s {
+		case Auto, Latest:
+			if p.Time.After(result.Time) {
+				result = p
+			}
+		case Earliest:
+			if p.Time.Before(result.Time) {
+				result = p
+			}
+		case Nearest:
+			if distance(p.Time, t) < distance(result.Time, t) {
+				result = p
+			}
+		case Max:
+			if p.Value > result.Value {
+				result = p
+			}
+		case Sum:
+			result.Value += p.Value
+			if p.Time.After(result.Time) {
+				result.Time = p.Time
+			}
+		default:
+			return Point{}, errors.New("Unknown Selector")
+		}
+	}
+	return result, nil
+}
+
+// distance returns the absolute duration between two times
+func distance(a, b time.Time) time.Duration {
+	d := a.Sub(b)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// toPoints converts Datadog data points to Points, skipping null and NaN values
+func toPoints(dps []datadog.DataPoint) []Point {
+	points := []Point{}
+	for _, dp := range dps {
+		// dp[0] == Unix epoch timestamp in ms
+		// dp[1] == data
+		if dp[0] == nil || dp[1] == nil || math.IsNaN(*dp[1]) {
+			continue
+		}
+		points = append(points, Point{
+			Time:  time.Unix(0, int64(*dp[0])*int64(time.Millisecond)),
+			Value: *dp[1],
+		})
+	}
+	return points
+}
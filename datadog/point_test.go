@@ -0,0 +1,75 @@
+package datadog
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	datadog "gopkg.in/zorkian/go-datadog-api.v2"
+)
+
+func Test_toPoints(t *testing.T) {
+	f := func(f float64) *float64 {
+		return &f
+	}
+	dps := []datadog.DataPoint{
+		{f(1000), f(1.0)},
+		{f(2000), nil},
+		{f(3000), f(math.NaN())},
+		{f(4000), f(4.0)},
+	}
+	points := toPoints(dps)
+	if got, want := len(points), 2; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := points[1].Time, time.Unix(4, 0); !got.Equal(want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if got, want := points[1].Value, 4.0; got != want {
+		t.Errorf("got %f; want %f", got, want)
+	}
+}
+func TestSelector_Select(t *testing.T) {
+	now := time.Unix(100, 0)
+	points := []Point{
+		{Time: time.Unix(40, 0), Value: 2.0},
+		{Time: time.Unix(90, 0), Value: 1.0},
+		{Time: time.Unix(60, 0), Value: 4.0},
+		{Time: time.Unix(99, 0), Value: 3.0},
+	}
+	tests := []struct {
+		selector Selector
+		want     Point
+	}{
+		{Latest, Point{Time: time.Unix(99, 0), Value: 3.0}},
+		{Earliest, Point{Time: time.Unix(40, 0), Value: 2.0}},
+		{Nearest, Point{Time: time.Unix(99, 0), Value: 3.0}},
+		{Max, Point{Time: time.Unix(60, 0), Value: 4.0}},
+		{Sum, Point{Time: time.Unix(99, 0), Value: 10.0}},
+	}
+	for _, test := range tests {
+		t.Run(test.selector.String(), func(t *testing.T) {
+			got, err := test.selector.Select(points, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Time.Equal(test.want.Time) || got.Value != test.want.Value {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+	t.Run("Nearest in the Past", func(t *testing.T) {
+		got, err := Nearest.Select(points, time.Unix(58, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 4.0; got.Value != want {
+			t.Errorf("got %f; want %f", got.Value, want)
+		}
+	})
+	t.Run("No Points", func(t *testing.T) {
+		if _, err := Latest.Select([]Point{}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package elasticsearch
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Aggregation determines how the documents that match a View's name and labels are reduced to a value
+type Aggregation string
+
+// Aggregations
+const (
+	// TopHits returns the value of the most recent document (the default)
+	TopHits Aggregation = "top_hits"
+	// Max, Min, Avg and Sum aggregate the value of every document in the window
+	Max Aggregation = "max"
+	Min Aggregation = "min"
+	Avg Aggregation = "avg"
+	Sum Aggregation = "sum"
+)
+
+const (
+	defaultURL            = "http://localhost:9200"
+	defaultIndex          = "metrics-*"
+	defaultNameField      = "name"
+	defaultValueField     = "value"
+	defaultTimestampField = "@timestamp"
+	defaultLabelPrefix    = "labels."
+	defaultWindow         = 5 * time.Minute
+)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from documents in Elasticsearch (or OpenSearch) indices
+// Each document is a point with a name, a value, a timestamp and a field for each label
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+// The URL defaults to the value of environment variable ELASTICSEARCH_URL
+func NewImporter(o Options) (*Importer, error) {
+	if o.URL == "" {
+		o.URL = os.Getenv("ELASTICSEARCH_URL")
+	}
+	if o.URL == "" {
+		o.URL = defaultURL
+	}
+	o.URL = strings.TrimSuffix(o.URL, "/")
+	if o.Index == "" {
+		o.Index = defaultIndex
+	}
+	if o.NameField == "" {
+		o.NameField = defaultNameField
+	}
+	if o.ValueField == "" {
+		o.ValueField = defaultValueField
+	}
+	if o.TimestampField == "" {
+		o.TimestampField = defaultTimestampField
+	}
+	if o.LabelPrefix == "" {
+		o.LabelPrefix = defaultLabelPrefix
+	}
+	switch o.Aggregation {
+	case "":
+		o.Aggregation = TopHits
+	case TopHits, Max, Min, Avg, Sum:
+	default:
+		return nil, errors.New("Unknown Aggregation")
+	}
+	if o.APIKey != "" && o.Username != "" {
+		return nil, errors.New("Expect either an API key or a username and password")
+	}
+	return &Importer{
+		name:    "elasticsearch",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the Point for the View with the label values in the window ending at the time specified
+// With TopHits, it's the most recent document; otherwise, it's the aggregate of the documents at the time of the most recent
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return Point{}, errors.New("Inconsistency between labels and values")
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+	s := NewSearch(i.options.NameField, i.options.MetricPrefix+v.Name)
+	for j, labelName := range v.LabelNames {
+		s.AddTerm(i.options.LabelPrefix+labelName+i.options.LabelSuffix, labelValues[j])
+	}
+	s.AddRange(i.options.TimestampField, t.Add(-window), t)
+	s.SetAggregation(i.options.Aggregation, i.options.ValueField, i.options.TimestampField)
+	log.Println(s.String())
+	return i.search(s)
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// URL is the cluster's base URL; defaults to environment variable ELASTICSEARCH_URL or http://localhost:9200
+	URL string
+	// Username and Password (basic authentication) or APIKey authenticate requests
+	Username string
+	Password string
+	APIKey   string
+	// Index is an index pattern (e.g. metrics-*, or a comma-separated list) that's searched; defaults to metrics-*
+	Index string
+	// MetricPrefix is prepended to the View's name to give the value of the name field
+	MetricPrefix string
+	// NameField, ValueField and TimestampField map the documents' fields; default to name, value and @timestamp
+	NameField      string
+	ValueField     string
+	TimestampField string
+	// LabelPrefix and LabelSuffix map label names to fields; default to labels.[label]
+	// Use a LabelSuffix of .keyword when labels are dynamically mapped text fields
+	LabelPrefix string
+	LabelSuffix string
+	// Aggregation reduces the matching documents to a value; defaults to TopHits
+	Aggregation Aggregation
+	// Window is the duration (ending at the time of the read) that's searched; defaults to 5 minutes
+	Window time.Duration
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
@@ -0,0 +1,231 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/elasticsearch/elasticsearchtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// newTestImporter creates an Importer that talks to an elasticsearchtest.Server
+func newTestImporter(t *testing.T, s *elasticsearchtest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		os.Unsetenv("ELASTICSEARCH_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "elasticsearch"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		for _, test := range []struct {
+			got, want string
+		}{
+			{i.options.URL, "http://localhost:9200"},
+			{i.options.Index, "metrics-*"},
+			{i.options.NameField, "name"},
+			{i.options.ValueField, "value"},
+			{i.options.TimestampField, "@timestamp"},
+			{i.options.LabelPrefix, "labels."},
+			{string(i.options.Aggregation), "top_hits"},
+		} {
+			if test.got != test.want {
+				t.Errorf("got %s; want %s", test.got, test.want)
+			}
+		}
+	})
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("ELASTICSEARCH_URL", "http://es:9200/")
+		defer os.Unsetenv("ELASTICSEARCH_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.options.URL, "http://es:9200"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name    string
+		options Options
+	}{
+		{"Unknown Aggregation", Options{Aggregation: "median"}},
+		{"APIKey And Username", Options{APIKey: "key", Username: "elastic"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewImporter(test.options); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func TestImporter_Value(t *testing.T) {
+	s := elasticsearchtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	const (
+		filter  = `{"term":{"name":"counter0"}},{"term":{"labels.key1":"value1"}},{"term":{"labels.key2":"value2"}},{"range":{"@timestamp":{"format":"strict_date_optional_time","gt":"2019-01-02T02:59:05Z","lte":"2019-01-02T03:04:05Z"}}}`
+		topHits = `{"value":{"top_hits":{"_source":["@timestamp","value"],"size":1,"sort":[{"@timestamp":{"order":"desc"}}]}}}`
+	)
+	source := func(ts time.Time, value float64) map[string]interface{} {
+		return map[string]interface{}{
+			"@timestamp": ts.UTC().Format(time.RFC3339Nano),
+			"value":      value,
+		}
+	}
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		index   string
+		search  string
+		seed    func(index, search string)
+		want    Point
+	}{
+		{
+			"TopHits",
+			Options{},
+			"metrics-*",
+			`{"size":0,"query":{"bool":{"filter":[` + filter + `]}},"aggs":` + topHits + `}`,
+			func(index, search string) {
+				s.SetTopHitsResult(index, search, 3, source(now.Add(-30*time.Second), 2))
+			},
+			Point{now.Add(-30 * time.Second), 2},
+		},
+		{
+			"Max",
+			Options{Aggregation: Max},
+			"metrics-*",
+			`{"size":0,"query":{"bool":{"filter":[` + filter + `]}},"aggs":{"timestamp":{"max":{"field":"@timestamp"}},"value":{"max":{"field":"value"}}}}`,
+			func(index, search string) {
+				s.SetMetricResult(index, search, 3, 4, now.Add(-30*time.Second))
+			},
+			Point{now.Add(-30 * time.Second), 4},
+		},
+		{
+			"Avg",
+			Options{Aggregation: Avg},
+			"metrics-*",
+			`{"size":0,"query":{"bool":{"filter":[` + filter + `]}},"aggs":{"timestamp":{"max":{"field":"@timestamp"}},"value":{"avg":{"field":"value"}}}}`,
+			func(index, search string) {
+				s.SetMetricResult(index, search, 3, 7.0/3, now.Add(-30*time.Second))
+			},
+			Point{now.Add(-30 * time.Second), 7.0 / 3},
+		},
+		{
+			"Index List",
+			Options{Index: "metrics-2026.09,missing"},
+			"metrics-2026.09,missing",
+			`{"size":0,"query":{"bool":{"filter":[` + filter + `]}},"aggs":` + topHits + `}`,
+			func(index, search string) {
+				s.SetTopHitsResult(index, search, 1, source(now.Add(-60*time.Second), 4))
+			},
+			Point{now.Add(-60 * time.Second), 4},
+		},
+		{
+			"Window",
+			Options{Window: 20 * time.Minute},
+			"metrics-*",
+			`{"size":0,"query":{"bool":{"filter":[{"term":{"name":"counter0"}},{"term":{"labels.key1":"value1"}},{"term":{"labels.key2":"value2"}},{"range":{"@timestamp":{"format":"strict_date_optional_time","gt":"2019-01-02T02:44:05Z","lte":"2019-01-02T03:04:05Z"}}}]}},"aggs":` + topHits + `}`,
+			func(index, search string) {
+				s.SetTopHitsResult(index, search, 4, source(now.Add(-10*time.Minute), 32))
+			},
+			Point{now.Add(-10 * time.Minute), 32},
+		},
+		{
+			"MetricPrefix And LabelSuffix",
+			Options{MetricPrefix: "prefix.", LabelSuffix: ".keyword"},
+			"metrics-*",
+			`{"size":0,"query":{"bool":{"filter":[{"term":{"name":"prefix.counter0"}},{"term":{"labels.key1.keyword":"value1"}},{"term":{"labels.key2.keyword":"value2"}},{"range":{"@timestamp":{"format":"strict_date_optional_time","gt":"2019-01-02T02:59:05Z","lte":"2019-01-02T03:04:05Z"}}}]}},"aggs":` + topHits + `}`,
+			func(index, search string) {
+				s.SetTopHitsResult(index, search, 1, source(now.Add(-10*time.Second), 128))
+			},
+			Point{now.Add(-10 * time.Second), 128},
+		},
+		{
+			"Field Mapping",
+			Options{Index: "otel", NameField: "metric", ValueField: "gauge", TimestampField: "ts", LabelPrefix: "attributes."},
+			"otel",
+			`{"size":0,"query":{"bool":{"filter":[{"term":{"metric":"counter0"}},{"term":{"attributes.key1":"value1"}},{"term":{"attributes.key2":"value2"}},{"range":{"ts":{"format":"strict_date_optional_time","gt":"2019-01-02T02:59:05Z","lte":"2019-01-02T03:04:05Z"}}}]}},"aggs":{"value":{"top_hits":{"_source":["ts","gauge"],"size":1,"sort":[{"ts":{"order":"desc"}}]}}}}`,
+			func(index, search string) {
+				// Flattened label keys and a timestamp in milliseconds
+				s.SetTopHitsResult(index, search, 1, map[string]interface{}{"ts": now.Add(-5*time.Second).UnixNano() / int64(time.Millisecond), "gauge": 256})
+			},
+			Point{now.Add(-5 * time.Second), 256},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.seed(test.index, test.search)
+			i := newTestImporter(t, s, test.options)
+			got, err := i.Point(v, []string{"value1", "value2"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Time.Equal(test.want.Time) || got.Value != test.want.Value {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+			searches := s.Queries()
+			if got, want := searches[len(searches)-1], test.index+" "+test.search; got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+	value9 := strings.Replace(filter, `"value2"`, `"value9"`, 1)
+	s.SetTopHitsResult("metrics-*", `{"size":0,"query":{"bool":{"filter":[`+value9+`]}},"aggs":`+topHits+`}`, 0)
+	s.SetMetricResult("metrics-*", `{"size":0,"query":{"bool":{"filter":[`+value9+`]}},"aggs":{"timestamp":{"max":{"field":"@timestamp"}},"value":{"max":{"field":"value"}}}}`, 0, 0, time.Time{})
+	s.SetTopHitsResult("metrics-*", `{"size":0,"query":{"bool":{"filter":[`+filter+`]}},"aggs":{"value":{"top_hits":{"_source":["@timestamp","missing"],"size":1,"sort":[{"@timestamp":{"order":"desc"}}]}}}}`, 1, map[string]interface{}{"@timestamp": now.UTC().Format(time.RFC3339)})
+	for _, test := range []struct {
+		name        string
+		options     Options
+		labelValues []string
+	}{
+		{"No Documents", Options{}, []string{"value1", "value9"}},
+		{"No Documents Max", Options{Aggregation: Max}, []string{"value1", "value9"}},
+		{"Unexpected Search", Options{Index: "logs-*"}, []string{"value1", "value2"}},
+		{"Label Mismatch", Options{}, []string{"value1"}},
+		{"Missing Value Field", Options{ValueField: "missing"}, []string{"value1", "value2"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			if _, err := i.Value(v, test.labelValues, now); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusServiceUnavailable)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("APIKey", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{APIKey: "secret"})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+			t.Fatal(err)
+		}
+		headers := s.Headers()
+		if got, want := headers[len(headers)-1].Get("Authorization"), "ApiKey secret"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
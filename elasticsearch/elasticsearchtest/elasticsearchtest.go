@@ -0,0 +1,86 @@
+package elasticsearchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for the Elasticsearch (and OpenSearch) _search API
+// It returns the results seeded for each exact search: the index pattern and the (JSON) body e.g. "metrics-* {...}"
+type Server struct {
+	*standin.Server
+}
+
+// NewServer creates and starts a new Server with no results
+func NewServer() *Server {
+	return &Server{standin.NewServer(search)}
+}
+
+// search returns the text of a search: its index pattern and body
+func search(r *http.Request) (string, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.HasSuffix(path, "/_search") {
+		return "", fmt.Errorf("No handler found for uri [%s]", r.URL.Path)
+	}
+	index := strings.TrimSuffix(path, "/_search")
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	return index + " " + string(b), nil
+}
+
+// SetTopHitsResult seeds the result of a search whose "value" aggregation is top_hits
+// total is the number of documents that match and sources are the (most recent) documents' fields
+func (s *Server) SetTopHitsResult(index, search string, total int, sources ...map[string]interface{}) {
+	hits := []interface{}{}
+	for _, source := range sources {
+		hits = append(hits, map[string]interface{}{
+			"_index":  index,
+			"_source": source,
+		})
+	}
+	s.set(index, search, total, map[string]interface{}{
+		"value": map[string]interface{}{
+			"hits": map[string]interface{}{
+				"hits": hits,
+			},
+		},
+	})
+}
+
+// SetMetricResult seeds the result of a search whose "value" aggregation is a single-value metric e.g. max
+// The "timestamp" aggregation is the max of the timestamp field; dates are milliseconds since the epoch
+func (s *Server) SetMetricResult(index, search string, total int, value float64, timestamp time.Time) {
+	s.set(index, search, total, map[string]interface{}{
+		"value": map[string]interface{}{
+			"value": value,
+		},
+		"timestamp": map[string]interface{}{
+			"value":           timestamp.UnixNano() / int64(time.Millisecond),
+			"value_as_string": timestamp.UTC().Format(time.RFC3339Nano),
+		},
+	})
+}
+
+// set seeds the response to a search with the (7.x) hits total and the aggregations
+func (s *Server) set(index, search string, total int, aggregations map[string]interface{}) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"timed_out": false,
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{
+				"value":    total,
+				"relation": "eq",
+			},
+			"hits": []interface{}{},
+		},
+		"aggregations": aggregations,
+	})
+	s.SetResponse(index+" "+search, "application/json", string(b))
+}
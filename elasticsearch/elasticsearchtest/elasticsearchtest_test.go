@@ -0,0 +1,56 @@
+package elasticsearchtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	s.SetTopHitsResult("metrics-*", `{"size":0}`, 1, map[string]interface{}{"value": 1.5})
+	s.SetMetricResult("metrics-2026.10,otel", `{"size":0}`, 2, 2.5, now)
+	post := func(path, body string) (int, string) {
+		resp, err := s.Client().Post(s.URL()+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	for _, test := range []struct {
+		name string
+		path string
+		body string
+		code int
+		want string
+	}{
+		{"TopHits", "/metrics-*/_search?allow_no_indices=true", `{"size":0}`, http.StatusOK, `{"aggregations":{"value":{"hits":{"hits":[{"_index":"metrics-*","_source":{"value":1.5}}]}}},"hits":{"hits":[],"total":{"relation":"eq","value":1}},"timed_out":false}`},
+		{"Metric", "/metrics-2026.10%2Cotel/_search", `{"size":0}`, http.StatusOK, `{"aggregations":{"timestamp":{"value":1546398245000,"value_as_string":"2019-01-02T03:04:05Z"},"value":{"value":2.5}},"hits":{"hits":[],"total":{"relation":"eq","value":2}},"timed_out":false}`},
+		{"Unexpected Search", "/metrics-*/_search", `{"size":1}`, http.StatusBadRequest, ""},
+		{"Not A Search", "/metrics-*/_bulk", `{}`, http.StatusBadRequest, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code, body := post(test.path, test.body)
+			if code != test.code {
+				t.Errorf("got %d; want %d", code, test.code)
+			}
+			if test.want != "" && body != test.want {
+				t.Errorf("got %s; want %s", body, test.want)
+			}
+		})
+	}
+	want := "metrics-* {\"size\":0}\nmetrics-2026.10,otel {\"size\":0}\nmetrics-* {\"size\":1}"
+	if got := strings.Join(s.Queries(), "\n"); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
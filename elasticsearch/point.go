@@ -0,0 +1,123 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point represents a value at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// topHits returns the Point of the top_hits aggregation's (first) document
+func topHits(raw json.RawMessage, valueField, timestampField string) (Point, error) {
+	var agg struct {
+		Hits struct {
+			Hits []struct {
+				Source map[string]interface{} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(raw, &agg); err != nil {
+		return Point{}, fmt.Errorf("Unable to decode top_hits: %s", err)
+	}
+	if len(agg.Hits.Hits) == 0 {
+		return Point{}, errors.New("No documents match the search")
+	}
+	source := agg.Hits.Hits[0].Source
+	v, ok := Lookup(source, valueField)
+	if !ok {
+		return Point{}, fmt.Errorf("Document has no '%s' field", valueField)
+	}
+	value, err := toFloat64(v)
+	if err != nil {
+		return Point{}, err
+	}
+	ts, ok := Lookup(source, timestampField)
+	if !ok {
+		return Point{}, fmt.Errorf("Document has no '%s' field", timestampField)
+	}
+	t, err := toTime(ts)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{
+		Time:  t,
+		Value: value,
+	}, nil
+}
+
+// metric returns the Point of a single-value metric aggregation and the max timestamp aggregation
+func metric(value, timestamp json.RawMessage) (Point, error) {
+	var v, ts struct {
+		Value *float64 `json:"value"`
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return Point{}, fmt.Errorf("Unable to decode aggregation: %s", err)
+	}
+	if err := json.Unmarshal(timestamp, &ts); err != nil {
+		return Point{}, fmt.Errorf("Unable to decode aggregation: %s", err)
+	}
+	if v.Value == nil || ts.Value == nil {
+		return Point{}, errors.New("No documents have the value field")
+	}
+	// Date aggregations' values are milliseconds since the epoch
+	return Point{
+		Time:  time.Unix(0, int64(*ts.Value)*int64(time.Millisecond)),
+		Value: *v.Value,
+	}, nil
+}
+
+// Lookup returns the value of the (dotted) field in the document
+// Fields may be objects (e.g. {"labels":{"key1":...}}) or dotted keys (e.g. {"labels.key1":...})
+func Lookup(source map[string]interface{}, field string) (interface{}, bool) {
+	if v, ok := source[field]; ok {
+		return v, true
+	}
+	parts := strings.Split(field, ".")
+	for j := 1; j < len(parts); j++ {
+		if m, ok := source[strings.Join(parts[:j], ".")].(map[string]interface{}); ok {
+			if v, ok := Lookup(m, strings.Join(parts[j:], ".")); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// toFloat64 converts a numeric (or numeric string) field
+func toFloat64(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0.0, fmt.Errorf("Value '%s' is not numeric", v)
+		}
+		return f, nil
+	}
+	return 0.0, fmt.Errorf("Value '%v' is not numeric", v)
+}
+
+// toTime converts a date field; dates are strings (RFC 3339) or milliseconds since the epoch
+func toTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case float64:
+		return time.Unix(0, int64(v)*int64(time.Millisecond)), nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(0, ms*int64(time.Millisecond)), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Timestamp '%v' is not a date", v)
+}
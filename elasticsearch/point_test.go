@@ -0,0 +1,64 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func Test_Lookup(t *testing.T) {
+	var source map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"labels":{"key1":"value1"},"labels.key2":"value2","a":{"b.c":{"d":"value3"}}}`), &source); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		field string
+		want  interface{}
+	}{
+		{"labels.key1", "value1"},
+		{"labels.key2", "value2"},
+		{"a.b.c.d", "value3"},
+		{"labels.key3", nil},
+	} {
+		t.Run(test.field, func(t *testing.T) {
+			got, ok := Lookup(source, test.field)
+			if ok != (test.want != nil) || got != test.want {
+				t.Errorf("got %v (%t); want %v", got, ok, test.want)
+			}
+		})
+	}
+}
+func Test_toTime(t *testing.T) {
+	want := time.Date(2026, 10, 19, 12, 0, 0, 500000000, time.UTC)
+	for _, v := range []interface{}{
+		"2026-10-19T12:00:00.5Z",
+		float64(want.UnixNano() / int64(time.Millisecond)),
+		"1792411200500",
+	} {
+		got, err := toTime(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	}
+	if _, err := toTime(true); err == nil {
+		t.Errorf("got nil; want error")
+	}
+}
+func Test_metric(t *testing.T) {
+	p, err := metric(json.RawMessage(`{"value":42}`), json.RawMessage(`{"value":1792411200500,"value_as_string":"2026-10-19T12:00:00.500Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Value, 42.0; got != want {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if got, want := p.Time, time.Date(2026, 10, 19, 12, 0, 0, 500000000, time.UTC); !got.Equal(want) {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if _, err := metric(json.RawMessage(`{"value":null}`), json.RawMessage(`{"value":null}`)); err == nil {
+		t.Errorf("got nil; want error")
+	}
+}
@@ -0,0 +1,186 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Search represents the body of a _search request
+// Documents are filtered (without scoring) and reduced by a single aggregation named "value"
+type Search struct {
+	Size  int                    `json:"size"`
+	Query map[string]interface{} `json:"query"`
+	Aggs  map[string]interface{} `json:"aggs,omitempty"`
+
+	filters []interface{}
+}
+
+// NewSearch creates a Search for documents whose name field has the value
+func NewSearch(field, name string) *Search {
+	s := &Search{}
+	s.AddTerm(field, name)
+	return s
+}
+
+// AddTerm filters the documents to those whose (keyword) field has the value
+func (s *Search) AddTerm(field, value string) {
+	s.filters = append(s.filters, map[string]interface{}{
+		"term": map[string]interface{}{
+			field: value,
+		},
+	})
+	s.update()
+}
+
+// AddRange filters the documents to those whose timestamp field is after from and at or before to
+func (s *Search) AddRange(field string, from, to time.Time) {
+	s.filters = append(s.filters, map[string]interface{}{
+		"range": map[string]interface{}{
+			field: map[string]interface{}{
+				"gt":     from.UTC().Format(time.RFC3339Nano),
+				"lte":    to.UTC().Format(time.RFC3339Nano),
+				"format": "strict_date_optional_time",
+			},
+		},
+	})
+	s.update()
+}
+
+// SetAggregation sets the aggregation of the value field
+// top_hits returns the most recent document's fields; other aggregations are accompanied by the max of the timestamp field
+func (s *Search) SetAggregation(a Aggregation, valueField, timestampField string) {
+	if a == TopHits {
+		s.Aggs = map[string]interface{}{
+			"value": map[string]interface{}{
+				"top_hits": map[string]interface{}{
+					"size": 1,
+					"sort": []interface{}{
+						map[string]interface{}{
+							timestampField: map[string]interface{}{"order": "desc"},
+						},
+					},
+					"_source": []string{timestampField, valueField},
+				},
+			},
+		}
+		return
+	}
+	s.Aggs = map[string]interface{}{
+		"value": map[string]interface{}{
+			string(a): map[string]interface{}{"field": valueField},
+		},
+		"timestamp": map[string]interface{}{
+			"max": map[string]interface{}{"field": timestampField},
+		},
+	}
+}
+
+// update rebuilds the query from the filters
+func (s *Search) update() {
+	s.Query = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": s.filters,
+		},
+	}
+}
+
+// String returns the Search as JSON
+func (s *Search) String() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// response represents a _search response
+type response struct {
+	Hits struct {
+		Total total `json:"total"`
+	} `json:"hits"`
+	Aggregations map[string]json.RawMessage `json:"aggregations"`
+}
+
+// total represents the number of documents that match a search
+// 7.x returns an object {"value":...,"relation":...}; 6.x (and OpenSearch with rest_total_hits_as_int) returns a number
+type total struct {
+	Value int `json:"value"`
+}
+
+// UnmarshalJSON decodes either shape of the total
+func (t *total) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &t.Value); err == nil {
+		return nil
+	}
+	var v struct {
+		Value int `json:"value"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("Unable to decode hits total: %s", err)
+	}
+	t.Value = v.Value
+	return nil
+}
+
+// errorResponse represents an Elasticsearch error
+type errorResponse struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+	Status int `json:"status"`
+}
+
+// search sends the Search to the Importer's index pattern and returns the Point that its aggregation gives
+func (i *Importer) search(s *Search) (Point, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return Point{}, err
+	}
+	params := url.Values{}
+	// Index patterns that match no indices return no hits rather than an error
+	params.Set("ignore_unavailable", "true")
+	params.Set("allow_no_indices", "true")
+	req, err := http.NewRequest(http.MethodPost, i.options.URL+"/"+url.PathEscape(i.options.Index)+"/_search?"+params.Encode(), bytes.NewReader(b))
+	if err != nil {
+		return Point{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case i.options.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+i.options.APIKey)
+	case i.options.Username != "":
+		req.SetBasicAuth(i.options.Username, i.options.Password)
+	}
+	client := i.options.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Point{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e); err != nil || e.Error.Type == "" {
+			return Point{}, fmt.Errorf("Search failed (%s)", resp.Status)
+		}
+		return Point{}, fmt.Errorf("Search failed (%s): %s: %s", resp.Status, e.Error.Type, e.Error.Reason)
+	}
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return Point{}, fmt.Errorf("Unable to decode response: %s", err)
+	}
+	if r.Hits.Total.Value == 0 {
+		return Point{}, errors.New("No documents match the search")
+	}
+	if i.options.Aggregation == TopHits {
+		return topHits(r.Aggregations["value"], i.options.ValueField, i.options.TimestampField)
+	}
+	return metric(r.Aggregations["value"], r.Aggregations["timestamp"])
+}
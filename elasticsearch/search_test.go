@@ -0,0 +1,62 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSearch_String(t *testing.T) {
+	from := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	s := NewSearch("name", "counter0")
+	s.AddTerm("labels.key1", "value1")
+	s.AddRange("@timestamp", from, from.Add(5*time.Minute))
+	t.Run("TopHits", func(t *testing.T) {
+		s.SetAggregation(TopHits, "value", "@timestamp")
+		want := `{"size":0,"query":{"bool":{"filter":[` +
+			`{"term":{"name":"counter0"}},` +
+			`{"term":{"labels.key1":"value1"}},` +
+			`{"range":{"@timestamp":{"format":"strict_date_optional_time","gt":"2026-10-19T12:00:00Z","lte":"2026-10-19T12:05:00Z"}}}]}},` +
+			`"aggs":{"value":{"top_hits":{"_source":["@timestamp","value"],"size":1,"sort":[{"@timestamp":{"order":"desc"}}]}}}}`
+		if got := s.String(); got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Max", func(t *testing.T) {
+		s.SetAggregation(Max, "value", "@timestamp")
+		want := `{"timestamp":{"max":{"field":"@timestamp"}},"value":{"max":{"field":"value"}}}`
+		b, err := json.Marshal(s.Aggs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func Test_total(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		json string
+		want int
+	}{
+		{"6.x", `{"hits":{"total":3}}`, 3},
+		{"7.x", `{"hits":{"total":{"value":3,"relation":"eq"}}}`, 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var r response
+			if err := json.Unmarshal([]byte(test.json), &r); err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Hits.Total.Value; got != test.want {
+				t.Errorf("got %d; want %d", got, test.want)
+			}
+		})
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		var r response
+		if err := json.Unmarshal([]byte(`{"hits":{"total":"3"}}`), &r); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
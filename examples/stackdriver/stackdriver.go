@@ -47,6 +47,7 @@ func main() {
 	if err != nil {
 		glog.Fatal(err)
 	}
+	defer importer.Close()
 
 	importer_view.RegisterImporter(importer)
 
@@ -0,0 +1,155 @@
+package graphite
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	// defaultURL is graphite-web's default (Docker image) address
+	defaultURL = "http://localhost:8080"
+	// defaultPath encodes the label values, in the order of the View's label names, as the path's trailing nodes
+	defaultPath = "{{.Prefix}}{{.Name}}{{range .Values}}.{{.}}{{end}}"
+	// defaultTarget reads the path without applying functions
+	defaultTarget = "{{.Path}}"
+	// defaultWindow allows for Carbon's flush interval
+	defaultWindow = 5 * time.Minute
+)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from Graphite's render API; a View and its label values are encoded as a dotted path
+type Importer struct {
+	name    string
+	options Options
+	path    *template.Template
+	target  *template.Template
+}
+
+// NewImporter creates a new importer using the Options provided
+// The URL defaults to the value of environment variable GRAPHITE_URL
+func NewImporter(o Options) (*Importer, error) {
+	if o.URL == "" {
+		o.URL = os.Getenv("GRAPHITE_URL")
+	}
+	if o.URL == "" {
+		o.URL = defaultURL
+	}
+	o.URL = strings.TrimSuffix(o.URL, "/")
+	if o.Path == "" {
+		o.Path = defaultPath
+	}
+	if o.Target == "" {
+		o.Target = defaultTarget
+	}
+	path, err := template.New("path").Option("missingkey=error").Parse(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	target, err := template.New("target").Option("missingkey=error").Parse(o.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &Importer{
+		name:    "graphite",
+		options: o,
+		path:    path,
+		target:  target,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent (non-null) Point in the window ending at the time specified for the View with the label values
+// Every series that the target returns is considered
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	target, err := i.Target(v, labelValues)
+	if err != nil {
+		return Point{}, err
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+	log.Println(target)
+	series, err := i.render(target, t.Add(-window), t)
+	if err != nil {
+		return Point{}, err
+	}
+	if len(series) == 0 {
+		return Point{}, errors.New("No series match the target")
+	}
+	points := []Point{}
+	for _, s := range series {
+		points = append(points, s.Points()...)
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No points in the series")
+	}
+	return points[j], nil
+}
+
+// Target returns the render API target for the View with the label values
+// The Path template is executed to give the path which is then substituted into the Target template
+func (i *Importer) Target(v *view.View, labelValues []string) (string, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return "", errors.New("Inconsistency between labels and values")
+	}
+	data := PathData{
+		Prefix: i.options.MetricPrefix,
+		Name:   Sanitize(v.Name),
+		Labels: map[string]string{},
+		Values: make([]string, len(labelValues)),
+	}
+	for j, labelName := range v.LabelNames {
+		data.Labels[labelName] = Sanitize(labelValues[j])
+		data.Values[j] = Sanitize(labelValues[j])
+	}
+	var path strings.Builder
+	if err := i.path.Execute(&path, data); err != nil {
+		return "", err
+	}
+	var target strings.Builder
+	if err := i.target.Execute(&target, TargetData{Path: path.String()}); err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// URL is graphite-web's base URL; defaults to environment variable GRAPHITE_URL or http://localhost:8080
+	URL string
+	// MetricPrefix is available to the Path template as .Prefix and should include its trailing "."
+	MetricPrefix string
+	// Path is a text/template, executed with a PathData, that gives the View's dotted path
+	// By default, the label values are appended to the name e.g. counter0.value1.value2
+	Path string
+	// Target is a text/template, executed with a TargetData, that gives the render API target
+	// e.g. summarize({{.Path}}, "1min", "sum"); by default, the path itself
+	Target string
+	// Window is the duration (ending at the time of the read) that's rendered; defaults to 5 minutes
+	Window time.Duration
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
@@ -0,0 +1,247 @@
+package graphite
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/graphite/graphitetest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// newTestImporter creates an Importer that talks to a graphitetest.Server
+func newTestImporter(t *testing.T, s *graphitetest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+// float returns a pointer to the value
+func float(f float64) *float64 {
+	return &f
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		os.Unsetenv("GRAPHITE_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "graphite"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.URL, "http://localhost:8080"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("GRAPHITE_URL", "http://graphite:8080/")
+		defer os.Unsetenv("GRAPHITE_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.options.URL, "http://graphite:8080"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Invalid Template", func(t *testing.T) {
+		if _, err := NewImporter(Options{Path: "{{.Name"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+		if _, err := NewImporter(Options{Target: "{{.Path"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Target(t *testing.T) {
+	v := &view.View{
+		Name:       "opencensus.io/counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"Default", Options{}, "opencensus_io_counter0.value1.value_2"},
+		{"MetricPrefix", Options{MetricPrefix: "apps.freddie."}, "apps.freddie.opencensus_io_counter0.value1.value_2"},
+		{"Labels", Options{Path: "{{.Labels.key2}}.{{.Name}}.{{.Labels.key1}}"}, "value_2.opencensus_io_counter0.value1"},
+		{"Summarize", Options{Target: `summarize({{.Path}}, "1min", "sum")`}, `summarize(opencensus_io_counter0.value1.value_2, "1min", "sum")`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i, err := NewImporter(test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := i.Target(v, []string{"value1", "value 2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+	t.Run("Unknown Label", func(t *testing.T) {
+		i, err := NewImporter(Options{Path: "{{.Name}}.{{.Labels.key3}}"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Target(v, []string{"value1", "value2"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Label Mismatch", func(t *testing.T) {
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Target(v, []string{"value1"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := graphitetest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	from := now.Add(-5 * time.Minute)
+	s.SetResult("counter0.value1.value2", from, now, graphitetest.Series{
+		Target: "counter0.value1.value2",
+		Datapoints: []graphitetest.Datapoint{
+			{Time: now.Add(-20 * time.Second), Value: float(3)},
+			{Time: now.Add(-10 * time.Second), Value: float(4)},
+			{Time: now, Value: nil},
+		},
+	})
+	s.SetResult("counter0.*.value2", from, now,
+		graphitetest.Series{
+			Target:     "counter0.value1.value2",
+			Datapoints: []graphitetest.Datapoint{{Time: now.Add(-10 * time.Second), Value: float(4)}},
+		},
+		graphitetest.Series{
+			Target:     "counter0.value3.value2",
+			Datapoints: []graphitetest.Datapoint{{Time: now.Add(-30 * time.Second), Value: float(5)}},
+		},
+	)
+	s.SetResult(`summarize(counter0.value1.value2, "1min", "sum")`, from, now, graphitetest.Series{
+		Target: `summarize(counter0.value1.value2, "1min", "sum")`,
+		Datapoints: []graphitetest.Datapoint{
+			{Time: now.Add(-time.Minute), Value: float(3)},
+			{Time: now, Value: float(9)},
+		},
+	})
+	s.SetResult(`sumSeries(counter0.*.value2)`, now.Add(-30*time.Second), now, graphitetest.Series{
+		Target:     "sumSeries(counter0.*.value2)",
+		Datapoints: []graphitetest.Datapoint{{Time: now.Add(-10 * time.Second), Value: float(9)}},
+	})
+	s.SetResult("counter0.value1.value9", from, now)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name        string
+		options     Options
+		labelValues []string
+		target      string
+		want        float64
+	}{
+		{"Newest", Options{}, []string{"value1", "value2"}, "counter0.value1.value2 from 1546397940 until 1546398240", 4},
+		{"Wildcard", Options{Path: "{{.Name}}.*.{{.Labels.key2}}"}, []string{"value1", "value2"}, "counter0.*.value2 from 1546397940 until 1546398240", 4},
+		{"Summarize", Options{Target: `summarize({{.Path}}, "1min", "sum")`}, []string{"value1", "value2"}, `summarize(counter0.value1.value2, "1min", "sum") from 1546397940 until 1546398240`, 9},
+		{"Window", Options{Path: "{{.Name}}.*.{{.Labels.key2}}", Target: `sumSeries({{.Path}})`, Window: 30 * time.Second}, []string{"value1", "value2"}, "sumSeries(counter0.*.value2) from 1546398210 until 1546398240", 9},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			got, err := i.Value(v, test.labelValues, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+			targets := s.Queries()
+			if got := targets[len(targets)-1]; got != test.target {
+				t.Errorf("got %s; want %s", got, test.target)
+			}
+		})
+	}
+	t.Run("No Series", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value9"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Points", func(t *testing.T) {
+		s.SetResult("counter0.value1.value2", from.Add(-time.Hour), now.Add(-time.Hour), graphitetest.Series{
+			Target:     "counter0.value1.value2",
+			Datapoints: []graphitetest.Datapoint{{Time: now.Add(-time.Hour), Value: nil}},
+		})
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now.Add(-time.Hour)); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusInternalServerError)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Carbon(t *testing.T) {
+	s := graphitetest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	i := newTestImporter(t, s, Options{MetricPrefix: "opencensus."})
+	path, err := i.Target(v, []string{"value1", "value2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write as the Graphite exporter does, using Carbon's plaintext protocol
+	conn, err := net.Dial("tcp", s.CarbonAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(conn, "%s %v %d\n", path, 41, now.Add(-10*time.Second).Unix())
+	fmt.Fprintf(conn, "%s %v %d\n", path, 42, now.Unix())
+	// After the read
+	fmt.Fprintf(conn, "%s %v %d\n", path, 43, now.Add(10*time.Second).Unix())
+	conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); s.Received() < 3; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d datapoints; want 3", s.Received())
+		}
+	}
+
+	got, err := i.Value(v, []string{"value1", "value2"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 42.0; got != want {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	targets := s.Queries()
+	if got, want := targets[len(targets)-1], "opencensus.counter0.value1.value2 from 1546397940 until 1546398240"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+package graphitetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for graphite-web's render API (format=json) and Carbon's plaintext protocol on a local TCP listener
+// It returns the series seeded for each exact request e.g. "counter0.value1.value2 from 1546397945 until 1546398245"
+// A target that's a path Carbon has received datapoints for is rendered from those datapoints instead
+type Server struct {
+	*standin.Server
+	carbon net.Listener
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	received map[string]map[int64]float64
+	count    int
+}
+
+// NewServer creates and starts a new Server with no series
+// As with httptest.NewServer, it panics if it's unable to listen for Carbon
+func NewServer() *Server {
+	carbon, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("graphitetest: failed to listen on a port: %v", err))
+	}
+	s := &Server{
+		carbon:   carbon,
+		received: map[string]map[int64]float64{},
+	}
+	s.Server = standin.NewServer(s.request)
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// CarbonAddr returns the address (host:port) of Carbon's plaintext listener
+func (s *Server) CarbonAddr() string {
+	return s.carbon.Addr().String()
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.carbon.Close()
+	s.wg.Wait()
+	s.Server.Close()
+}
+
+// Received returns the number of datapoints that Carbon has received
+// Carbon is asynchronous; use it to wait for datapoints written to CarbonAddr
+func (s *Server) Received() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// accept handles Carbon connections until the listener's closed
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.carbon.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.receive(conn)
+	}
+}
+
+// receive reads lines of "path value timestamp" from a Carbon connection
+// As with Carbon, invalid lines are dropped; a timestamp of -1 is the time of receipt
+func (s *Server) receive(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		path, value, ts, err := ParseLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+		if ts == -1 {
+			ts = time.Now().Unix()
+		}
+		s.mu.Lock()
+		if _, ok := s.received[path]; !ok {
+			s.received[path] = map[int64]float64{}
+		}
+		s.received[path][ts] = value
+		s.count++
+		s.mu.Unlock()
+	}
+}
+
+// ParseLine parses a line of Carbon's plaintext protocol: path value timestamp
+func ParseLine(line string) (string, float64, int64, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return "", 0, 0, fmt.Errorf("Invalid line '%s'", line)
+	}
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("Invalid value '%s'", fields[1])
+	}
+	ts, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("Invalid timestamp '%s'", fields[2])
+	}
+	return fields[0], value, int64(ts), nil
+}
+
+// request returns the text of a render request: its target and (Unix) times
+// When Carbon has received datapoints for the target, those in the window (after from until until) are its result
+func (s *Server) request(r *http.Request) (string, error) {
+	if r.URL.Path != "/render" {
+		return "", fmt.Errorf("Path '%s' not found", r.URL.Path)
+	}
+	params := r.URL.Query()
+	if params.Get("format") != "json" {
+		return "", errors.New("Only format=json is supported")
+	}
+	if params.Get("target") == "" {
+		return "", errors.New("Missing required parameter 'target'")
+	}
+	text := fmt.Sprintf("%s from %s until %s", params.Get("target"), params.Get("from"), params.Get("until"))
+	if series, ok := s.carbonSeries(params.Get("target"), params.Get("from"), params.Get("until")); ok {
+		s.SetResponse(text, "application/json", render(series))
+	}
+	return text, nil
+}
+
+// carbonSeries returns the series of the datapoints Carbon has received for the path in the window, if there are any
+func (s *Server) carbonSeries(path, from, until string) (Series, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	received, ok := s.received[path]
+	if !ok {
+		return Series{}, false
+	}
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return Series{}, false
+	}
+	end, err := strconv.ParseInt(until, 10, 64)
+	if err != nil {
+		return Series{}, false
+	}
+	series := Series{Target: path, Datapoints: []Datapoint{}}
+	for ts, value := range received {
+		if ts > start && ts <= end {
+			value := value
+			series.Datapoints = append(series.Datapoints, Datapoint{time.Unix(ts, 0), &value})
+		}
+	}
+	sort.Slice(series.Datapoints, func(i, j int) bool { return series.Datapoints[i].Time.Before(series.Datapoints[j].Time) })
+	return series, true
+}
+
+// Datapoint represents a value of a series; a nil Value is a time without data
+type Datapoint struct {
+	Time  time.Time
+	Value *float64
+}
+
+// Series represents a series of the render API
+type Series struct {
+	Target     string
+	Datapoints []Datapoint
+}
+
+// SetResult seeds the series that are returned when the target is rendered between the times specified
+func (s *Server) SetResult(target string, from, until time.Time, series ...Series) {
+	s.SetResponse(fmt.Sprintf("%s from %d until %d", target, from.Unix(), until.Unix()), "application/json", render(series...))
+}
+
+// render returns the series in the render API's JSON format
+func render(series ...Series) string {
+	type result struct {
+		Target     string        `json:"target"`
+		Datapoints [][2]*float64 `json:"datapoints"`
+	}
+	results := []result{}
+	for _, ss := range series {
+		r := result{
+			Target:     ss.Target,
+			Datapoints: [][2]*float64{},
+		}
+		for _, dp := range ss.Datapoints {
+			ts := float64(dp.Time.Unix())
+			r.Datapoints = append(r.Datapoints, [2]*float64{dp.Value, &ts})
+		}
+		results = append(results, r)
+	}
+	b, _ := json.Marshal(results)
+	return string(b)
+}
@@ -0,0 +1,113 @@
+package graphitetest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_SetResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	value := 1.5
+	now := time.Unix(1546398245, 0)
+	s.SetResult("counter0", now.Add(-time.Minute), now, Series{
+		Target:     "counter0",
+		Datapoints: []Datapoint{{now.Add(-10 * time.Second), nil}, {now, &value}},
+	})
+	render := func(target string) (int, string) {
+		params := url.Values{
+			"target": {target},
+			"from":   {"1546398185"},
+			"until":  {"1546398245"},
+			"format": {"json"},
+		}
+		resp, err := s.Client().Get(s.URL() + "/render?" + params.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	t.Run("Result", func(t *testing.T) {
+		code, body := render("counter0")
+		if got, want := code, http.StatusOK; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := body, `[{"target":"counter0","datapoints":[[null,1546398235],[1.5,1546398245]]}]`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Unexpected Target", func(t *testing.T) {
+		code, _ := render("counter1")
+		if got, want := code, http.StatusBadRequest; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	want := "counter0 from 1546398185 until 1546398245\ncounter1 from 1546398185 until 1546398245"
+	if got := strings.Join(s.Queries(), "\n"); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestServer_Carbon(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.CarbonAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "counter0 1 1546398180\ncounter0 2 1546398240\ninvalid\ncounter0 3 1546398300\n")
+	conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); s.Received() < 3; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d; want 3", s.Received())
+		}
+	}
+	params := url.Values{
+		"target": {"counter0"},
+		"from":   {"1546398180"},
+		"until":  {"1546398240"},
+		"format": {"json"},
+	}
+	resp, err := s.Client().Get(s.URL() + "/render?" + params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `[{"target":"counter0","datapoints":[[2,1546398240]]}]`; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func Test_ParseLine(t *testing.T) {
+	path, value, ts, err := ParseLine("counter0.value1 1.5 1546398245")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "counter0.value1" || value != 1.5 || ts != 1546398245 {
+		t.Errorf("got %s %v %d; want counter0.value1 1.5 1546398245", path, value, ts)
+	}
+	for _, line := range []string{
+		"counter0 1.5",
+		"counter0 one 1546398245",
+		"counter0 1.5 now",
+	} {
+		if _, _, _, err := ParseLine(line); err == nil {
+			t.Errorf("%s: got nil; want error", line)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package graphite
+
+import (
+	"strings"
+)
+
+// PathData is the data with which the Path template is executed
+type PathData struct {
+	// Prefix is the Importer's MetricPrefix
+	Prefix string
+	// Name is the View's (sanitized) name
+	Name string
+	// Labels maps the View's label names to their (sanitized) values
+	Labels map[string]string
+	// Values are the (sanitized) label values in the order of the View's label names
+	Values []string
+}
+
+// TargetData is the data with which the Target template is executed
+type TargetData struct {
+	Path string
+}
+
+// Sanitize replaces characters that Graphite treats specially in a path node with "_"
+// Dots separate nodes, whitespace separates Carbon's plaintext fields and the rest are wildcards or function syntax
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '_' || r == '-' || r == ':':
+			return r
+		}
+		return '_'
+	}, s)
+}
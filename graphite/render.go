@@ -0,0 +1,74 @@
+package graphite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Point represents a datapoint's value at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series represents a series returned by the render API
+// Datapoints are [value, timestamp] pairs; the value is null when there's no data for the timestamp
+type Series struct {
+	Target     string        `json:"target"`
+	Datapoints [][2]*float64 `json:"datapoints"`
+}
+
+// Points returns the Series' non-null datapoints
+func (s Series) Points() []Point {
+	points := []Point{}
+	for _, dp := range s.Datapoints {
+		if dp[0] == nil || dp[1] == nil {
+			continue
+		}
+		points = append(points, Point{
+			Time:  time.Unix(int64(*dp[1]), 0),
+			Value: *dp[0],
+		})
+	}
+	return points
+}
+
+// render gets the target's series between the times specified using the render API's JSON format
+func (i *Importer) render(target string, from, until time.Time) ([]Series, error) {
+	params := url.Values{}
+	params.Set("target", target)
+	params.Set("from", strconv.FormatInt(from.Unix(), 10))
+	params.Set("until", strconv.FormatInt(until.Unix(), 10))
+	params.Set("format", "json")
+	client := i.options.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(i.options.URL + "/render?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// graphite-web's errors are plain text (or HTML)
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return nil, fmt.Errorf("Render failed (%s): %s", resp.Status, body)
+	}
+	var series []Series
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, fmt.Errorf("Unable to decode response: %s", err)
+	}
+	return series, nil
+}
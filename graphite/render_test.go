@@ -0,0 +1,35 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeries_Points(t *testing.T) {
+	s := Series{Target: "a", Datapoints: [][2]*float64{{float(1), float(60)}, {nil, float(70)}, {float(3), float(80)}}}
+	points := s.Points()
+	if got, want := len(points), 2; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := points[1].Value, 3.0; got != want {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if got, want := points[1].Time, time.Unix(80, 0); !got.Equal(want) {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestSanitize(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want string
+	}{
+		{"value1", "value1"},
+		{"opencensus.io/counter0", "opencensus_io_counter0"},
+		{"a b*{c}", "a_b__c_"},
+		{"us-east1:a", "us-east1:a"},
+	} {
+		if got := Sanitize(test.s); got != test.want {
+			t.Errorf("got %s; want %s", got, test.want)
+		}
+	}
+}
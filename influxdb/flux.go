@@ -0,0 +1,182 @@
+package influxdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FluxQuery represents a Flux (InfluxDB 2.x) query for a field of a measurement with a set of tags
+type FluxQuery struct {
+	Bucket      string
+	Measurement string
+	Field       string
+	Tags        map[string]string
+	// From is inclusive and To is exclusive
+	From time.Time
+	To   time.Time
+	// Last restricts the query to the most recent point of each series
+	Last bool
+}
+
+// String returns the query as a Flux script; tags are sorted so that the query is deterministic
+func (q *FluxQuery) String() string {
+	conditions := []string{
+		"r._measurement == " + QuoteFlux(q.Measurement),
+		"r._field == " + QuoteFlux(q.Field),
+	}
+	keys := make([]string, 0, len(q.Tags))
+	for key := range q.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, "r["+QuoteFlux(key)+"] == "+QuoteFlux(q.Tags[key]))
+	}
+	lines := []string{
+		"from(bucket: " + QuoteFlux(q.Bucket) + ")",
+		"range(start: " + q.From.UTC().Format(time.RFC3339Nano) + ", stop: " + q.To.UTC().Format(time.RFC3339Nano) + ")",
+		"filter(fn: (r) => " + strings.Join(conditions, " and ") + ")",
+	}
+	if q.Last {
+		lines = append(lines, "last()")
+	}
+	return strings.Join(lines, "\n  |> ")
+}
+
+// QuoteFlux returns the Flux string literal in double quotes
+func QuoteFlux(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "${", `\${`).Replace(s) + `"`
+}
+
+// fluxError represents the error response of the 2.x API
+type fluxError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// queryFlux sends the query to the 2.x /api/v2/query endpoint and returns its points
+func (i *Importer) queryFlux(q *FluxQuery) ([]Point, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": q.String(),
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"header":      true,
+			"annotations": []string{"datatype", "group", "default"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("org", i.options.Org)
+	req, err := http.NewRequest(http.MethodPost, i.baseURL()+"/api/v2/query?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	if i.options.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.options.Token)
+	}
+	resp, err := i.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e fluxError
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Message == "" {
+			return nil, fmt.Errorf("Query failed (%s)", resp.Status)
+		}
+		return nil, fmt.Errorf("Query failed (%s): %s: %s", resp.Status, e.Code, e.Message)
+	}
+	return parseCSV(resp.Body)
+}
+
+// parseCSV converts the _time and _value columns of an annotated CSV response into Points
+// See: https://docs.influxdata.com/influxdb/v2/reference/syntax/annotated-csv/
+func parseCSV(r io.Reader) ([]Point, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	points := []Point{}
+	var header []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		// Annotations precede each table's header
+		if strings.HasPrefix(record[0], "#") {
+			header = nil
+			continue
+		}
+		if header == nil {
+			header = record
+			continue
+		}
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("Inconsistency between header and record")
+		}
+		// In-band errors have "error" and "reference" columns
+		if len(header) > 1 && header[1] == "error" {
+			return nil, fmt.Errorf("Query failed: %s", record[1])
+		}
+		var (
+			p              Point
+			hasTime, found bool
+		)
+		for j, column := range header {
+			switch column {
+			case "_time":
+				t, err := time.Parse(time.RFC3339Nano, record[j])
+				if err != nil {
+					return nil, err
+				}
+				p.Time, hasTime = t, true
+			case "_value":
+				if record[j] == "" {
+					continue
+				}
+				v, err := parseValue(record[j])
+				if err != nil {
+					return nil, err
+				}
+				p.Value, found = v, true
+			}
+		}
+		if hasTime && found {
+			points = append(points, p)
+		}
+	}
+}
+
+// parseValue parses a numeric or boolean field value
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Field value '%s' is not numeric", s)
+	}
+	return v, nil
+}
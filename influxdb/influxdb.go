@@ -0,0 +1,188 @@
+package influxdb
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Language is the query language used to read from InfluxDB
+type Language int
+
+// Languages
+const (
+	// InfluxQL is used with InfluxDB 1.x (the default)
+	InfluxQL Language = iota
+	// Flux is used with InfluxDB 2.x
+	Flux
+)
+
+const (
+	defaultURL   = "http://localhost:8086"
+	defaultField = "value"
+)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from InfluxDB; a View is a measurement, its label names are tags and its value is a field
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+func NewImporter(o Options) (*Importer, error) {
+	switch o.Language {
+	case InfluxQL:
+		if o.Database == "" {
+			return nil, errors.New("Expect an InfluxDB Database")
+		}
+	case Flux:
+		if o.Bucket == "" || o.Org == "" {
+			return nil, errors.New("Expect an InfluxDB Bucket and Org")
+		}
+	default:
+		return nil, errors.New("Unknown Language")
+	}
+	if o.Field == "" {
+		o.Field = defaultField
+	}
+	return &Importer{
+		name:    "influxdb",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+// The most recent point of each series that matches the tags is read and the newest of these is returned
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	points, err := i.query(v, labelValues, t, true)
+	if err != nil {
+		return Point{}, err
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No points match the query")
+	}
+	return points[j], nil
+}
+
+// Series returns every Point (oldest first) in the window ending at the time specified for the View with the label values
+// Points from every series that matches the tags are included
+func (i *Importer) Series(v *view.View, labelValues []string, t time.Time) ([]Point, error) {
+	points, err := i.query(v, labelValues, t, false)
+	if err != nil {
+		return nil, err
+	}
+	sortPoints(points)
+	return points, nil
+}
+
+// query reads the points for the View with the label values in the window ending at the time specified
+// When latest is true, only the most recent point (of each series) is read
+func (i *Importer) query(v *view.View, labelValues []string, t time.Time, latest bool) ([]Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return nil, errors.New("Inconsistency between labels and values")
+	}
+	tags := map[string]string{}
+	for j, labelName := range v.LabelNames {
+		tags[labelName] = labelValues[j]
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = time.Minute
+	}
+	measurement := i.options.MetricPrefix + v.Name
+
+	switch i.options.Language {
+	case Flux:
+		q := &FluxQuery{
+			Bucket:      i.options.Bucket,
+			Measurement: measurement,
+			Field:       i.options.Field,
+			Tags:        tags,
+			From:        t.Add(-window),
+			// range's stop is exclusive; include points at the time specified as InfluxQL does
+			To:   t.Add(time.Nanosecond),
+			Last: latest,
+		}
+		log.Println(q.String())
+		return i.queryFlux(q)
+	default:
+		q := &InfluxQLQuery{
+			Database:        i.options.Database,
+			RetentionPolicy: i.options.RetentionPolicy,
+			Measurement:     measurement,
+			Field:           i.options.Field,
+			Tags:            tags,
+			From:            t.Add(-window),
+			To:              t,
+		}
+		if latest {
+			q.Limit = 1
+		}
+		log.Println(q.String())
+		return i.queryInfluxQL(q)
+	}
+}
+
+// baseURL returns the InfluxDB URL without a trailing slash
+func (i *Importer) baseURL() string {
+	if i.options.URL == "" {
+		return defaultURL
+	}
+	return strings.TrimSuffix(i.options.URL, "/")
+}
+
+// client returns the HTTP client used to make requests
+func (i *Importer) client() *http.Client {
+	if i.options.HTTPClient != nil {
+		return i.options.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// URL is InfluxDB's base URL; defaults to http://localhost:8086
+	URL string
+	// Language determines whether InfluxQL (1.x) or Flux (2.x) is used; defaults to InfluxQL
+	Language Language
+	// Database and RetentionPolicy are used by InfluxQL; the Database is required, the default RetentionPolicy is used if none is specified
+	Database        string
+	RetentionPolicy string
+	// Username and Password authenticate InfluxQL queries
+	Username string
+	Password string
+	// Org and Bucket are required by Flux
+	Org    string
+	Bucket string
+	// Token authenticates with InfluxDB 2.x (and 1.x's compatibility API)
+	Token string
+	// MetricPrefix is prepended to the View's name to give the measurement
+	MetricPrefix string
+	// Field is the measurement's field that's read; defaults to "value"
+	Field string
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 1 minute
+	Window time.Duration
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
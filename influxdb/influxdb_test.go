@@ -0,0 +1,277 @@
+package influxdb
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/influxdb/influxdbtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	database = "opencensus"
+	org      = "dazwilkin"
+	bucket   = "opencensus"
+	token    = "token"
+)
+
+// newTestImporter creates an Importer that talks to an influxdbtest.Server
+func newTestImporter(t *testing.T, s *influxdbtest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.HTTPClient = s.Client()
+	switch o.Language {
+	case Flux:
+		o.Org = org
+		o.Bucket = bucket
+	default:
+		o.Database = database
+	}
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+// Queries for counter0 with key1=value1 and key2=value2 in the minute ending at 2019-01-02T03:04:05Z
+const (
+	influxQLLatest = `SELECT "value" FROM "opencensus".."counter0" WHERE "key1" = 'value1' AND "key2" = 'value2' AND time > 1546398185000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`
+	influxQLSeries = `SELECT "value" FROM "opencensus".."counter0" WHERE "key1" = 'value1' AND "key2" = 'value2' AND time > 1546398185000000000 AND time <= 1546398245000000000 ORDER BY time DESC`
+	fluxSeries     = `from(bucket: "opencensus")
+  |> range(start: 2019-01-02T03:03:05Z, stop: 2019-01-02T03:04:05.000000001Z)
+  |> filter(fn: (r) => r._measurement == "counter0" and r._field == "value" and r["key1"] == "value1" and r["key2"] == "value2")`
+	fluxLatest = fluxSeries + `
+  |> last()`
+)
+
+// seed sets the results of the queries; the most recent points are those of each of hosts a and b
+func seed(s *influxdbtest.Server, now time.Time) {
+	tags := map[string]string{"key1": "value1", "key2": "value2"}
+	s.SetInfluxQLResult(influxQLLatest,
+		influxdbtest.Series{
+			Name:    "counter0",
+			Tags:    map[string]string{"host": "a"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{now.Add(-20 * time.Second).UnixNano(), 2}},
+		},
+		influxdbtest.Series{
+			Name:    "counter0",
+			Tags:    map[string]string{"host": "b"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{now.Add(-30 * time.Second).UnixNano(), 3}},
+		},
+	)
+	s.SetInfluxQLResult(influxQLSeries, influxdbtest.Series{
+		Name:    "counter0",
+		Columns: []string{"time", "value"},
+		Values: [][]interface{}{
+			{now.Add(-20 * time.Second).UnixNano(), 2},
+			{now.Add(-30 * time.Second).UnixNano(), 3},
+			{now.Add(-40 * time.Second).UnixNano(), 1},
+		},
+	})
+	s.SetFluxResult(fluxLatest,
+		influxdbtest.Table{Measurement: "counter0", Field: "value", Tags: merge(tags, "host", "a"), Points: []influxdbtest.Point{
+			{Time: now.Add(-20 * time.Second), Value: 2},
+		}},
+		influxdbtest.Table{Measurement: "counter0", Field: "value", Tags: merge(tags, "host", "b"), Points: []influxdbtest.Point{
+			{Time: now.Add(-30 * time.Second), Value: 3},
+		}},
+	)
+	s.SetFluxResult(fluxSeries,
+		influxdbtest.Table{Measurement: "counter0", Field: "value", Tags: merge(tags, "host", "a"), Points: []influxdbtest.Point{
+			{Time: now.Add(-40 * time.Second), Value: 1},
+			{Time: now.Add(-20 * time.Second), Value: 2},
+		}},
+		influxdbtest.Table{Measurement: "counter0", Field: "value", Tags: merge(tags, "host", "b"), Points: []influxdbtest.Point{
+			{Time: now.Add(-30 * time.Second), Value: 3},
+		}},
+	)
+}
+
+// merge returns a copy of the tags with another tag
+func merge(tags map[string]string, key, value string) map[string]string {
+	result := map[string]string{key: value}
+	for k, v := range tags {
+		result[k] = v
+	}
+	return result
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("No Database", func(t *testing.T) {
+		if _, err := NewImporter(Options{}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Bucket", func(t *testing.T) {
+		if _, err := NewImporter(Options{Language: Flux, Org: org}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unknown Language", func(t *testing.T) {
+		if _, err := NewImporter(Options{Language: Language(2), Database: database}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("With Options", func(t *testing.T) {
+		i, err := NewImporter(Options{Database: database})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "influxdb"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Field, "value"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.baseURL(), "http://localhost:8086"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := influxdbtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	seed(s, now)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, language := range []struct {
+		name     string
+		language Language
+		query    string
+	}{
+		{"InfluxQL", InfluxQL, influxQLLatest},
+		{"Flux", Flux, fluxLatest},
+	} {
+		t.Run(language.name, func(t *testing.T) {
+			t.Run("Newest", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Language: language.language})
+				got, err := i.Value(v, []string{"value1", "value2"}, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := 2.0; got != want {
+					t.Errorf("got %v; want %v", got, want)
+				}
+				queries := s.Queries()
+				if got, want := queries[len(queries)-1], language.query; got != want {
+					t.Errorf("got %s; want %s", got, want)
+				}
+			})
+			t.Run("No Points", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Language: language.language, Field: "count"})
+				if language.language == Flux {
+					s.SetFluxResult(strings.Replace(language.query, `r._field == "value"`, `r._field == "count"`, 1))
+				} else {
+					s.SetInfluxQLResult(strings.Replace(language.query, `SELECT "value"`, `SELECT "count"`, 1))
+				}
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+					t.Errorf("got nil; want error")
+				}
+			})
+			t.Run("Label Mismatch", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Language: language.language})
+				if _, err := i.Value(v, []string{"value1"}, now); err == nil {
+					t.Errorf("got nil; want error")
+				}
+			})
+			t.Run("Unexpected Query", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Language: language.language, MetricPrefix: "prefix/"})
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+					t.Errorf("got nil; want error")
+				}
+			})
+			t.Run("Server Error", func(t *testing.T) {
+				s.SetStatus(http.StatusServiceUnavailable)
+				defer s.SetStatus(http.StatusOK)
+				i := newTestImporter(t, s, Options{Language: language.language})
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+					t.Errorf("got nil; want error")
+				}
+			})
+			t.Run("Token", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Language: language.language, Token: token})
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+					t.Fatal(err)
+				}
+				headers := s.Headers()
+				if got, want := headers[len(headers)-1].Get("Authorization"), "Token "+token; got != want {
+					t.Errorf("got %s; want %s", got, want)
+				}
+			})
+		})
+	}
+}
+func TestImporter_query(t *testing.T) {
+	// The Options determine the measurement, field and database of the query
+	s := influxdbtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"Field", Options{Field: "count"}, `SELECT "count" FROM "opencensus".."counter0" WHERE "key1" = 'value1' AND time > 1546398185000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`},
+		{"MetricPrefix", Options{MetricPrefix: "prefix/"}, `SELECT "value" FROM "opencensus".."prefix/counter0" WHERE "key1" = 'value1' AND time > 1546398185000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`},
+		{"RetentionPolicy", Options{RetentionPolicy: "rp"}, `SELECT "value" FROM "opencensus"."rp"."counter0" WHERE "key1" = 'value1' AND time > 1546398185000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`},
+		{"Window", Options{Window: 5 * time.Minute}, `SELECT "value" FROM "opencensus".."counter0" WHERE "key1" = 'value1' AND time > 1546397945000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			// The Server has no results; only the query is of interest
+			i.Value(v, []string{"value1"}, now)
+			queries := s.Queries()
+			if got := queries[len(queries)-1]; got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+func TestImporter_Series(t *testing.T) {
+	s := influxdbtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	seed(s, now)
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, language := range []Language{InfluxQL, Flux} {
+		i := newTestImporter(t, s, Options{Language: language})
+		points, err := i.Series(v, []string{"value1", "value2"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Point{
+			{now.Add(-40 * time.Second), 1},
+			{now.Add(-30 * time.Second), 3},
+			{now.Add(-20 * time.Second), 2},
+		}
+		if got, want := len(points), len(want); got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		for j, p := range points {
+			if !p.Time.Equal(want[j].Time) || p.Value != want[j].Value {
+				t.Errorf("got %v; want %v", p, want[j])
+			}
+		}
+	}
+	// Every point is read rather than the newest
+	if got, want := strings.Join(s.Queries(), "\n"), influxQLSeries+"\n"+fluxSeries; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package influxdbtest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for InfluxDB's 1.x (/query) and 2.x (/api/v2/query) HTTP APIs
+// It returns the results seeded for each exact InfluxQL or Flux query
+type Server struct {
+	*standin.Server
+}
+
+// NewServer creates and starts a new Server with no results
+func NewServer() *Server {
+	return &Server{standin.NewServer(query)}
+}
+
+// query returns the request's InfluxQL ("q" parameter) or Flux (the JSON body's "query") query
+func query(r *http.Request) (string, error) {
+	if r.URL.Path == "/api/v2/query" {
+		var body struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return "", err
+		}
+		return body.Query, nil
+	}
+	if q := r.URL.Query().Get("q"); q != "" {
+		return q, nil
+	}
+	return "", errors.New("missing required parameter \"q\"")
+}
+
+// Series represents a series of an InfluxQL result; Values are rows of the Columns e.g. [time (epoch ns), value]
+type Series struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+}
+
+// SetInfluxQLResult seeds the result of an InfluxQL query
+func (s *Server) SetInfluxQLResult(query string, series ...Series) {
+	result := map[string]interface{}{
+		"statement_id": 0,
+	}
+	if len(series) > 0 {
+		result["series"] = series
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"results": []interface{}{result},
+	})
+	s.SetResponse(query, "application/json", string(b))
+}
+
+// Point represents a point of a Flux table
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Table represents a Flux table: the points of a field of a measurement with a set of tags
+type Table struct {
+	Measurement string
+	Field       string
+	Tags        map[string]string
+	Points      []Point
+}
+
+// SetFluxResult seeds the result of a Flux query; each table is returned as annotated CSV
+func (s *Server) SetFluxResult(query string, tables ...Table) {
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	for j, table := range tables {
+		keys := make([]string, 0, len(table.Tags))
+		for k := range table.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		datatype := []string{"#datatype", "string", "long", "dateTime:RFC3339", "double", "string", "string"}
+		group := []string{"#group", "false", "false", "false", "false", "true", "true"}
+		defaults := []string{"#default", "_result", "", "", "", "", ""}
+		header := []string{"", "result", "table", "_time", "_value", "_field", "_measurement"}
+		for _, k := range keys {
+			datatype = append(datatype, "string")
+			group = append(group, "true")
+			defaults = append(defaults, "")
+			header = append(header, k)
+		}
+		cw.Write(datatype)
+		cw.Write(group)
+		cw.Write(defaults)
+		cw.Write(header)
+		for _, p := range table.Points {
+			record := []string{"", "", strconv.Itoa(j), p.Time.UTC().Format(time.RFC3339Nano), strconv.FormatFloat(p.Value, 'f', -1, 64), table.Field, table.Measurement}
+			for _, k := range keys {
+				record = append(record, table.Tags[k])
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		// Tables are separated by an empty line
+		b.WriteString("\n")
+	}
+	s.SetResponse(query, "text/csv; charset=utf-8", strings.TrimSuffix(b.String(), "\n"))
+}
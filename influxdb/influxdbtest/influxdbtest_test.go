@@ -0,0 +1,83 @@
+package influxdbtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// send returns the status and body of the Server's response to the request
+func send(t *testing.T, s *Server, method, path, body string) (int, string) {
+	r, err := http.NewRequest(method, s.URL()+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.Client().Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+func TestServer_SetInfluxQLResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	query := `SELECT "value" FROM "counter0"`
+	s.SetInfluxQLResult(query, Series{
+		Name:    "counter0",
+		Columns: []string{"time", "value"},
+		Values:  [][]interface{}{{1546398185000000000, 1}},
+	})
+	code, body := send(t, s, http.MethodGet, "/query?"+url.Values{"q": {query}}.Encode(), "")
+	if got, want := code, http.StatusOK; got != want {
+		t.Errorf("got %d; want %d", got, want)
+	}
+	if got, want := body, `{"results":[{"series":[{"name":"counter0","columns":["time","value"],"values":[[1546398185000000000,1]]}],"statement_id":0}]}`; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	t.Run("Unexpected Query", func(t *testing.T) {
+		code, _ := send(t, s, http.MethodGet, "/query?"+url.Values{"q": {`SELECT "count" FROM "counter0"`}}.Encode(), "")
+		if got, want := code, http.StatusBadRequest; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := strings.Join(s.Queries(), "\n"), query+"\n"+`SELECT "count" FROM "counter0"`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestServer_SetFluxResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	query := `from(bucket: "opencensus")`
+	s.SetFluxResult(query, Table{
+		Measurement: "counter0",
+		Field:       "value",
+		Tags:        map[string]string{"key1": "value1"},
+		Points:      []Point{{time.Unix(1546398185, 0), 1.5}},
+	})
+	code, body := send(t, s, http.MethodPost, "/api/v2/query?org=org", `{"query":"from(bucket: \"opencensus\")"}`)
+	if got, want := code, http.StatusOK; got != want {
+		t.Errorf("got %d; want %d", got, want)
+	}
+	want := strings.Join([]string{
+		"#datatype,string,long,dateTime:RFC3339,double,string,string,string",
+		"#group,false,false,false,false,true,true,true",
+		"#default,_result,,,,,,",
+		",result,table,_time,_value,_field,_measurement,key1",
+		",,0,2019-01-02T03:03:05Z,1.5,value,counter0,value1",
+		"",
+	}, "\n")
+	if got := body; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfluxQLQuery represents an InfluxQL (InfluxDB 1.x) query for a field of a measurement with a set of tags
+type InfluxQLQuery struct {
+	Database        string
+	RetentionPolicy string
+	Measurement     string
+	Field           string
+	Tags            map[string]string
+	From            time.Time
+	To              time.Time
+	// Limit restricts the query to the most recent points of each series; 0 returns every point
+	Limit int
+}
+
+// String returns the query as an InfluxQL SELECT statement; tags are sorted so that the query is deterministic
+func (q *InfluxQLQuery) String() string {
+	from := QuoteIdent(q.Measurement)
+	if q.Database != "" {
+		from = QuoteIdent(q.Database) + "." + QuoteIdent(q.RetentionPolicy) + "." + from
+		if q.RetentionPolicy == "" {
+			from = QuoteIdent(q.Database) + ".." + QuoteIdent(q.Measurement)
+		}
+	}
+	conditions := []string{}
+	keys := make([]string, 0, len(q.Tags))
+	for key := range q.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, QuoteIdent(key)+" = "+QuoteString(q.Tags[key]))
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, "time > "+strconv.FormatInt(q.From.UnixNano(), 10))
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, "time <= "+strconv.FormatInt(q.To.UnixNano(), 10))
+	}
+	s := "SELECT " + QuoteIdent(q.Field) + " FROM " + from
+	if len(conditions) > 0 {
+		s += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if q.Limit > 0 {
+		// Without GROUP BY, LIMIT applies to the points of every series together
+		s += " GROUP BY *"
+	}
+	s += " ORDER BY time DESC"
+	if q.Limit > 0 {
+		s += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	return s
+}
+
+// QuoteIdent returns the InfluxQL identifier in double quotes
+func QuoteIdent(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// QuoteString returns the InfluxQL string literal in single quotes
+func QuoteString(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + `'`
+}
+
+// influxQLResponse represents the response of the 1.x /query endpoint
+type influxQLResponse struct {
+	Results []struct {
+		StatementID int    `json:"statement_id"`
+		Error       string `json:"error"`
+		Series      []struct {
+			Name    string            `json:"name"`
+			Tags    map[string]string `json:"tags"`
+			Columns []string          `json:"columns"`
+			Values  [][]interface{}   `json:"values"`
+		} `json:"series"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+// queryInfluxQL sends the query to the 1.x /query endpoint and returns its points
+// Times are requested as epoch nanoseconds
+func (i *Importer) queryInfluxQL(q *InfluxQLQuery) ([]Point, error) {
+	params := url.Values{}
+	params.Set("q", q.String())
+	params.Set("db", q.Database)
+	if q.RetentionPolicy != "" {
+		params.Set("rp", q.RetentionPolicy)
+	}
+	params.Set("epoch", "ns")
+	req, err := http.NewRequest(http.MethodGet, i.baseURL()+"/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if i.options.Username != "" {
+		req.SetBasicAuth(i.options.Username, i.options.Password)
+	}
+	if i.options.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.options.Token)
+	}
+	resp, err := i.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r influxQLResponse
+	d := json.NewDecoder(resp.Body)
+	d.UseNumber()
+	if err := d.Decode(&r); err != nil {
+		return nil, fmt.Errorf("Unable to decode response (%s): %s", resp.Status, err)
+	}
+	if r.Error != "" {
+		return nil, fmt.Errorf("Query failed (%s): %s", resp.Status, r.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Query failed (%s)", resp.Status)
+	}
+	points := []Point{}
+	for _, result := range r.Results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("Query failed: %s", result.Error)
+		}
+		for _, series := range result.Series {
+			if len(series.Columns) != 2 || series.Columns[0] != "time" {
+				return nil, errors.New("Expect columns 'time' and the field")
+			}
+			for _, values := range series.Values {
+				p, err := influxQLPoint(values)
+				if err != nil {
+					return nil, err
+				}
+				if p != nil {
+					points = append(points, *p)
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// influxQLPoint converts a [time,value] row into a Point; rows with null values return nil
+func influxQLPoint(values []interface{}) (*Point, error) {
+	if len(values) != 2 {
+		return nil, errors.New("Inconsistency between columns and values")
+	}
+	if values[1] == nil {
+		return nil, nil
+	}
+	ns, ok := values[0].(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("Invalid time '%v'", values[0])
+	}
+	t, err := ns.Int64()
+	if err != nil {
+		return nil, err
+	}
+	var value float64
+	switch v := values[1].(type) {
+	case json.Number:
+		if value, err = v.Float64(); err != nil {
+			return nil, err
+		}
+	case bool:
+		if v {
+			value = 1
+		}
+	default:
+		return nil, fmt.Errorf("Field value '%v' is not numeric", values[1])
+	}
+	return &Point{
+		Time:  time.Unix(0, t),
+		Value: value,
+	}, nil
+}
@@ -0,0 +1,19 @@
+package influxdb
+
+import (
+	"sort"
+	"time"
+)
+
+// Point represents a field's value at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// sortPoints sorts the points oldest first
+func sortPoints(points []Point) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+}
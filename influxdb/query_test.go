@@ -0,0 +1,113 @@
+package influxdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxQLQuery_String(t *testing.T) {
+	from := time.Unix(1546398185, 0)
+	to := time.Unix(1546398245, 0)
+	for _, test := range []struct {
+		name  string
+		query InfluxQLQuery
+		want  string
+	}{
+		{
+			"Measurement",
+			InfluxQLQuery{Measurement: "counter0", Field: "value"},
+			`SELECT "value" FROM "counter0" ORDER BY time DESC`,
+		},
+		{
+			"Database",
+			InfluxQLQuery{Database: "db", Measurement: "counter0", Field: "value"},
+			`SELECT "value" FROM "db".."counter0" ORDER BY time DESC`,
+		},
+		{
+			"Retention Policy",
+			InfluxQLQuery{Database: "db", RetentionPolicy: "rp", Measurement: "counter0", Field: "value"},
+			`SELECT "value" FROM "db"."rp"."counter0" ORDER BY time DESC`,
+		},
+		{
+			"Tags",
+			InfluxQLQuery{Measurement: "counter0", Field: "value", Tags: map[string]string{"key2": "it's", "key1": "value1"}, From: from, To: to, Limit: 1},
+			`SELECT "value" FROM "counter0" WHERE "key1" = 'value1' AND "key2" = 'it\'s' AND time > 1546398185000000000 AND time <= 1546398245000000000 GROUP BY * ORDER BY time DESC LIMIT 1`,
+		},
+		{
+			"Quote",
+			InfluxQLQuery{Measurement: `a"b`, Field: "value"},
+			`SELECT "value" FROM "a\"b" ORDER BY time DESC`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.query.String(); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+func TestFluxQuery_String(t *testing.T) {
+	q := FluxQuery{
+		Bucket:      "opencensus",
+		Measurement: "counter0",
+		Field:       "value",
+		Tags:        map[string]string{"key2": `say "hi"`, "key1": "value1"},
+		From:        time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		To:          time.Date(2019, 1, 2, 3, 5, 5, 0, time.UTC),
+		Last:        true,
+	}
+	want := strings.Join([]string{
+		`from(bucket: "opencensus")`,
+		`  |> range(start: 2019-01-02T03:04:05Z, stop: 2019-01-02T03:05:05Z)`,
+		`  |> filter(fn: (r) => r._measurement == "counter0" and r._field == "value" and r["key1"] == "value1" and r["key2"] == "say \"hi\"")`,
+		`  |> last()`,
+	}, "\n")
+	if got := q.String(); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func Test_parseCSV(t *testing.T) {
+	t.Run("Tables", func(t *testing.T) {
+		body := strings.Join([]string{
+			"#datatype,string,long,dateTime:RFC3339,double,string",
+			"#group,false,false,false,false,true",
+			"#default,_result,,,,",
+			",result,table,_time,_value,_field",
+			",,0,2019-01-02T03:04:05Z,1,value",
+			",,0,2019-01-02T03:04:15Z,,value",
+			"",
+			"#datatype,string,long,dateTime:RFC3339,boolean,string",
+			"#group,false,false,false,false,true",
+			"#default,_result,,,,",
+			",result,table,_time,_value,_field",
+			",,1,2019-01-02T03:04:25Z,true,value",
+			"",
+		}, "\r\n")
+		points, err := parseCSV(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(points), 2; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := points[1].Value, 1.0; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := points[1].Time, time.Date(2019, 1, 2, 3, 4, 25, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		body := "#datatype,string,string\n#group,true,true\n#default,,\n,error,reference\n,failed to execute query,897\n"
+		if _, err := parseCSV(strings.NewReader(body)); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Not Numeric", func(t *testing.T) {
+		body := ",result,table,_time,_value\n,,0,2019-01-02T03:04:05Z,hello\n"
+		if _, err := parseCSV(strings.NewReader(body)); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
@@ -0,0 +1,16 @@
+// Package newest chooses the most recent of an importer's points
+package newest
+
+import "time"
+
+// Index returns the index of the most recent of n points or -1 if there are none
+// When points are equally recent, the first is chosen; time returns the time of the point with index i
+func Index(n int, time func(i int) time.Time) int {
+	result := -1
+	for i := 0; i < n; i++ {
+		if result == -1 || time(i).After(time(result)) {
+			result = i
+		}
+	}
+	return result
+}
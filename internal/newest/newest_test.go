@@ -0,0 +1,26 @@
+package newest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name  string
+		times []time.Time
+		want  int
+	}{
+		{"None", nil, -1},
+		{"One", []time.Time{now}, 0},
+		{"Newest", []time.Time{now.Add(-time.Minute), now, now.Add(-time.Second)}, 1},
+		{"Equal", []time.Time{now, now}, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Index(len(test.times), func(i int) time.Time { return test.times[i] }); got != test.want {
+				t.Errorf("got %d; want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package standin is the core of the importers' test servers
+// A Server returns the responses seeded for the exact text (e.g. the query) of each request and records what it receives
+package standin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Server is an HTTP server that returns canned responses keyed on the text of each request
+// Requests without a response fail with 400 Bad Request
+type Server struct {
+	server *httptest.Server
+	mux    *http.ServeMux
+	key    func(r *http.Request) (string, error)
+
+	mu        sync.Mutex
+	responses map[string]response
+	queries   []string
+	headers   []http.Header
+	status    int
+}
+
+// response is a canned response
+type response struct {
+	contentType string
+	body        string
+}
+
+// NewServer creates and starts a new Server with no responses
+// key returns the text that identifies a request e.g. its query
+func NewServer(key func(r *http.Request) (string, error)) *Server {
+	s := &Server{
+		key:       key,
+		mux:       http.NewServeMux(),
+		responses: map[string]response{},
+	}
+	s.mux.HandleFunc("/", s.handle)
+	s.server = httptest.NewServer(s.mux)
+	return s
+}
+
+// Handle serves the pattern's requests with the handler rather than canned responses e.g. to upgrade a WebSocket
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// URL returns the Server's base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns an HTTP client configured to talk to the Server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetResponse seeds the body (of the content type) that's returned for requests with the text
+func (s *Server) SetResponse(text, contentType, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[text] = response{contentType, body}
+}
+
+// SetStatus makes the Server fail every subsequent request with the HTTP status code
+// Use http.StatusOK (or 0) to return to normal
+func (s *Server) SetStatus(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+}
+
+// Queries returns the text of each request the Server has received
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.queries...)
+}
+
+// Headers returns the headers of each request the Server has received
+func (s *Server) Headers() []http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]http.Header{}, s.headers...)
+}
+
+// handle returns the response for the request's text
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	text, err := s.key(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.queries = append(s.queries, text)
+	s.headers = append(s.headers, r.Header.Clone())
+	code := s.status
+	resp, ok := s.responses[text]
+	s.mu.Unlock()
+
+	if code != 0 && code != http.StatusOK {
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unexpected request '%s'", text), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", resp.contentType)
+	fmt.Fprint(w, resp.body)
+}
@@ -0,0 +1,67 @@
+package standin
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(func(r *http.Request) (string, error) {
+		return r.URL.Query().Get("q"), nil
+	})
+	defer s.Close()
+	s.SetResponse("query", "text/plain", "result")
+
+	get := func(q string) int {
+		req, err := http.NewRequest(http.MethodGet, s.URL()+"/?q="+q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Token "+q)
+		resp, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, test := range []struct {
+		name   string
+		status int
+		query  string
+		want   int
+	}{
+		{"Response", http.StatusOK, "query", http.StatusOK},
+		{"Unexpected Request", http.StatusOK, "other", http.StatusBadRequest},
+		{"SetStatus", http.StatusServiceUnavailable, "query", http.StatusServiceUnavailable},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s.SetStatus(test.status)
+			if got := get(test.query); got != test.want {
+				t.Errorf("got %d; want %d", got, test.want)
+			}
+		})
+	}
+	if got, want := len(s.Queries()), 3; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := s.Headers()[1].Get("Authorization"), "Token other"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	t.Run("Handle", func(t *testing.T) {
+		s.Handle("/other", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		resp, err := s.Client().Get(s.URL() + "/other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got, want := resp.StatusCode, http.StatusAccepted; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := len(s.Queries()), 3; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
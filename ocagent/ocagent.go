@@ -0,0 +1,148 @@
+package ocagent
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+	"google.golang.org/grpc"
+)
+
+// defaultAddr is the OpenCensus Agent's (and ocagent exporter's) default address
+const defaultAddr = "localhost:55678"
+
+// Importer represents the inverse of an OpenCensus Exporter
+// Rather than query a service, it implements the OpenCensus Agent's MetricsService and answers reads from the metrics it's received
+// Point the ocagent exporter at Addr e.g. ocagent.WithAddress(i.Addr()), ocagent.WithInsecure()
+type Importer struct {
+	name     string
+	options  Options
+	listener net.Listener
+	server   *grpc.Server
+
+	mu      sync.Mutex
+	metrics map[string][]Point
+	nodes   []*commonpb.Node
+}
+
+// NewImporter creates a new importer using the Options provided and starts its MetricsService
+// Use Close to stop the MetricsService
+func NewImporter(o Options) (*Importer, error) {
+	if o.Addr == "" {
+		o.Addr = defaultAddr
+	}
+	switch o.DistributionField {
+	case DistributionSum, DistributionCount, DistributionMean, DistributionSumOfSquaredDeviation:
+	default:
+		return nil, errors.New("Unknown DistributionField")
+	}
+	listener, err := net.Listen("tcp", o.Addr)
+	if err != nil {
+		return nil, err
+	}
+	i := &Importer{
+		name:     "ocagent",
+		options:  o,
+		listener: listener,
+		server:   grpc.NewServer(),
+		metrics:  map[string][]Point{},
+	}
+	agentmetricspb.RegisterMetricsServiceServer(i.server, &metricsService{importer: i})
+	go i.server.Serve(listener)
+	return i, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Addr returns the address (host:port) of the MetricsService
+func (i *Importer) Addr() string {
+	return i.listener.Addr().String()
+}
+
+// Close stops the MetricsService, closing exporters' streams
+func (i *Importer) Close() error {
+	i.server.Stop()
+	return nil
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+// Distributions return the Options' DistributionField and summaries their sum (or count)
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.value(i.options.DistributionField)
+}
+
+// Point returns the most recent Point, at or before the time specified, for the View with the label values
+// The View's label names are matched against the time-series' label keys; other labels are ignored
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return Point{}, errors.New("Inconsistency between labels and values")
+	}
+	points := i.Points(i.options.MetricPrefix + v.Name)
+	if len(points) == 0 {
+		return Point{}, errors.New("No metric has been received with the View's name")
+	}
+	candidates := []Point{}
+	for _, p := range points {
+		if !p.Time.After(t) && p.matches(v.LabelNames, labelValues) {
+			candidates = append(candidates, p)
+		}
+	}
+	j := newest.Index(len(candidates), func(j int) time.Time { return candidates[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No points match the labels")
+	}
+	return candidates[j], nil
+}
+
+// Points returns every Point that's been received for the metric
+func (i *Importer) Points(name string) []Point {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]Point{}, i.metrics[name]...)
+}
+
+// Nodes returns the Node that identified each of the exporters' streams
+func (i *Importer) Nodes() []*commonpb.Node {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]*commonpb.Node{}, i.nodes...)
+}
+
+// add stores the points by metric name
+func (i *Importer) add(points map[string][]Point) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for name, ps := range points {
+		i.metrics[name] = append(i.metrics[name], ps...)
+	}
+}
+
+// addNode records an exporter's Node
+func (i *Importer) addNode(node *commonpb.Node) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.nodes = append(i.nodes, node)
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// Addr is the address on which the MetricsService listens; defaults to localhost:55678
+	// Use port 0 to listen on any free port
+	Addr string
+	// MetricPrefix is prepended to the View's name to give the metric's name
+	MetricPrefix string
+	// DistributionField determines which part of a distribution point is returned; defaults to the sum
+	DistributionField DistributionField
+}
@@ -0,0 +1,232 @@
+package ocagent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/dazwilkin/opencensus/stats/view"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// newTestImporter creates an Importer whose MetricsService listens on a free port
+func newTestImporter(t *testing.T, o Options) *Importer {
+	o.Addr = "127.0.0.1:0"
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+// metric creates a metric with a time-series for each of the label values
+func metric(name string, typ metricspb.MetricDescriptor_Type, labelValues [][]string, points ...*metricspb.Point) *metricspb.Metric {
+	m := &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{
+			Name:      name,
+			Type:      typ,
+			LabelKeys: []*metricspb.LabelKey{{Key: "key1"}, {Key: "key2"}},
+		},
+	}
+	for j, values := range labelValues {
+		ts := &metricspb.TimeSeries{
+			Points: []*metricspb.Point{points[j]},
+		}
+		for _, value := range values {
+			ts.LabelValues = append(ts.LabelValues, &metricspb.LabelValue{Value: value, HasValue: value != ""})
+		}
+		m.Timeseries = append(m.Timeseries, ts)
+	}
+	return m
+}
+
+// send streams the requests to the Importer, as the ocagent exporter does, and waits for them to be received
+func send(t *testing.T, i *Importer, reqs ...*agentmetricspb.ExportMetricsServiceRequest) {
+	conn, err := grpc.Dial(i.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	stream, err := agentmetricspb.NewMetricsServiceClient(conn).Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	// The Importer ends the stream once it's received every request
+	stream.Recv()
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("Unknown DistributionField", func(t *testing.T) {
+		if _, err := NewImporter(Options{Addr: "127.0.0.1:0", DistributionField: DistributionField(9)}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Address In Use", func(t *testing.T) {
+		i := newTestImporter(t, Options{})
+		defer i.Close()
+		if _, err := NewImporter(Options{Addr: i.Addr()}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Name", func(t *testing.T) {
+		i := newTestImporter(t, Options{})
+		defer i.Close()
+		if got, want := i.Name(), "ocagent"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *timestamppb.Timestamp {
+		return timestamppb.New(now.Add(d))
+	}
+	int64Point := func(d time.Duration, v int64) *metricspb.Point {
+		return &metricspb.Point{Timestamp: at(d), Value: &metricspb.Point_Int64Value{Int64Value: v}}
+	}
+	distribution := &metricspb.Point{Timestamp: at(0), Value: &metricspb.Point_DistributionValue{DistributionValue: &metricspb.DistributionValue{
+		Count:                 4,
+		Sum:                   10,
+		SumOfSquaredDeviation: 5,
+		BucketOptions: &metricspb.DistributionValue_BucketOptions{Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
+			Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{Bounds: []float64{2}},
+		}},
+		Buckets: []*metricspb.DistributionValue_Bucket{{Count: 1}, {Count: 3}},
+	}}}
+	summary := &metricspb.Point{Timestamp: at(0), Value: &metricspb.Point_SummaryValue{SummaryValue: &metricspb.SummaryValue{
+		Count: wrapperspb.Int64(3),
+		Sum:   wrapperspb.Double(6),
+	}}}
+	values := []string{"value1", "value2"}
+	reqs := []*agentmetricspb.ExportMetricsServiceRequest{
+		{Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "test"}}},
+		{Metrics: []*metricspb.Metric{
+			metric("counter0", metricspb.MetricDescriptor_CUMULATIVE_INT64, [][]string{values, {"value3", ""}}, int64Point(-time.Minute, 1), int64Point(0, 7)),
+			metric("gauge0", metricspb.MetricDescriptor_GAUGE_DOUBLE, [][]string{values}, &metricspb.Point{Timestamp: at(0), Value: &metricspb.Point_DoubleValue{DoubleValue: 0.5}}),
+			metric("distribution0", metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION, [][]string{values}, distribution),
+			metric("summary0", metricspb.MetricDescriptor_SUMMARY, [][]string{values}, summary),
+			// Dropped
+			{Timeseries: []*metricspb.TimeSeries{{}}},
+		}},
+		{Metrics: []*metricspb.Metric{
+			metric("counter0", metricspb.MetricDescriptor_CUMULATIVE_INT64, [][]string{values}, int64Point(0, 2)),
+		}},
+	}
+	v := func(name string) *view.View {
+		return &view.View{
+			Name:       name,
+			LabelNames: []string{"key1", "key2"},
+		}
+	}
+	for _, test := range []struct {
+		name        string
+		options     Options
+		view        *view.View
+		labelValues []string
+		want        float64
+	}{
+		{"Cumulative", Options{}, v("counter0"), values, 2},
+		{"Unset Label", Options{}, v("counter0"), []string{"value3", ""}, 7},
+		{"Gauge", Options{}, v("gauge0"), values, 0.5},
+		{"Distribution Sum", Options{}, v("distribution0"), values, 10},
+		{"Distribution Mean", Options{DistributionField: DistributionMean}, v("distribution0"), values, 2.5},
+		{"Distribution Sum Of Squared Deviation", Options{DistributionField: DistributionSumOfSquaredDeviation}, v("distribution0"), values, 5},
+		{"Summary Count", Options{DistributionField: DistributionCount}, v("summary0"), values, 3},
+		{"MetricPrefix", Options{MetricPrefix: "gauge"}, v("0"), values, 0.5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, test.options)
+			defer i.Close()
+			send(t, i, reqs...)
+			got, err := i.Value(test.view, test.labelValues, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+
+	i := newTestImporter(t, Options{})
+	defer i.Close()
+	send(t, i, reqs...)
+	t.Run("Before", func(t *testing.T) {
+		got, err := i.Value(v("counter0"), values, now.Add(-time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1.0; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Summary Sum Of Squared Deviation", func(t *testing.T) {
+		i := newTestImporter(t, Options{DistributionField: DistributionSumOfSquaredDeviation})
+		defer i.Close()
+		send(t, i, reqs...)
+		if _, err := i.Value(v("summary0"), values, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unknown Metric", func(t *testing.T) {
+		if _, err := i.Value(v("counter1"), values, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Points", func(t *testing.T) {
+		if _, err := i.Value(v("counter0"), []string{"value1", "value9"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Label Mismatch", func(t *testing.T) {
+		if _, err := i.Value(v("counter0"), []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Nodes", func(t *testing.T) {
+		nodes := i.Nodes()
+		if got, want := len(nodes), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := nodes[0].GetServiceInfo().GetName(), "test"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Distribution Buckets", func(t *testing.T) {
+		points := i.Points("distribution0")
+		if got, want := len(points), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := len(points[0].Buckets), 2; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
+func Test_toPoints(t *testing.T) {
+	t.Run("Label Mismatch", func(t *testing.T) {
+		m := metric("counter0", metricspb.MetricDescriptor_CUMULATIVE_INT64, [][]string{{"value1"}}, &metricspb.Point{Value: &metricspb.Point_Int64Value{Int64Value: 1}})
+		if _, err := toPoints(m, nil); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Value", func(t *testing.T) {
+		m := metric("counter0", metricspb.MetricDescriptor_CUMULATIVE_INT64, [][]string{{"value1", "value2"}}, &metricspb.Point{})
+		if _, err := toPoints(m, nil); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
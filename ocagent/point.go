@@ -0,0 +1,152 @@
+package ocagent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+)
+
+// DistributionField determines which part of a distribution point is returned
+type DistributionField int
+
+// DistributionFields
+const (
+	// DistributionSum returns the sum of the recorded values (the default)
+	DistributionSum DistributionField = iota
+	DistributionCount
+	DistributionMean
+	DistributionSumOfSquaredDeviation
+)
+
+// String returns the DistributionField's name
+func (f DistributionField) String() string {
+	switch f {
+	case DistributionSum:
+		return "sum"
+	case DistributionCount:
+		return "count"
+	case DistributionMean:
+		return "mean"
+	case DistributionSumOfSquaredDeviation:
+		return "sum_of_squared_deviation"
+	default:
+		return "unknown"
+	}
+}
+
+// Point represents a point of a time-series that's been received
+type Point struct {
+	// Type is the metric descriptor's type e.g. CUMULATIVE_INT64
+	Type metricspb.MetricDescriptor_Type
+	// Labels maps the descriptor's label keys to the time-series' label values; unset values are ""
+	Labels map[string]string
+	// Resource is the metric's (or else the request's) resource
+	Resource *resourcepb.Resource
+	// Start is the time-series' start time (for cumulative metrics); Time is the point's time
+	Start time.Time
+	Time  time.Time
+	// Value is an INT64 or DOUBLE point's value
+	Value float64
+	// Count, Sum and SumOfSquaredDeviation summarize a distribution (or summary) point
+	Count                 int64
+	Sum                   float64
+	SumOfSquaredDeviation float64
+	// Bounds and Buckets are a distribution's explicit bucket bounds and counts
+	Bounds  []float64
+	Buckets []int64
+}
+
+// matches returns true if the Point has each of the labels with the value
+func (p Point) matches(labels, values []string) bool {
+	for j, label := range labels {
+		if v, ok := p.Labels[label]; !ok || v != values[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the Point's value; distributions and summaries return the field
+func (p Point) value(field DistributionField) (float64, error) {
+	switch p.Type {
+	case metricspb.MetricDescriptor_GAUGE_INT64, metricspb.MetricDescriptor_GAUGE_DOUBLE,
+		metricspb.MetricDescriptor_CUMULATIVE_INT64, metricspb.MetricDescriptor_CUMULATIVE_DOUBLE:
+		return p.Value, nil
+	case metricspb.MetricDescriptor_SUMMARY:
+		if field == DistributionSumOfSquaredDeviation {
+			return 0.0, errors.New("Summaries don't include the sum of squared deviation")
+		}
+	}
+	switch field {
+	case DistributionSum:
+		return p.Sum, nil
+	case DistributionCount:
+		return float64(p.Count), nil
+	case DistributionMean:
+		if p.Count == 0 {
+			return 0.0, errors.New("Unable to calculate the mean of an empty distribution")
+		}
+		return p.Sum / float64(p.Count), nil
+	case DistributionSumOfSquaredDeviation:
+		return p.SumOfSquaredDeviation, nil
+	default:
+		return 0.0, errors.New("Unknown DistributionField")
+	}
+}
+
+// toPoints decodes the metric's time-series; the resource is used if the metric has none
+func toPoints(m *metricspb.Metric, resource *resourcepb.Resource) ([]Point, error) {
+	descriptor := m.GetMetricDescriptor()
+	if descriptor == nil {
+		return nil, errors.New("Metric has no descriptor")
+	}
+	if m.GetResource() != nil {
+		resource = m.GetResource()
+	}
+	keys := descriptor.GetLabelKeys()
+	points := []Point{}
+	for _, ts := range m.GetTimeseries() {
+		if len(ts.GetLabelValues()) != len(keys) {
+			return nil, fmt.Errorf("Metric '%s' has %d label keys but a time-series has %d values", descriptor.GetName(), len(keys), len(ts.GetLabelValues()))
+		}
+		labels := make(map[string]string, len(keys))
+		for j, key := range keys {
+			labels[key.GetKey()] = ts.GetLabelValues()[j].GetValue()
+		}
+		for _, pt := range ts.GetPoints() {
+			p := Point{
+				Type:     descriptor.GetType(),
+				Labels:   labels,
+				Resource: resource,
+				Time:     pt.GetTimestamp().AsTime(),
+			}
+			if ts.GetStartTimestamp() != nil {
+				p.Start = ts.GetStartTimestamp().AsTime()
+			}
+			switch v := pt.GetValue().(type) {
+			case *metricspb.Point_Int64Value:
+				p.Value = float64(v.Int64Value)
+			case *metricspb.Point_DoubleValue:
+				p.Value = v.DoubleValue
+			case *metricspb.Point_DistributionValue:
+				p.Count = v.DistributionValue.GetCount()
+				p.Sum = v.DistributionValue.GetSum()
+				p.SumOfSquaredDeviation = v.DistributionValue.GetSumOfSquaredDeviation()
+				p.Bounds = v.DistributionValue.GetBucketOptions().GetExplicit().GetBounds()
+				for _, b := range v.DistributionValue.GetBuckets() {
+					p.Buckets = append(p.Buckets, b.GetCount())
+				}
+			case *metricspb.Point_SummaryValue:
+				p.Count = v.SummaryValue.GetCount().GetValue()
+				p.Sum = v.SummaryValue.GetSum().GetValue()
+			default:
+				return nil, fmt.Errorf("Metric '%s' has a point without a value", descriptor.GetName())
+			}
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
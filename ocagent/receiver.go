@@ -0,0 +1,48 @@
+package ocagent
+
+import (
+	"io"
+
+	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/golang/glog"
+)
+
+// metricsService implements the OpenCensus Agent's MetricsService
+type metricsService struct {
+	agentmetricspb.UnimplementedMetricsServiceServer
+	importer *Importer
+}
+
+// Export receives the stream of requests from an exporter until it's closed
+// The first request identifies the exporter's Node; a request's Resource applies to those of its metrics without one
+// As with the OpenCensus Service, metrics that can't be decoded are dropped and no responses are sent
+func (s *metricsService) Export(stream agentmetricspb.MetricsService_ExportServer) error {
+	var resource *resourcepb.Resource
+	for first := true; ; first = false {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first && req.GetNode() != nil {
+			s.importer.addNode(req.GetNode())
+		}
+		if req.GetResource() != nil {
+			resource = req.GetResource()
+		}
+		received := map[string][]Point{}
+		for _, m := range req.GetMetrics() {
+			points, err := toPoints(m, resource)
+			if err != nil {
+				glog.Warning(err)
+				continue
+			}
+			name := m.GetMetricDescriptor().GetName()
+			received[name] = append(received[name], points...)
+		}
+		s.importer.add(received)
+	}
+}
@@ -0,0 +1,79 @@
+package ocagent_test
+
+import (
+	"testing"
+	"time"
+
+	exporter "contrib.go.opencensus.io/exporter/ocagent"
+	"github.com/dazwilkin/opencensus/ocagent"
+	importer_view "github.com/dazwilkin/opencensus/stats/view"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// TestImporter_RoundTrip exports a View using the ocagent exporter and reads it using the Importer
+func TestImporter_RoundTrip(t *testing.T) {
+	i, err := ocagent.NewImporter(ocagent.Options{
+		Addr: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+
+	e, err := exporter.NewExporter(
+		exporter.WithInsecure(),
+		exporter.WithAddress(i.Addr()),
+		exporter.WithServiceName("roundtrip"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	key1, err := tag.NewKey("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &view.View{
+		Name:        "counter0",
+		Description: "Testing",
+		Measure:     stats.Int64("counter0", "Testing", "1"),
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{key1},
+	}
+	end := time.Now()
+	data := &view.Data{
+		View:  v,
+		Start: end.Add(-time.Minute),
+		End:   end,
+		Rows: []*view.Row{{
+			Tags: []tag.Tag{{Key: key1, Value: "value1"}},
+			Data: &view.SumData{Value: 42},
+		}},
+	}
+
+	// The exporter connects and sends asynchronously
+	for deadline := time.Now().Add(5 * time.Second); len(i.Points("counter0")) == 0; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("got no points; want 1")
+		}
+		e.ExportView(data)
+		e.Flush()
+	}
+
+	got, err := i.Value(&importer_view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}, []string{"value1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 42.0; got != want {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if nodes := i.Nodes(); len(nodes) == 0 || nodes[0].GetServiceInfo().GetName() != "roundtrip" {
+		t.Errorf("got %v; want the exporter's node", nodes)
+	}
+}
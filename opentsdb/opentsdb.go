@@ -0,0 +1,166 @@
+package opentsdb
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const (
+	// defaultURL is the TSD's default address
+	defaultURL = "http://localhost:4242"
+	// defaultAggregator combines series that share the View's tags but differ in others e.g. host
+	defaultAggregator = "sum"
+	defaultWindow     = 5 * time.Minute
+)
+
+// aggregators are OpenTSDB's (2.3) aggregation functions
+var aggregators = map[string]bool{
+	"avg": true, "count": true, "dev": true, "first": true, "last": true, "max": true, "min": true, "mimmax": true, "mimmin": true,
+	"none": true, "sum": true, "zimsum": true,
+	"p50": true, "p75": true, "p90": true, "p95": true, "p99": true, "p999": true,
+}
+
+// downsample matches OpenTSDB's downsample specification e.g. 1m-avg or 1h-sum-zero
+var downsample = regexp.MustCompile(`^(0all|\d+(ms|s|m|h|d|w|n|y))-[a-z0-9]+(-(none|nan|null|zero))?$`)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from OpenTSDB's HTTP API; a View is a metric and its label names are tag keys
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+// The URL defaults to the value of environment variable OPENTSDB_URL
+func NewImporter(o Options) (*Importer, error) {
+	if o.URL == "" {
+		o.URL = os.Getenv("OPENTSDB_URL")
+	}
+	if o.URL == "" {
+		o.URL = defaultURL
+	}
+	o.URL = strings.TrimSuffix(o.URL, "/")
+	if o.Aggregator == "" {
+		o.Aggregator = defaultAggregator
+	}
+	if !aggregators[o.Aggregator] {
+		return nil, fmt.Errorf("Unknown Aggregator '%s'", o.Aggregator)
+	}
+	if o.Downsample != "" && !downsample.MatchString(o.Downsample) {
+		return nil, fmt.Errorf("Invalid Downsample '%s'; expect e.g. 1m-avg", o.Downsample)
+	}
+	if o.Last && o.Downsample != "" {
+		return nil, errors.New("Downsample can't be used with Last")
+	}
+	if o.BackScan < 0 {
+		return nil, errors.New("BackScan must not be negative")
+	}
+	return &Importer{
+		name:    "opentsdb",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+// With Last, /api/query/last returns the most recent point of each series regardless of the time specified
+// Label values can't include "|"; it separates the alternatives of a tag value
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return Point{}, errors.New("Inconsistency between labels and values")
+	}
+	tags := map[string]string{}
+	for j, labelName := range v.LabelNames {
+		if strings.Contains(labelValues[j], "|") {
+			return Point{}, fmt.Errorf("Label value '%s' includes '|' which would match as alternatives", labelValues[j])
+		}
+		tags[labelName] = labelValues[j]
+	}
+	metric := i.options.MetricPrefix + v.Name
+
+	var (
+		points []Point
+		err    error
+	)
+	if i.options.Last {
+		q := &LastQuery{
+			Queries: []LastSubQuery{{
+				Metric: metric,
+				Tags:   tags,
+			}},
+			ResolveNames: true,
+			BackScan:     i.options.BackScan,
+		}
+		log.Println(q)
+		points, err = i.last(q)
+	} else {
+		window := i.options.Window
+		if window == 0 {
+			window = defaultWindow
+		}
+		q := NewQuery(metric, i.options.Aggregator, t.Add(-window), t)
+		q.Queries[0].Downsample = i.options.Downsample
+		q.AddFilters(tags)
+		log.Println(q)
+		points, err = i.query(q)
+	}
+	if err != nil {
+		return Point{}, err
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No data points match the query")
+	}
+	return points[j], nil
+}
+
+// client returns the HTTP client used to make requests
+func (i *Importer) client() *http.Client {
+	if i.options.HTTPClient != nil {
+		return i.options.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// URL is the TSD's base URL; defaults to environment variable OPENTSDB_URL or http://localhost:4242
+	URL string
+	// MetricPrefix is prepended to the View's name to give the metric
+	MetricPrefix string
+	// Aggregator combines the series that match the View's tags; defaults to sum
+	// Use none to read the series without aggregation
+	Aggregator string
+	// Downsample (e.g. 1m-avg) reduces each series to a point per interval before aggregation
+	Downsample string
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 5 minutes
+	Window time.Duration
+	// Last uses /api/query/last to read the most recent point of each series; Aggregator and Window don't apply
+	Last bool
+	// BackScan is the number of hours /api/query/last searches back when the TSD doesn't track the latest point; 0 doesn't scan
+	BackScan int
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
@@ -0,0 +1,219 @@
+package opentsdb
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/opentsdb/opentsdbtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// newTestImporter creates an Importer that talks to an opentsdbtest.Server
+func newTestImporter(t *testing.T, s *opentsdbtest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		os.Unsetenv("OPENTSDB_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "opentsdb"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.URL, "http://localhost:4242"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Aggregator, "sum"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("OPENTSDB_URL", "http://tsd:4242/")
+		defer os.Unsetenv("OPENTSDB_URL")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.options.URL, "http://tsd:4242"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name    string
+		options Options
+	}{
+		{"Unknown Aggregator", Options{Aggregator: "median"}},
+		{"Invalid Downsample", Options{Downsample: "avg"}},
+		{"Downsample With Last", Options{Downsample: "1m-avg", Last: true}},
+		{"Negative BackScan", Options{Last: true, BackScan: -1}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewImporter(test.options); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func TestImporter_Value(t *testing.T) {
+	s := opentsdbtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	tags := func(host string) map[string]string {
+		return map[string]string{"key1": "value1", "key2": "value2", "host": host}
+	}
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		query   string
+		results []opentsdbtest.Result
+		want    float64
+	}{
+		{
+			"Sum",
+			Options{},
+			`{"start":1546397940000,"end":1546398240000,"queries":[{"aggregator":"sum","metric":"counter0","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`,
+			[]opentsdbtest.Result{{
+				Metric:        "counter0",
+				Tags:          map[string]string{"key1": "value1", "key2": "value2"},
+				AggregateTags: []string{"host"},
+				DataPoints: []opentsdbtest.DataPoint{
+					{Timestamp: now.Add(-90 * time.Second), Value: 1},
+					{Timestamp: now.Add(-20 * time.Second), Value: 12},
+					{Timestamp: now.Add(-30 * time.Second), Value: 2},
+				},
+			}},
+			12,
+		},
+		{
+			"None",
+			Options{Aggregator: "none"},
+			`{"start":1546397940000,"end":1546398240000,"queries":[{"aggregator":"none","metric":"counter0","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`,
+			[]opentsdbtest.Result{
+				{Metric: "counter0", Tags: tags("a"), DataPoints: []opentsdbtest.DataPoint{{Timestamp: now.Add(-20 * time.Second), Value: 4}}},
+				{Metric: "counter0", Tags: tags("b"), DataPoints: []opentsdbtest.DataPoint{{Timestamp: now.Add(-30 * time.Second), Value: 8}}},
+			},
+			4,
+		},
+		{
+			"Downsample",
+			Options{Downsample: "1m-sum"},
+			`{"start":1546397940000,"end":1546398240000,"queries":[{"aggregator":"sum","metric":"counter0","downsample":"1m-sum","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`,
+			[]opentsdbtest.Result{{Metric: "counter0", DataPoints: []opentsdbtest.DataPoint{{Timestamp: now.Add(-time.Minute), Value: 14}}}},
+			14,
+		},
+		{
+			"MetricPrefix",
+			Options{MetricPrefix: "prefix."},
+			`{"start":1546397940000,"end":1546398240000,"queries":[{"aggregator":"sum","metric":"prefix.counter0","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`,
+			[]opentsdbtest.Result{{Metric: "prefix.counter0", DataPoints: []opentsdbtest.DataPoint{{Timestamp: now.Add(-10 * time.Second), Value: 64}}}},
+			64,
+		},
+		{
+			"Window",
+			Options{Window: time.Minute},
+			`{"start":1546398180000,"end":1546398240000,"queries":[{"aggregator":"sum","metric":"counter0","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`,
+			[]opentsdbtest.Result{{Metric: "counter0", DataPoints: []opentsdbtest.DataPoint{{Timestamp: now.Add(-20 * time.Second), Value: 12}}}},
+			12,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s.SetQueryResult(test.query, test.results...)
+			i := newTestImporter(t, s, test.options)
+			got, err := i.Value(v, []string{"value1", "value2"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+			queries := s.Queries()
+			if got, want := queries[len(queries)-1], "/api/query "+test.query; got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		query   string
+	}{
+		{"Last", Options{Last: true}, `{"queries":[{"metric":"counter0","tags":{"key1":"value1","key2":"value2"}}],"resolveNames":true,"backScan":0}`},
+		{"BackScan", Options{Last: true, BackScan: 24}, `{"queries":[{"metric":"counter0","tags":{"key1":"value1","key2":"value2"}}],"resolveNames":true,"backScan":24}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s.SetLastResult(test.query,
+				opentsdbtest.LastResult{Metric: "counter0", Tags: tags("a"), DataPoint: opentsdbtest.DataPoint{Timestamp: now.Add(-time.Hour), Value: 16}},
+				opentsdbtest.LastResult{Metric: "counter0", Tags: tags("b"), DataPoint: opentsdbtest.DataPoint{Timestamp: now.Add(time.Minute), Value: 32}},
+			)
+			i := newTestImporter(t, s, test.options)
+			got, err := i.Value(v, []string{"value1", "value2"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The most recent point of any series, even after the time specified
+			if want := 32.0; got != want {
+				t.Errorf("got %v; want %v", got, want)
+			}
+			queries := s.Queries()
+			if got, want := queries[len(queries)-1], "/api/query/last "+test.query; got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+	s.SetQueryResult(`{"start":1546397940000,"end":1546398240000,"queries":[{"aggregator":"sum","metric":"counter0","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value9","groupBy":false}]}],"msResolution":true}`)
+	s.SetLastResult(`{"queries":[{"metric":"counter0","tags":{"key1":"value1","key2":"value9"}}],"resolveNames":true,"backScan":0}`)
+	for _, test := range []struct {
+		name        string
+		options     Options
+		labelValues []string
+	}{
+		{"No Data Points", Options{}, []string{"value1", "value9"}},
+		{"Last No Data Points", Options{Last: true}, []string{"value1", "value9"}},
+		{"Unknown Metric", Options{MetricPrefix: "unknown."}, []string{"value1", "value2"}},
+		{"Label Mismatch", Options{}, []string{"value1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			if _, err := i.Value(v, test.labelValues, now); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+	for _, last := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Separator Last=%t", last), func(t *testing.T) {
+			n := len(s.Queries())
+			i := newTestImporter(t, s, Options{Last: last})
+			if _, err := i.Value(v, []string{"value1|value3", "value2"}, now); err == nil {
+				t.Errorf("got nil; want error")
+			}
+			if got := len(s.Queries()); got != n {
+				t.Errorf("got %d; want %d", got, n)
+			}
+		})
+	}
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusServiceUnavailable)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
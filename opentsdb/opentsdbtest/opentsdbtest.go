@@ -0,0 +1,101 @@
+package opentsdbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for a TSD's /api/query and /api/query/last
+// It returns the results seeded for each exact request: the path and its (JSON) body e.g. "/api/query/last {...}"
+type Server struct {
+	*standin.Server
+}
+
+// NewServer creates and starts a new Server with no results
+func NewServer() *Server {
+	return &Server{standin.NewServer(request)}
+}
+
+// request returns the text of a query: its path and body
+func request(r *http.Request) (string, error) {
+	if r.URL.Path != "/api/query" && r.URL.Path != "/api/query/last" {
+		return "", fmt.Errorf("Endpoint not found '%s'", r.URL.Path)
+	}
+	if r.Method != http.MethodPost {
+		return "", fmt.Errorf("Method not allowed '%s'", r.Method)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	return r.URL.Path + " " + string(b), nil
+}
+
+// DataPoint represents a value at a time
+type DataPoint struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Result represents a series of the response to /api/query
+type Result struct {
+	Metric        string
+	Tags          map[string]string
+	AggregateTags []string
+	DataPoints    []DataPoint
+}
+
+// SetQueryResult seeds the series that /api/query returns for the (JSON) query
+// Timestamps are returned in milliseconds, as with msResolution
+func (s *Server) SetQueryResult(query string, results ...Result) {
+	type result struct {
+		Metric        string             `json:"metric"`
+		Tags          map[string]string  `json:"tags"`
+		AggregateTags []string           `json:"aggregateTags"`
+		DPS           map[string]float64 `json:"dps"`
+	}
+	rs := []result{}
+	for _, r := range results {
+		dps := map[string]float64{}
+		for _, dp := range r.DataPoints {
+			dps[strconv.FormatInt(dp.Timestamp.UnixNano()/int64(time.Millisecond), 10)] = dp.Value
+		}
+		rs = append(rs, result{r.Metric, r.Tags, r.AggregateTags, dps})
+	}
+	b, _ := json.Marshal(rs)
+	s.SetResponse("/api/query "+query, "application/json", string(b))
+}
+
+// LastResult represents a series' most recent data point in the response to /api/query/last
+type LastResult struct {
+	Metric    string
+	Tags      map[string]string
+	DataPoint DataPoint
+}
+
+// SetLastResult seeds the data points that /api/query/last returns for the (JSON) query
+func (s *Server) SetLastResult(query string, results ...LastResult) {
+	type result struct {
+		Metric    string            `json:"metric"`
+		Tags      map[string]string `json:"tags"`
+		Timestamp int64             `json:"timestamp"`
+		Value     string            `json:"value"`
+	}
+	rs := []result{}
+	for _, r := range results {
+		rs = append(rs, result{
+			Metric:    r.Metric,
+			Tags:      r.Tags,
+			Timestamp: r.DataPoint.Timestamp.UnixNano() / int64(time.Millisecond),
+			Value:     strconv.FormatFloat(r.DataPoint.Value, 'f', -1, 64),
+		})
+	}
+	b, _ := json.Marshal(rs)
+	s.SetResponse("/api/query/last "+query, "application/json", string(b))
+}
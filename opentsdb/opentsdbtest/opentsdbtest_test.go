@@ -0,0 +1,65 @@
+package opentsdbtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	s.SetQueryResult(`{"queries":[{"metric":"counter0"}]}`, Result{
+		Metric:     "counter0",
+		Tags:       map[string]string{"host": "a"},
+		DataPoints: []DataPoint{{now, 1.5}},
+	})
+	s.SetLastResult(`{"queries":[{"metric":"counter0"}]}`, LastResult{
+		Metric:    "counter0",
+		Tags:      map[string]string{"host": "a"},
+		DataPoint: DataPoint{now, 2.5},
+	})
+	post := func(path, body string) (int, string) {
+		resp, err := s.Client().Post(s.URL()+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	for _, test := range []struct {
+		name string
+		path string
+		body string
+		code int
+		want string
+	}{
+		{"Query", "/api/query", `{"queries":[{"metric":"counter0"}]}`, http.StatusOK, `[{"metric":"counter0","tags":{"host":"a"},"aggregateTags":null,"dps":{"1546398245000":1.5}}]`},
+		{"Last", "/api/query/last", `{"queries":[{"metric":"counter0"}]}`, http.StatusOK, `[{"metric":"counter0","tags":{"host":"a"},"timestamp":1546398245000,"value":"2.5"}]`},
+		{"Unexpected Query", "/api/query", `{"queries":[{"metric":"counter1"}]}`, http.StatusBadRequest, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code, body := post(test.path, test.body)
+			if code != test.code {
+				t.Errorf("got %d; want %d", code, test.code)
+			}
+			if test.want != "" && body != test.want {
+				t.Errorf("got %s; want %s", body, test.want)
+			}
+		})
+	}
+	want := `/api/query {"queries":[{"metric":"counter0"}]}` + "\n" +
+		`/api/query/last {"queries":[{"metric":"counter0"}]}` + "\n" +
+		`/api/query {"queries":[{"metric":"counter1"}]}`
+	if got := strings.Join(s.Queries(), "\n"); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
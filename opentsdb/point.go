@@ -0,0 +1,41 @@
+package opentsdb
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Point represents a data point's value at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Points returns the Result's data points
+func (r Result) Points() ([]Point, error) {
+	points := make([]Point, 0, len(r.DPS))
+	for k, v := range r.DPS {
+		ms, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid timestamp '%s'", k)
+		}
+		points = append(points, Point{
+			Time:  time.Unix(0, ms*int64(time.Millisecond)),
+			Value: v,
+		})
+	}
+	return points, nil
+}
+
+// Point returns the LastResult's data point
+func (r LastResult) Point() (Point, error) {
+	v, err := strconv.ParseFloat(r.Value, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("Invalid value '%s'", r.Value)
+	}
+	return Point{
+		Time:  time.Unix(0, r.Timestamp*int64(time.Millisecond)),
+		Value: v,
+	}, nil
+}
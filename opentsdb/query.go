@@ -0,0 +1,179 @@
+package opentsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query represents the body of a request to /api/query
+type Query struct {
+	Start   int64      `json:"start"`
+	End     int64      `json:"end,omitempty"`
+	Queries []SubQuery `json:"queries"`
+	// MsResolution returns timestamps in milliseconds
+	MsResolution bool `json:"msResolution,omitempty"`
+}
+
+// SubQuery represents a metric query
+type SubQuery struct {
+	Aggregator string   `json:"aggregator"`
+	Metric     string   `json:"metric"`
+	Downsample string   `json:"downsample,omitempty"`
+	Filters    []Filter `json:"filters,omitempty"`
+}
+
+// Filter represents a tag filter
+type Filter struct {
+	Type    string `json:"type"`
+	Tagk    string `json:"tagk"`
+	Filter  string `json:"filter"`
+	GroupBy bool   `json:"groupBy"`
+}
+
+// NewQuery creates a Query for the metric between the times specified
+func NewQuery(metric, aggregator string, start, end time.Time) *Query {
+	return &Query{
+		Start: start.UnixNano() / int64(time.Millisecond),
+		End:   end.UnixNano() / int64(time.Millisecond),
+		Queries: []SubQuery{{
+			Aggregator: aggregator,
+			Metric:     metric,
+		}},
+		MsResolution: true,
+	}
+}
+
+// AddFilters adds a literal filter for each of the tags; filters are sorted so that the query is deterministic
+// literal_or treats "|" as a separator so tag values can't include it; Importer.Point rejects label values that do
+func (q *Query) AddFilters(tags map[string]string) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		q.Queries[0].Filters = append(q.Queries[0].Filters, Filter{
+			Type:   "literal_or",
+			Tagk:   k,
+			Filter: tags[k],
+		})
+	}
+}
+
+// String returns the Query as JSON
+func (q *Query) String() string {
+	b, _ := json.Marshal(q)
+	return string(b)
+}
+
+// LastQuery represents the body of a request to /api/query/last
+type LastQuery struct {
+	Queries      []LastSubQuery `json:"queries"`
+	ResolveNames bool           `json:"resolveNames"`
+	BackScan     int            `json:"backScan"`
+}
+
+// LastSubQuery represents a metric (and tags) whose most recent points are requested
+type LastSubQuery struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+// String returns the LastQuery as JSON
+func (q *LastQuery) String() string {
+	b, _ := json.Marshal(q)
+	return string(b)
+}
+
+// Result represents a series in the response to /api/query
+// With msResolution, the keys of the data points are millisecond timestamps
+type Result struct {
+	Metric        string             `json:"metric"`
+	Tags          map[string]string  `json:"tags"`
+	AggregateTags []string           `json:"aggregateTags"`
+	DPS           map[string]float64 `json:"dps"`
+}
+
+// LastResult represents a series' most recent point in the response to /api/query/last
+// The timestamp is in milliseconds and the value is a string
+type LastResult struct {
+	Metric    string            `json:"metric"`
+	Tags      map[string]string `json:"tags"`
+	Timestamp int64             `json:"timestamp"`
+	Value     string            `json:"value"`
+	TSUID     string            `json:"tsuid"`
+}
+
+// errorResponse represents OpenTSDB's error response
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// query sends the Query to /api/query and returns the points of every series
+func (i *Importer) query(q *Query) ([]Point, error) {
+	var results []Result
+	if err := i.post("/api/query", q, &results); err != nil {
+		return nil, err
+	}
+	points := []Point{}
+	for _, r := range results {
+		ps, err := r.Points()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, ps...)
+	}
+	return points, nil
+}
+
+// last sends the LastQuery to /api/query/last and returns the most recent point of every series
+func (i *Importer) last(q *LastQuery) ([]Point, error) {
+	var results []LastResult
+	if err := i.post("/api/query/last", q, &results); err != nil {
+		return nil, err
+	}
+	points := make([]Point, 0, len(results))
+	for _, r := range results {
+		p, err := r.Point()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// post sends the body as JSON to the API's path and decodes the response into result
+func (i *Importer) post(path string, body interface{}, result interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := i.client().Post(i.options.URL+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e); err != nil || e.Error.Message == "" {
+			return fmt.Errorf("Query failed (%s)", resp.Status)
+		}
+		// OpenTSDB returns 400 when no series match e.g. "No such name for 'metrics'"
+		return fmt.Errorf("Query failed (%s): %s", resp.Status, strings.TrimSpace(e.Error.Message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("Unable to decode response: %s", err)
+	}
+	return nil
+}
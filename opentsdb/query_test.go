@@ -0,0 +1,58 @@
+package opentsdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+)
+
+func TestQuery_String(t *testing.T) {
+	q := NewQuery("counter0", "sum", time.Unix(1546398185, 0), time.Unix(1546398245, 0))
+	q.Queries[0].Downsample = "1m-avg"
+	q.AddFilters(map[string]string{"key2": "value2", "key1": "value1"})
+	want := `{"start":1546398185000,"end":1546398245000,"queries":[{"aggregator":"sum","metric":"counter0","downsample":"1m-avg","filters":[{"type":"literal_or","tagk":"key1","filter":"value1","groupBy":false},{"type":"literal_or","tagk":"key2","filter":"value2","groupBy":false}]}],"msResolution":true}`
+	if got := q.String(); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestLastQuery_String(t *testing.T) {
+	q := &LastQuery{
+		Queries:      []LastSubQuery{{Metric: "counter0", Tags: map[string]string{"key1": "value1"}}},
+		ResolveNames: true,
+		BackScan:     24,
+	}
+	want := `{"queries":[{"metric":"counter0","tags":{"key1":"value1"}}],"resolveNames":true,"backScan":24}`
+	if got := q.String(); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestResult_Points(t *testing.T) {
+	t.Run("Points", func(t *testing.T) {
+		points, err := Result{DPS: map[string]float64{"1546398185000": 1, "1546398245500": 2}}.Points()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := points[newest.Index(len(points), func(j int) time.Time { return points[j].Time })]
+		if got, want := p.Time, time.Unix(1546398245, 500*int64(time.Millisecond)); !got.Equal(want) {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Invalid Timestamp", func(t *testing.T) {
+		if _, err := (Result{DPS: map[string]float64{"x": 1}}).Points(); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestLastResult_Point(t *testing.T) {
+	p, err := LastResult{Timestamp: 1546398245000, Value: "42.5"}.Point()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Value, 42.5; got != want {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if _, err := (LastResult{Value: "x"}).Point(); err == nil {
+		t.Errorf("got nil; want error")
+	}
+}
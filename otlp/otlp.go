@@ -0,0 +1,159 @@
+package otlp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+const (
+	// defaultGRPCAddr and defaultHTTPAddr are OTLP's default ports
+	defaultGRPCAddr = "localhost:4317"
+	defaultHTTPAddr = "localhost:4318"
+)
+
+// Importer represents the inverse of an OpenCensus Exporter
+// Rather than query a service, it runs an in-process OTLP (gRPC and HTTP) metrics receiver and answers reads from the metrics it's received
+// Point OpenTelemetry (or OpenCensus) exporters at GRPCAddr or HTTPAddr
+type Importer struct {
+	name    string
+	options Options
+	store   *store
+
+	grpcListener net.Listener
+	grpcServer   *grpc.Server
+	httpListener net.Listener
+	httpServer   *http.Server
+}
+
+// NewImporter creates a new importer using the Options provided and starts its receivers
+// Use Close to stop the receivers
+func NewImporter(o Options) (*Importer, error) {
+	if o.GRPCAddr == "" {
+		o.GRPCAddr = defaultGRPCAddr
+	}
+	if o.HTTPAddr == "" {
+		o.HTTPAddr = defaultHTTPAddr
+	}
+	switch o.HistogramField {
+	case HistogramSum, HistogramCount, HistogramMean, HistogramMin, HistogramMax:
+	default:
+		return nil, errors.New("Unknown HistogramField")
+	}
+	grpcListener, err := net.Listen("tcp", o.GRPCAddr)
+	if err != nil {
+		return nil, err
+	}
+	httpListener, err := net.Listen("tcp", o.HTTPAddr)
+	if err != nil {
+		grpcListener.Close()
+		return nil, err
+	}
+	i := &Importer{
+		name:         "otlp",
+		options:      o,
+		store:        newStore(),
+		grpcListener: grpcListener,
+		grpcServer:   grpc.NewServer(),
+		httpListener: httpListener,
+	}
+	colmetricspb.RegisterMetricsServiceServer(i.grpcServer, &metricsService{store: i.store})
+	mux := http.NewServeMux()
+	mux.Handle("/v1/metrics", &metricsHandler{store: i.store})
+	i.httpServer = &http.Server{Handler: mux}
+
+	go i.grpcServer.Serve(grpcListener)
+	go i.httpServer.Serve(httpListener)
+	return i, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// GRPCAddr returns the address (host:port) of the OTLP/gRPC receiver
+func (i *Importer) GRPCAddr() string {
+	return i.grpcListener.Addr().String()
+}
+
+// HTTPAddr returns the address (host:port) of the OTLP/HTTP receiver; metrics are POSTed to /v1/metrics
+func (i *Importer) HTTPAddr() string {
+	return i.httpListener.Addr().String()
+}
+
+// Close stops the receivers
+func (i *Importer) Close() error {
+	i.grpcServer.Stop()
+	return i.httpServer.Close()
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+// Gauges and sums return their value; histograms return the Options' HistogramField
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.value(i.options.HistogramField)
+}
+
+// Point returns the most recent Point, at or before the time specified, for the View with the label values
+// The View's label names are matched against the points' attributes; other attributes are ignored
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return Point{}, errors.New("Inconsistency between labels and values")
+	}
+	attributes := map[string]string{}
+	for j, labelName := range v.LabelNames {
+		attributes[labelName] = labelValues[j]
+	}
+	points := i.store.points(i.options.MetricPrefix + v.Name)
+	if len(points) == 0 {
+		return Point{}, errors.New("No metric has been received with the View's name")
+	}
+	candidates := []Point{}
+	for _, p := range points {
+		if !p.Time.After(t) && matches(p.Attributes, attributes) {
+			candidates = append(candidates, p)
+		}
+	}
+	j := newest.Index(len(candidates), func(j int) time.Time { return candidates[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No points match the labels")
+	}
+	return candidates[j], nil
+}
+
+// Points returns every Point that's been received for the metric
+func (i *Importer) Points(name string) []Point {
+	return i.store.points(name)
+}
+
+// matches returns true if the attributes include every one of want
+func matches(attributes, want map[string]string) bool {
+	for k, v := range want {
+		if a, ok := attributes[k]; !ok || a != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// GRPCAddr and HTTPAddr are the addresses on which the receivers listen; default to localhost:4317 and localhost:4318
+	// Use port 0 to listen on any free port
+	GRPCAddr string
+	HTTPAddr string
+	// MetricPrefix is prepended to the View's name to give the metric's name
+	MetricPrefix string
+	// HistogramField determines which part of a (exponential) histogram point is returned; defaults to the sum
+	HistogramField HistogramField
+}
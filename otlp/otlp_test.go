@@ -0,0 +1,347 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestImporter creates an Importer whose receivers listen on free ports
+func newTestImporter(t *testing.T, o Options) *Importer {
+	o.GRPCAddr = "127.0.0.1:0"
+	o.HTTPAddr = "127.0.0.1:0"
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+// kv creates a string attribute
+func kv(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// request creates an export request with a sum, a gauge, a histogram and an exponential histogram
+func request(now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	ns := func(t time.Time) uint64 {
+		return uint64(t.UnixNano())
+	}
+	float := func(f float64) *float64 {
+		return &f
+	}
+	labels := []*commonpb.KeyValue{kv("key1", "value1"), kv("key2", "value2")}
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{
+					{
+						Name: "counter0",
+						Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+							IsMonotonic:            true,
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							DataPoints: []*metricspb.NumberDataPoint{
+								{Attributes: labels, TimeUnixNano: ns(now.Add(-time.Minute)), Value: &metricspb.NumberDataPoint_AsInt{AsInt: 1}},
+								{Attributes: labels, TimeUnixNano: ns(now), Value: &metricspb.NumberDataPoint_AsInt{AsInt: 2}},
+								{Attributes: append(labels, kv("host", "b")), TimeUnixNano: ns(now.Add(-30 * time.Second)), Value: &metricspb.NumberDataPoint_AsInt{AsInt: 5}},
+								{Attributes: []*commonpb.KeyValue{kv("key1", "value3"), kv("key2", "value2")}, TimeUnixNano: ns(now), Value: &metricspb.NumberDataPoint_AsInt{AsInt: 7}},
+							},
+						}},
+					},
+					{
+						Name: "gauge0",
+						Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+							DataPoints: []*metricspb.NumberDataPoint{
+								{Attributes: labels, TimeUnixNano: ns(now), Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 0.5}},
+							},
+						}},
+					},
+					{
+						Name: "histogram0",
+						Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							DataPoints: []*metricspb.HistogramDataPoint{
+								{Attributes: labels, TimeUnixNano: ns(now), Count: 4, Sum: float(10), Min: float(1), Max: float(4), BucketCounts: []uint64{1, 3}, ExplicitBounds: []float64{2}},
+							},
+						}},
+					},
+					{
+						Name: "exponential0",
+						Data: &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+							DataPoints: []*metricspb.ExponentialHistogramDataPoint{
+								{Attributes: labels, TimeUnixNano: ns(now), Count: 3, Sum: float(9), Scale: 1, ZeroCount: 1, Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{Offset: 2, BucketCounts: []uint64{2}}},
+							},
+						}},
+					},
+				},
+			}},
+		}},
+	}
+}
+
+// exportGRPC exports the request using OTLP/gRPC (with gzip compression)
+func exportGRPC(t *testing.T, i *Importer, req *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceResponse {
+	conn, err := grpc.Dial(i.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := colmetricspb.NewMetricsServiceClient(conn).Export(context.Background(), req, grpc.UseCompressor("gzip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// exportHTTP exports the request using OTLP/HTTP with the content type
+func exportHTTP(t *testing.T, i *Importer, req *colmetricspb.ExportMetricsServiceRequest, contentType string) *colmetricspb.ExportMetricsServiceResponse {
+	var (
+		b   []byte
+		err error
+	)
+	if contentType == contentTypeJSON {
+		b, err = protojson.Marshal(req)
+	} else {
+		b, err = proto.Marshal(req)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post("http://"+i.HTTPAddr()+"/v1/metrics", contentType, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := resp.Header.Get("Content-Type"), contentType; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &colmetricspb.ExportMetricsServiceResponse{}
+	if contentType == contentTypeJSON {
+		err = protojson.Unmarshal(body, result)
+	} else {
+		err = proto.Unmarshal(body, result)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func Test_NewImporter(t *testing.T) {
+	t.Run("Unknown HistogramField", func(t *testing.T) {
+		if _, err := NewImporter(Options{GRPCAddr: "127.0.0.1:0", HTTPAddr: "127.0.0.1:0", HistogramField: HistogramField(9)}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Address In Use", func(t *testing.T) {
+		i := newTestImporter(t, Options{})
+		defer i.Close()
+		if _, err := NewImporter(Options{GRPCAddr: "127.0.0.1:0", HTTPAddr: i.HTTPAddr()}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Name", func(t *testing.T) {
+		i := newTestImporter(t, Options{})
+		defer i.Close()
+		if got, want := i.Name(), "otlp"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	now := time.Now()
+	v := func(name string) *view.View {
+		return &view.View{
+			Name:       name,
+			LabelNames: []string{"key1", "key2"},
+		}
+	}
+	// The same assertions hold whichever transport the exporter uses
+	for _, transport := range []struct {
+		name   string
+		export func(*testing.T, *Importer, *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceResponse
+	}{
+		{"gRPC", exportGRPC},
+		{"HTTP Protobuf", func(t *testing.T, i *Importer, req *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceResponse {
+			return exportHTTP(t, i, req, contentTypeProtobuf)
+		}},
+		{"HTTP JSON", func(t *testing.T, i *Importer, req *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceResponse {
+			return exportHTTP(t, i, req, contentTypeJSON)
+		}},
+	} {
+		t.Run(transport.name, func(t *testing.T) {
+			for _, test := range []struct {
+				name        string
+				options     Options
+				view        *view.View
+				labelValues []string
+				want        float64
+			}{
+				{"Sum", Options{}, v("counter0"), []string{"value1", "value2"}, 2},
+				{"Labels", Options{}, v("counter0"), []string{"value3", "value2"}, 7},
+				{"Gauge", Options{}, v("gauge0"), []string{"value1", "value2"}, 0.5},
+				{"Histogram Sum", Options{}, v("histogram0"), []string{"value1", "value2"}, 10},
+				{"Histogram Mean", Options{HistogramField: HistogramMean}, v("histogram0"), []string{"value1", "value2"}, 2.5},
+				{"Histogram Max", Options{HistogramField: HistogramMax}, v("histogram0"), []string{"value1", "value2"}, 4},
+				{"Exponential Histogram Count", Options{HistogramField: HistogramCount}, v("exponential0"), []string{"value1", "value2"}, 3},
+				{"MetricPrefix", Options{MetricPrefix: "gauge"}, v("0"), []string{"value1", "value2"}, 0.5},
+			} {
+				t.Run(test.name, func(t *testing.T) {
+					i := newTestImporter(t, test.options)
+					defer i.Close()
+					if resp := transport.export(t, i, request(now)); resp.GetPartialSuccess() != nil {
+						t.Errorf("got %v; want nil", resp.GetPartialSuccess())
+					}
+					got, err := i.Value(test.view, test.labelValues, now)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != test.want {
+						t.Errorf("got %v; want %v", got, test.want)
+					}
+				})
+			}
+		})
+	}
+	i := newTestImporter(t, Options{})
+	defer i.Close()
+	exportGRPC(t, i, request(now))
+	t.Run("Before", func(t *testing.T) {
+		got, err := i.Value(v("counter0"), []string{"value1", "value2"}, now.Add(-10*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The host=b point is newer than the first point
+		if want := 5.0; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Unknown Metric", func(t *testing.T) {
+		if _, err := i.Value(v("counter1"), []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("No Points", func(t *testing.T) {
+		if _, err := i.Value(v("counter0"), []string{"value1", "value9"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Label Mismatch", func(t *testing.T) {
+		if _, err := i.Value(v("counter0"), []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Points", func(t *testing.T) {
+		points := i.Points("exponential0")
+		if got, want := len(points), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := points[0].Kind, ExponentialHistogram; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := points[0].Offset, int32(2); got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
+func TestImporter_Summary(t *testing.T) {
+	i := newTestImporter(t, Options{})
+	defer i.Close()
+	req := request(time.Now())
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0]
+	metrics.Metrics = append(metrics.Metrics, &metricspb.Metric{
+		Name: "summary0",
+		Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+			DataPoints: []*metricspb.SummaryDataPoint{{Count: 1}, {Count: 2}},
+		}},
+	})
+	resp := exportGRPC(t, i, req)
+	if got, want := resp.GetPartialSuccess().GetRejectedDataPoints(), int64(2); got != want {
+		t.Errorf("got %d; want %d", got, want)
+	}
+	// The other metrics are stored
+	if got, want := len(i.Points("counter0")), 4; got != want {
+		t.Errorf("got %d; want %d", got, want)
+	}
+}
+func TestMetricsHandler(t *testing.T) {
+	i := newTestImporter(t, Options{})
+	defer i.Close()
+	url := "http://" + i.HTTPAddr() + "/v1/metrics"
+
+	t.Run("Gzip", func(t *testing.T) {
+		b, err := proto.Marshal(request(time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(b)
+		gz.Close()
+		req, err := http.NewRequest(http.MethodPost, url, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentTypeProtobuf)
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"Method", http.MethodGet, contentTypeProtobuf, "", http.StatusMethodNotAllowed},
+		{"Content-Type", http.MethodPost, "text/plain", "", http.StatusUnsupportedMediaType},
+		{"Invalid JSON", http.MethodPost, contentTypeJSON, "{", http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, url, bytes.NewBufferString(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", test.contentType)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.StatusCode; got != test.want {
+				t.Errorf("got %d; want %d", got, test.want)
+			}
+		})
+	}
+}
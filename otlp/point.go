@@ -0,0 +1,252 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// Kind is the type of metric a Point belongs to
+type Kind int
+
+// Kinds
+const (
+	Gauge Kind = iota
+	Sum
+	Histogram
+	ExponentialHistogram
+)
+
+// String returns the Kind's name
+func (k Kind) String() string {
+	switch k {
+	case Gauge:
+		return "gauge"
+	case Sum:
+		return "sum"
+	case Histogram:
+		return "histogram"
+	case ExponentialHistogram:
+		return "exponential_histogram"
+	default:
+		return "unknown"
+	}
+}
+
+// HistogramField determines which part of a (exponential) histogram point is returned
+type HistogramField int
+
+// HistogramFields
+const (
+	// HistogramSum returns the sum of the recorded values (the default)
+	HistogramSum HistogramField = iota
+	HistogramCount
+	HistogramMean
+	// HistogramMin and HistogramMax require the point to include them
+	HistogramMin
+	HistogramMax
+)
+
+// String returns the HistogramField's name
+func (f HistogramField) String() string {
+	switch f {
+	case HistogramSum:
+		return "sum"
+	case HistogramCount:
+		return "count"
+	case HistogramMean:
+		return "mean"
+	case HistogramMin:
+		return "min"
+	case HistogramMax:
+		return "max"
+	default:
+		return "unknown"
+	}
+}
+
+// Point represents a data point that's been received
+type Point struct {
+	Kind Kind
+	// Attributes are the data point's attributes; values are converted to strings
+	Attributes map[string]string
+	Time       time.Time
+	// Value is a gauge's or sum's value
+	Value float64
+	// Monotonic and Temporality describe a sum (and Temporality, a histogram); delta points are stored as received
+	Monotonic   bool
+	Temporality metricspb.AggregationTemporality
+	// Count, Sum, Min and Max summarize a histogram; Sum, Min and Max are nil if they weren't recorded
+	Count uint64
+	Sum   *float64
+	Min   *float64
+	Max   *float64
+	// Buckets are a histogram's bucket counts; Bounds are its explicit bounds
+	Buckets []uint64
+	Bounds  []float64
+	// Scale and ZeroCount are an exponential histogram's; its Buckets are the positive buckets from Offset
+	Scale     int32
+	ZeroCount uint64
+	Offset    int32
+}
+
+// value returns the Point's value; histograms return the field
+func (p Point) value(field HistogramField) (float64, error) {
+	if p.Kind == Gauge || p.Kind == Sum {
+		return p.Value, nil
+	}
+	optional := func(f *float64) (float64, error) {
+		if f == nil {
+			return 0.0, fmt.Errorf("The %s point doesn't include its %s", p.Kind, field)
+		}
+		return *f, nil
+	}
+	switch field {
+	case HistogramSum:
+		return optional(p.Sum)
+	case HistogramCount:
+		return float64(p.Count), nil
+	case HistogramMean:
+		sum, err := optional(p.Sum)
+		if err != nil {
+			return 0.0, err
+		}
+		if p.Count == 0 {
+			return 0.0, errors.New("Unable to calculate the mean of an empty histogram")
+		}
+		return sum / float64(p.Count), nil
+	case HistogramMin:
+		return optional(p.Min)
+	case HistogramMax:
+		return optional(p.Max)
+	default:
+		return 0.0, errors.New("Unknown HistogramField")
+	}
+}
+
+// toPoints converts the metric's data points; summaries aren't supported
+func toPoints(m *metricspb.Metric) ([]Point, error) {
+	points := []Point{}
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			points = append(points, numberPoint(Gauge, dp))
+		}
+	case *metricspb.Metric_Sum:
+		for _, dp := range data.Sum.GetDataPoints() {
+			p := numberPoint(Sum, dp)
+			p.Monotonic = data.Sum.GetIsMonotonic()
+			p.Temporality = data.Sum.GetAggregationTemporality()
+			points = append(points, p)
+		}
+	case *metricspb.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			points = append(points, Point{
+				Kind:        Histogram,
+				Attributes:  attributes(dp.GetAttributes()),
+				Time:        unixNano(dp.GetTimeUnixNano()),
+				Temporality: data.Histogram.GetAggregationTemporality(),
+				Count:       dp.GetCount(),
+				Sum:         dp.Sum,
+				Min:         dp.Min,
+				Max:         dp.Max,
+				Buckets:     dp.GetBucketCounts(),
+				Bounds:      dp.GetExplicitBounds(),
+			})
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			points = append(points, Point{
+				Kind:        ExponentialHistogram,
+				Attributes:  attributes(dp.GetAttributes()),
+				Time:        unixNano(dp.GetTimeUnixNano()),
+				Temporality: data.ExponentialHistogram.GetAggregationTemporality(),
+				Count:       dp.GetCount(),
+				Sum:         dp.Sum,
+				Min:         dp.Min,
+				Max:         dp.Max,
+				Buckets:     dp.GetPositive().GetBucketCounts(),
+				Offset:      dp.GetPositive().GetOffset(),
+				Scale:       dp.GetScale(),
+				ZeroCount:   dp.GetZeroCount(),
+			})
+		}
+	case *metricspb.Metric_Summary:
+		return nil, fmt.Errorf("Summary metric '%s' isn't supported", m.GetName())
+	default:
+		return nil, fmt.Errorf("Metric '%s' has no data", m.GetName())
+	}
+	return points, nil
+}
+
+// numberPoint converts a gauge's or sum's data point
+func numberPoint(kind Kind, dp *metricspb.NumberDataPoint) Point {
+	p := Point{
+		Kind:       kind,
+		Attributes: attributes(dp.GetAttributes()),
+		Time:       unixNano(dp.GetTimeUnixNano()),
+	}
+	switch v := dp.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsDouble:
+		p.Value = v.AsDouble
+	case *metricspb.NumberDataPoint_AsInt:
+		p.Value = float64(v.AsInt)
+	}
+	return p
+}
+
+// unixNano converts OTLP's timestamps
+func unixNano(ns uint64) time.Time {
+	return time.Unix(0, int64(ns))
+}
+
+// attributes converts the key-values into a map of strings
+func attributes(kvs []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+	return m
+}
+
+// anyValue converts an attribute's value into a string; arrays and maps are rendered as [a,b] and {k=v}
+func anyValue(v *commonpb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := []string{}
+		for _, value := range v.ArrayValue.GetValues() {
+			values = append(values, anyValue(value))
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case *commonpb.AnyValue_KvlistValue:
+		m := attributes(v.KvlistValue.GetValues())
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for j, k := range keys {
+			values[j] = k + "=" + m[k]
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	}
+	return ""
+}
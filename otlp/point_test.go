@@ -0,0 +1,79 @@
+package otlp
+
+import (
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestPoint_value(t *testing.T) {
+	float := func(f float64) *float64 {
+		return &f
+	}
+	histogram := Point{Kind: Histogram, Count: 4, Sum: float(10), Min: float(1)}
+	for _, test := range []struct {
+		field HistogramField
+		want  float64
+	}{
+		{HistogramSum, 10},
+		{HistogramCount, 4},
+		{HistogramMean, 2.5},
+		{HistogramMin, 1},
+	} {
+		t.Run(test.field.String(), func(t *testing.T) {
+			got, err := histogram.value(test.field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+	t.Run("No Max", func(t *testing.T) {
+		if _, err := histogram.value(HistogramMax); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Empty Mean", func(t *testing.T) {
+		if _, err := (Point{Kind: ExponentialHistogram, Sum: float(0)}).value(HistogramMean); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Gauge", func(t *testing.T) {
+		got, err := (Point{Kind: Gauge, Value: 3}).value(HistogramCount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 3.0; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+}
+func Test_anyValue(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		value *commonpb.AnyValue
+		want  string
+	}{
+		{"String", &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "a"}}, "a"},
+		{"Bool", &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}, "true"},
+		{"Int", &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: -1}}, "-1"},
+		{"Double", &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}}, "0.5"},
+		{"Array", &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+			Values: []*commonpb.AnyValue{
+				{Value: &commonpb.AnyValue_StringValue{StringValue: "a"}},
+				{Value: &commonpb.AnyValue_IntValue{IntValue: 1}},
+			},
+		}}}, "[a,1]"},
+		{"Map", &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+			Values: []*commonpb.KeyValue{kv("b", "2"), kv("a", "1")},
+		}}}, "{a=1,b=2}"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := anyValue(test.value); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
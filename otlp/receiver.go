@@ -0,0 +1,114 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	// Exporters commonly compress requests
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// metricsService implements OTLP/gRPC's MetricsService
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	store *store
+}
+
+// Export stores the metrics of the request
+func (s *metricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	return s.store.export(req), nil
+}
+
+// metricsHandler implements OTLP/HTTP's /v1/metrics for both binary (application/x-protobuf) and JSON (application/json) encodings
+type metricsHandler struct {
+	store *store
+}
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// ServeHTTP stores the metrics of a POSTed request and responds with the same encoding
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != contentTypeProtobuf && contentType != contentTypeJSON) {
+		http.Error(w, "Unsupported Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			h.fail(w, contentType, http.StatusBadRequest, err)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		http.Error(w, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		h.fail(w, contentType, http.StatusBadRequest, err)
+		return
+	}
+
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	if contentType == contentTypeJSON {
+		err = protojson.Unmarshal(b, req)
+	} else {
+		err = proto.Unmarshal(b, req)
+	}
+	if err != nil {
+		h.fail(w, contentType, http.StatusBadRequest, err)
+		return
+	}
+	h.write(w, contentType, http.StatusOK, h.store.export(req))
+}
+
+// fail responds with a Status message as OTLP/HTTP requires
+func (h *metricsHandler) fail(w http.ResponseWriter, contentType string, code int, err error) {
+	h.write(w, contentType, code, &status.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: err.Error(),
+	})
+}
+
+// write responds with the message in the request's encoding
+func (h *metricsHandler) write(w http.ResponseWriter, contentType string, code int, m proto.Message) {
+	var (
+		b   []byte
+		err error
+	)
+	if contentType == contentTypeJSON {
+		b, err = protojson.Marshal(m)
+	} else {
+		b, err = proto.Marshal(m)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	w.Write(b)
+}
@@ -0,0 +1,71 @@
+package otlp
+
+import (
+	"strings"
+	"sync"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// store holds the points received, by metric name
+type store struct {
+	mu      sync.Mutex
+	metrics map[string][]Point
+}
+
+// newStore creates an empty store
+func newStore() *store {
+	return &store{
+		metrics: map[string][]Point{},
+	}
+}
+
+// export stores the request's metrics
+// Metrics that can't be stored are rejected; the partial success reports how many data points were rejected and why
+func (s *store) export(req *colmetricspb.ExportMetricsServiceRequest) *colmetricspb.ExportMetricsServiceResponse {
+	var (
+		rejected int64
+		reasons  []string
+	)
+	received := map[string][]Point{}
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				points, err := toPoints(m)
+				if err != nil {
+					rejected += dataPoints(m)
+					reasons = append(reasons, err.Error())
+					continue
+				}
+				received[m.GetName()] = append(received[m.GetName()], points...)
+			}
+		}
+	}
+	s.mu.Lock()
+	for name, points := range received {
+		s.metrics[name] = append(s.metrics[name], points...)
+	}
+	s.mu.Unlock()
+
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 || len(reasons) > 0 {
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       strings.Join(reasons, "; "),
+		}
+	}
+	return resp
+}
+
+// points returns the points of the metric
+func (s *store) points(name string) []Point {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Point{}, s.metrics[name]...)
+}
+
+// dataPoints returns the number of data points in a summary (or a metric without data)
+func dataPoints(m *metricspb.Metric) int64 {
+	return int64(len(m.GetSummary().GetDataPoints()))
+}
@@ -0,0 +1,22 @@
+package signalfx
+
+import (
+	"sort"
+	"time"
+)
+
+// Point represents a time series' value at a (logical) time
+// TSID and Dimensions identify the time series; Dimensions is empty when an Aggregate combines time series
+type Point struct {
+	Time       time.Time
+	Value      float64
+	TSID       string
+	Dimensions map[string]string
+}
+
+// sortPoints sorts the points oldest first
+func sortPoints(points []Point) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+}
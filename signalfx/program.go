@@ -0,0 +1,52 @@
+package signalfx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Program represents a SignalFlow program that publishes a metric's time series filtered by dimensions
+type Program struct {
+	Metric    string
+	Filters   [][2]string
+	Rollup    string
+	Aggregate string
+}
+
+// NewProgram creates a Program for the metric
+func NewProgram(metric string) *Program {
+	return &Program{
+		Metric: metric,
+	}
+}
+
+// AddFilter restricts the Program to time series with the dimension
+func (p *Program) AddFilter(key, value string) {
+	p.Filters = append(p.Filters, [2]string{key, value})
+}
+
+// String returns the Program's SignalFlow
+// e.g. data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2'), rollup='latest').sum().publish()
+func (p *Program) String() string {
+	args := []string{Quote(p.Metric)}
+	if len(p.Filters) > 0 {
+		filters := make([]string, len(p.Filters))
+		for j, f := range p.Filters {
+			filters[j] = fmt.Sprintf("filter(%s, %s)", Quote(f[0]), Quote(f[1]))
+		}
+		args = append(args, "filter="+strings.Join(filters, " and "))
+	}
+	if p.Rollup != "" {
+		args = append(args, "rollup="+Quote(p.Rollup))
+	}
+	s := fmt.Sprintf("data(%s)", strings.Join(args, ", "))
+	if p.Aggregate != "" {
+		s += "." + p.Aggregate + "()"
+	}
+	return s + ".publish()"
+}
+
+// Quote returns the string as a single-quoted SignalFlow literal; wildcards (*) can't be escaped so they match as wildcards
+func Quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
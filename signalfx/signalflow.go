@@ -0,0 +1,364 @@
+package signalfx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// channel is the name of the (only) channel used by each connection
+const channel = "ch-1"
+
+// Execute represents a request to execute a SignalFlow program
+// Times are milliseconds since the epoch and a Resolution of 0 lets SignalFlow choose
+type Execute struct {
+	Type       string `json:"type"`
+	Channel    string `json:"channel"`
+	Program    string `json:"program"`
+	Start      int64  `json:"start"`
+	Stop       int64  `json:"stop"`
+	Resolution int64  `json:"resolution,omitempty"`
+	Immediate  bool   `json:"immediate"`
+}
+
+// Message represents a (JSON) SignalFlow message
+// Data messages are binary over WebSockets; they're decoded into Messages too
+type Message struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	Event     string `json:"event"`
+	AbortInfo struct {
+		Reason string `json:"sf_job_abortReason"`
+		State  string `json:"sf_job_abortState"`
+	} `json:"abortInfo"`
+	TSID               string                 `json:"tsId"`
+	Properties         map[string]interface{} `json:"properties"`
+	LogicalTimestampMs int64                  `json:"logicalTimestampMs"`
+	Data               []Datum                `json:"data"`
+	Error              int                    `json:"error"`
+	Message            string                 `json:"message"`
+}
+
+// Datum represents a time series' value in a data message
+type Datum struct {
+	TSID  string  `json:"tsId"`
+	Value float64 `json:"value"`
+}
+
+// collector accumulates the points of an execution's data messages using the dimensions of its metadata messages
+type collector struct {
+	dimensions map[string]map[string]string
+	points     []Point
+	done       bool
+}
+
+// newCollector creates a new collector
+func newCollector() *collector {
+	return &collector{
+		dimensions: map[string]map[string]string{},
+		points:     []Point{},
+	}
+}
+
+// handle processes a message; the collector is done when the channel ends
+func (c *collector) handle(m Message) error {
+	switch m.Type {
+	case "error":
+		return fmt.Errorf("Execution failed (%d): %s", m.Error, m.Message)
+	case "control-message":
+		switch m.Event {
+		case "END_OF_CHANNEL":
+			c.done = true
+		case "CHANNEL_ABORT":
+			return fmt.Errorf("Execution aborted (%s): %s", m.AbortInfo.State, m.AbortInfo.Reason)
+		}
+	case "metadata":
+		dimensions := map[string]string{}
+		for k, v := range m.Properties {
+			// sf_ properties (e.g. sf_metric) describe the time series rather than being dimensions
+			if s, ok := v.(string); ok && !strings.HasPrefix(k, "sf_") {
+				dimensions[k] = s
+			}
+		}
+		c.dimensions[m.TSID] = dimensions
+	case "data":
+		for _, d := range m.Data {
+			c.points = append(c.points, Point{
+				Time:       time.Unix(0, m.LogicalTimestampMs*int64(time.Millisecond)),
+				Value:      d.Value,
+				TSID:       d.TSID,
+				Dimensions: c.dimensions[d.TSID],
+			})
+		}
+	}
+	return nil
+}
+
+// websocketURL returns the URL of the SignalFlow WebSocket endpoint
+func (i *Importer) websocketURL() string {
+	u := i.options.URL
+	switch {
+	case strings.HasPrefix(u, "https://"):
+		u = "wss://" + strings.TrimPrefix(u, "https://")
+	case strings.HasPrefix(u, "http://"):
+		u = "ws://" + strings.TrimPrefix(u, "http://")
+	}
+	return u + "/v2/signalflow/connect"
+}
+
+// websocket executes the program over a WebSocket connection and returns its points
+func (i *Importer) websocket(e *Execute) ([]Point, error) {
+	deadline := time.Now().Add(i.timeout())
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = i.timeout()
+	conn, resp, err := dialer.Dial(i.websocketURL(), nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("Unable to connect (%s)", resp.Status)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(deadline)
+	conn.SetWriteDeadline(deadline)
+
+	if err := conn.WriteJSON(map[string]string{
+		"type":  "authenticate",
+		"token": i.options.Token,
+	}); err != nil {
+		return nil, err
+	}
+	m, err := read(conn)
+	if err != nil {
+		return nil, err
+	}
+	switch m.Type {
+	case "authenticated":
+	case "error":
+		return nil, fmt.Errorf("Unable to authenticate (%d): %s", m.Error, m.Message)
+	default:
+		return nil, fmt.Errorf("Unexpected message '%s'; expect authenticated", m.Type)
+	}
+
+	e.Type, e.Channel = "execute", channel
+	if err := conn.WriteJSON(e); err != nil {
+		return nil, err
+	}
+	c := newCollector()
+	for !c.done {
+		m, err := read(conn)
+		if err != nil {
+			return nil, err
+		}
+		if m.Channel != "" && m.Channel != channel {
+			continue
+		}
+		if err := c.handle(m); err != nil {
+			return nil, err
+		}
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return c.points, nil
+}
+
+// read reads the next (text or binary) message from the connection
+func read(conn *websocket.Conn) (Message, error) {
+	typ, b, err := conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	if typ == websocket.BinaryMessage {
+		return DecodeBinary(b)
+	}
+	var m Message
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Message{}, fmt.Errorf("Unable to decode message: %s", err)
+	}
+	return m, nil
+}
+
+// Binary message types and flags
+const (
+	binaryData = 5
+
+	flagCompressed = 1 << 0
+	flagJSON       = 1 << 1
+)
+
+// Binary value types
+const (
+	valueLong   = 1
+	valueDouble = 2
+	valueInt    = 3
+)
+
+// DecodeBinary decodes a binary SignalFlow message
+// A 20 byte header (version, type, flags, reserved and a 16 byte channel name) precedes the body
+// The body may be compressed (gzip) and may be JSON; otherwise it's a data message of (type, tsId, value) triples
+// Version 1 data messages have a timestamp and a count; later versions add the max delay after the timestamp
+func DecodeBinary(b []byte) (Message, error) {
+	if len(b) < 20 {
+		return Message{}, errors.New("Binary message is too short")
+	}
+	version, typ, flags := b[0], b[1], b[2]
+	m := Message{
+		Channel: string(bytes.TrimRight(b[4:20], "\x00")),
+	}
+	body := b[20:]
+	if flags&flagCompressed != 0 {
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return Message{}, fmt.Errorf("Unable to decompress message: %s", err)
+		}
+		if body, err = ioutil.ReadAll(r); err != nil {
+			return Message{}, fmt.Errorf("Unable to decompress message: %s", err)
+		}
+	}
+	if flags&flagJSON != 0 {
+		if err := json.Unmarshal(body, &m); err != nil {
+			return Message{}, fmt.Errorf("Unable to decode message: %s", err)
+		}
+		return m, nil
+	}
+	if typ != binaryData {
+		return Message{}, fmt.Errorf("Unsupported binary message type %d", typ)
+	}
+	m.Type = "data"
+	r := bytes.NewReader(body)
+	var header struct {
+		Timestamp int64
+		MaxDelay  int64
+		Count     int32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header.Timestamp); err != nil {
+		return Message{}, fmt.Errorf("Unable to decode data message: %s", err)
+	}
+	if version > 1 {
+		if err := binary.Read(r, binary.BigEndian, &header.MaxDelay); err != nil {
+			return Message{}, fmt.Errorf("Unable to decode data message: %s", err)
+		}
+	}
+	if err := binary.Read(r, binary.BigEndian, &header.Count); err != nil {
+		return Message{}, fmt.Errorf("Unable to decode data message: %s", err)
+	}
+	m.LogicalTimestampMs = header.Timestamp
+	for j := int32(0); j < header.Count; j++ {
+		var d struct {
+			Type  uint8
+			TSID  [8]byte
+			Value [8]byte
+		}
+		if err := binary.Read(r, binary.BigEndian, &d); err != nil {
+			return Message{}, fmt.Errorf("Unable to decode data message: %s", err)
+		}
+		bits := binary.BigEndian.Uint64(d.Value[:])
+		var value float64
+		switch d.Type {
+		case valueLong, valueInt:
+			value = float64(int64(bits))
+		case valueDouble:
+			value = math.Float64frombits(bits)
+		default:
+			return Message{}, fmt.Errorf("Unsupported value type %d", d.Type)
+		}
+		m.Data = append(m.Data, Datum{
+			TSID:  base64.RawURLEncoding.EncodeToString(d.TSID[:]),
+			Value: value,
+		})
+	}
+	return m, nil
+}
+
+// rest executes the program using the execute endpoint and returns its points
+// The response is a stream of Server-Sent Events whose names are the messages' types
+func (i *Importer) rest(e *Execute) ([]Point, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(e.Start, 10))
+	params.Set("stop", strconv.FormatInt(e.Stop, 10))
+	if e.Resolution > 0 {
+		params.Set("resolution", strconv.FormatInt(e.Resolution, 10))
+	}
+	params.Set("immediate", strconv.FormatBool(e.Immediate))
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, i.options.URL+"/v2/signalflow/execute?"+params.Encode(), strings.NewReader(e.Program))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-SF-Token", i.options.Token)
+	client := i.options.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e); err != nil || e.Message == "" {
+			return nil, fmt.Errorf("Execution failed (%s)", resp.Status)
+		}
+		return nil, fmt.Errorf("Execution failed (%s): %s", resp.Status, e.Message)
+	}
+	c := newCollector()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1<<16), 1<<24)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				var m Message
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &m); err != nil {
+					return nil, fmt.Errorf("Unable to decode message: %s", err)
+				}
+				if m.Type == "" {
+					m.Type = event
+				}
+				if err := c.handle(m); err != nil {
+					return nil, err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if c.done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !c.done {
+		return nil, errors.New("Execution ended before the end of the channel")
+	}
+	return c.points, nil
+}
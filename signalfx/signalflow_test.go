@@ -0,0 +1,117 @@
+package signalfx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// header returns a binary message header
+func header(version, typ, flags byte, channel string) []byte {
+	h := make([]byte, 20)
+	h[0], h[1], h[2] = version, typ, flags
+	copy(h[4:], channel)
+	return h
+}
+func Test_DecodeBinary(t *testing.T) {
+	tsid := [8]byte{0, 0, 0, 0, 0, 0, 0, 1}
+	t.Run("Version 1", func(t *testing.T) {
+		var b bytes.Buffer
+		b.Write(header(1, binaryData, 0, "ch-1"))
+		binary.Write(&b, binary.BigEndian, int64(1792411200000))
+		binary.Write(&b, binary.BigEndian, int32(2))
+		b.WriteByte(valueLong)
+		b.Write(tsid[:])
+		binary.Write(&b, binary.BigEndian, int64(-42))
+		b.WriteByte(valueDouble)
+		b.Write(tsid[:])
+		binary.Write(&b, binary.BigEndian, math.Float64bits(0.5))
+		m, err := DecodeBinary(b.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Channel, "ch-1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.LogicalTimestampMs, int64(1792411200000); got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := len(m.Data), 2; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := m.Data[0].Value, -42.0; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := m.Data[1].Value, 0.5; got != want {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := m.Data[0].TSID, base64.RawURLEncoding.EncodeToString(tsid[:]); got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Compressed JSON", func(t *testing.T) {
+		var body bytes.Buffer
+		w := gzip.NewWriter(&body)
+		w.Write([]byte(`{"type":"control-message","event":"END_OF_CHANNEL"}`))
+		w.Close()
+		m, err := DecodeBinary(append(header(1, 10, flagCompressed|flagJSON, "ch-1"), body.Bytes()...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Event, "END_OF_CHANNEL"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := m.Channel, "ch-1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name string
+		b    []byte
+	}{
+		{"Short", []byte{1, 5}},
+		{"Unsupported Type", header(1, 10, 0, "ch-1")},
+		{"Truncated", append(header(2, binaryData, 0, "ch-1"), 0, 0, 0, 0)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeBinary(test.b); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func Test_collector(t *testing.T) {
+	c := newCollector()
+	for _, m := range []Message{
+		{Type: "control-message", Event: "STREAM_START"},
+		{Type: "metadata", TSID: "A", Properties: map[string]interface{}{"sf_metric": "counter0", "key1": "value1", "sf_isPreQuantized": true}},
+		{Type: "data", LogicalTimestampMs: 1000, Data: []Datum{{TSID: "A", Value: 1}}},
+	} {
+		if err := c.handle(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.done {
+		t.Errorf("got done; want not done")
+	}
+	if got, want := len(c.points), 1; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	if got, want := len(c.points[0].Dimensions), 1; got != want {
+		t.Errorf("got %d; want %d", got, want)
+	}
+	abort := Message{Type: "control-message", Event: "CHANNEL_ABORT"}
+	abort.AbortInfo.Reason = "Job exceeded limits"
+	if err := c.handle(abort); err == nil {
+		t.Errorf("got nil; want error")
+	}
+	if err := c.handle(Message{Type: "control-message", Event: "END_OF_CHANNEL"}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.done {
+		t.Errorf("got not done; want done")
+	}
+}
@@ -0,0 +1,220 @@
+package signalfx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Transport determines how SignalFlow programs are executed
+type Transport string
+
+// Transports
+const (
+	// WebSocket executes programs over a SignalFlow WebSocket connection (the default)
+	WebSocket Transport = "websocket"
+	// REST executes programs using the execute endpoint, which streams Server-Sent Events
+	REST Transport = "rest"
+)
+
+const (
+	defaultRealm   = "us0"
+	defaultWindow  = 5 * time.Minute
+	defaultTimeout = 30 * time.Second
+)
+
+// rollups are the SignalFlow rollups that summarize a time series' points within the resolution
+var rollups = map[string]bool{
+	"average": true, "count": true, "delta": true, "lag": true, "latest": true, "max": true, "min": true, "rate": true, "sum": true,
+}
+
+// aggregates are the SignalFlow stream methods that combine time series e.g. those from different hosts
+var aggregates = map[string]bool{
+	"count": true, "max": true, "mean": true, "min": true, "sum": true,
+}
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements by executing SignalFlow programs against SignalFx (Splunk Observability Cloud)
+// A View is a metric and its label names are dimensions
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+// The realm and token default to the values of environment variables SIGNALFX_REALM and SIGNALFX_TOKEN
+func NewImporter(o Options) (*Importer, error) {
+	if o.Token == "" {
+		o.Token = os.Getenv("SIGNALFX_TOKEN")
+	}
+	if o.Token == "" {
+		return nil, errors.New("Expect a SignalFx access token")
+	}
+	if o.Realm == "" {
+		o.Realm = os.Getenv("SIGNALFX_REALM")
+	}
+	if o.Realm == "" {
+		o.Realm = defaultRealm
+	}
+	if o.URL == "" {
+		o.URL = fmt.Sprintf("https://stream.%s.signalfx.com", o.Realm)
+	}
+	o.URL = strings.TrimSuffix(o.URL, "/")
+	switch o.Transport {
+	case "":
+		o.Transport = WebSocket
+	case WebSocket, REST:
+	default:
+		return nil, fmt.Errorf("Unknown Transport '%s'", o.Transport)
+	}
+	if o.Rollup != "" && !rollups[o.Rollup] {
+		return nil, fmt.Errorf("Unknown Rollup '%s'", o.Rollup)
+	}
+	if o.Aggregate != "" && !aggregates[o.Aggregate] {
+		return nil, fmt.Errorf("Unknown Aggregate '%s'", o.Aggregate)
+	}
+	if o.Resolution < 0 || (o.Resolution > 0 && o.Resolution < time.Second) {
+		return nil, errors.New("Resolution must be at least 1 second")
+	}
+	return &Importer{
+		name:    "signalfx",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	points, err := i.execute(v, labelValues, t)
+	if err != nil {
+		return Point{}, err
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No datapoints match the program")
+	}
+	return points[j], nil
+}
+
+// Series returns the Points (oldest first) in the window ending at the time specified for the View with the label values
+// When an Aggregate isn't used, the points of every time series that matches are returned
+func (i *Importer) Series(v *view.View, labelValues []string, t time.Time) ([]Point, error) {
+	points, err := i.execute(v, labelValues, t)
+	if err != nil {
+		return nil, err
+	}
+	sortPoints(points)
+	return points, nil
+}
+
+// Program returns the SignalFlow program for the View with the label values
+// e.g. data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2')).publish()
+// Label values can't include "*"; it's a wildcard in SignalFlow filters and can't be escaped
+func (i *Importer) Program(v *view.View, labelValues []string) (string, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return "", errors.New("Inconsistency between labels and values")
+	}
+	p := NewProgram(i.options.MetricPrefix + v.Name)
+	for j, labelName := range v.LabelNames {
+		if strings.Contains(labelValues[j], "*") {
+			return "", fmt.Errorf("Label value '%s' includes '*' which would match as a wildcard", labelValues[j])
+		}
+		p.AddFilter(labelName, labelValues[j])
+	}
+	// The exporter adds its default dimensions to every datapoint
+	keys := make([]string, 0, len(i.options.Dimensions))
+	for k := range i.options.Dimensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p.AddFilter(k, i.options.Dimensions[k])
+	}
+	p.Rollup = i.options.Rollup
+	p.Aggregate = i.options.Aggregate
+	return p.String(), nil
+}
+
+// execute executes the View's program over the window ending at the time specified and returns its points
+func (i *Importer) execute(v *view.View, labelValues []string, t time.Time) ([]Point, error) {
+	program, err := i.Program(v, labelValues)
+	if err != nil {
+		return nil, err
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+	e := &Execute{
+		Program:    program,
+		Start:      millis(t.Add(-window)),
+		Stop:       millis(t),
+		Resolution: int64(i.options.Resolution / time.Millisecond),
+		Immediate:  true,
+	}
+	if i.options.Transport == REST {
+		return i.rest(e)
+	}
+	return i.websocket(e)
+}
+
+// timeout returns the time allowed for an execution
+func (i *Importer) timeout() time.Duration {
+	if i.options.Timeout > 0 {
+		return i.options.Timeout
+	}
+	return defaultTimeout
+}
+
+// millis returns the time as milliseconds since the epoch
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// Token is an access token with the API scope; defaults to environment variable SIGNALFX_TOKEN
+	Token string
+	// Realm (e.g. us1) determines the stream URL; defaults to environment variable SIGNALFX_REALM or us0
+	Realm string
+	// URL overrides the stream URL (https://stream.[realm].signalfx.com)
+	URL string
+	// Transport executes programs over a WebSocket (the default) or using the REST execute endpoint
+	Transport Transport
+	// MetricPrefix is prepended to the View's name to give the metric
+	MetricPrefix string
+	// Dimensions are dimensions that the exporter adds to every datapoint; unlike label values, they may include wildcards (*)
+	Dimensions map[string]string
+	// Rollup (e.g. latest or sum) summarizes each time series' points within the resolution; defaults to the metric type's
+	Rollup string
+	// Aggregate (e.g. sum) combines the time series that match the View's dimensions
+	Aggregate string
+	// Resolution is the (minimum) interval between the points returned; defaults to that chosen by SignalFlow
+	Resolution time.Duration
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 5 minutes
+	Window time.Duration
+	// Timeout is the time allowed for an execution; defaults to 30 seconds
+	Timeout time.Duration
+	// HTTPClient overrides the HTTP client used by the REST Transport
+	HTTPClient *http.Client
+}
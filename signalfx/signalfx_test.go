@@ -0,0 +1,281 @@
+package signalfx
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/signalfx/signalfxtest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+const token = "token"
+
+// newTestImporter creates an Importer that talks to a signalfxtest.Server
+func newTestImporter(t *testing.T, s *signalfxtest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.Token = token
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	os.Unsetenv("SIGNALFX_TOKEN")
+	os.Unsetenv("SIGNALFX_REALM")
+	t.Run("Defaults", func(t *testing.T) {
+		i, err := NewImporter(Options{Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "signalfx"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.URL, "https://stream.us0.signalfx.com"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.websocketURL(), "wss://stream.us0.signalfx.com/v2/signalflow/connect"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Transport, WebSocket; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("SIGNALFX_TOKEN", token)
+		os.Setenv("SIGNALFX_REALM", "eu0")
+		defer os.Unsetenv("SIGNALFX_TOKEN")
+		defer os.Unsetenv("SIGNALFX_REALM")
+		i, err := NewImporter(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.options.URL, "https://stream.eu0.signalfx.com"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name    string
+		options Options
+	}{
+		{"No Token", Options{}},
+		{"Unknown Transport", Options{Token: token, Transport: "grpc"}},
+		{"Unknown Rollup", Options{Token: token, Rollup: "median"}},
+		{"Unknown Aggregate", Options{Token: token, Aggregate: "median"}},
+		{"Resolution", Options{Token: token, Resolution: time.Millisecond}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewImporter(test.options); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func TestImporter_Program(t *testing.T) {
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"Default", Options{}, `data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2')).publish()`},
+		{"Dimensions", Options{Dimensions: map[string]string{"host": "a"}}, `data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2') and filter('host', 'a')).publish()`},
+		{"Rollup Aggregate", Options{MetricPrefix: "app.", Rollup: "latest", Aggregate: "sum"}, `data('app.counter0', filter=filter('key1', 'value1') and filter('key2', 'value2'), rollup='latest').sum().publish()`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.options.Token = token
+			i, err := NewImporter(test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := i.Program(v, []string{"value1", "value2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+	t.Run("Wildcard", func(t *testing.T) {
+		i, err := NewImporter(Options{Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Program(v, []string{"value*", "value2"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := signalfxtest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	execution := func(program string) signalfxtest.Execution {
+		return signalfxtest.Execution{
+			Program: program,
+			Start:   now.Add(-5 * time.Minute),
+			Stop:    now,
+		}
+	}
+	dimensions := func(host string) map[string]string {
+		return map[string]string{"key1": "value1", "key2": "value2", "host": host}
+	}
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, transport := range []Transport{WebSocket, REST} {
+		t.Run(string(transport), func(t *testing.T) {
+			for _, test := range []struct {
+				name      string
+				options   Options
+				execution signalfxtest.Execution
+				series    []signalfxtest.Series
+				want      Point
+			}{
+				{
+					"Newest",
+					Options{},
+					execution(`data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2')).publish()`),
+					[]signalfxtest.Series{
+						{Metric: "counter0", Dimensions: dimensions("a"), Points: []signalfxtest.Point{
+							{Time: now.Add(-30 * time.Second), Value: 2},
+							{Time: now.Add(-20 * time.Second), Value: 4},
+						}},
+						{Metric: "counter0", Dimensions: dimensions("b"), Points: []signalfxtest.Point{
+							{Time: now.Add(-30 * time.Second), Value: 8},
+						}},
+					},
+					Point{Time: now.Add(-20 * time.Second), Value: 4, Dimensions: dimensions("a")},
+				},
+				{
+					"Dimensions",
+					Options{Dimensions: map[string]string{"host": "b"}},
+					execution(`data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2') and filter('host', 'b')).publish()`),
+					[]signalfxtest.Series{
+						{Metric: "counter0", Dimensions: dimensions("b"), Points: []signalfxtest.Point{
+							{Time: now.Add(-30 * time.Second), Value: 8},
+						}},
+					},
+					Point{Time: now.Add(-30 * time.Second), Value: 8, Dimensions: dimensions("b")},
+				},
+				{
+					"Aggregate",
+					Options{Aggregate: "sum"},
+					execution(`data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2')).sum().publish()`),
+					[]signalfxtest.Series{
+						{Metric: "counter0", Points: []signalfxtest.Point{
+							{Time: now.Add(-20 * time.Second), Value: 12},
+						}},
+					},
+					Point{Time: now.Add(-20 * time.Second), Value: 12},
+				},
+				{
+					"Rollup",
+					Options{MetricPrefix: "prefix.", Rollup: "sum", Resolution: time.Minute, Window: time.Minute},
+					signalfxtest.Execution{
+						Program:    `data('prefix.counter0', filter=filter('key1', 'value1') and filter('key2', 'value2'), rollup='sum').publish()`,
+						Start:      now.Add(-time.Minute),
+						Stop:       now,
+						Resolution: time.Minute,
+					},
+					[]signalfxtest.Series{
+						{Metric: "prefix.counter0", Dimensions: dimensions("a"), Points: []signalfxtest.Point{
+							{Time: now.Add(-time.Minute), Value: 6},
+						}},
+					},
+					Point{Time: now.Add(-time.Minute), Value: 6, Dimensions: dimensions("a")},
+				},
+			} {
+				t.Run(test.name, func(t *testing.T) {
+					s.SetResult(test.execution, test.series...)
+					test.options.Transport = transport
+					i := newTestImporter(t, s, test.options)
+					got, err := i.Point(v, []string{"value1", "value2"}, now)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !got.Time.Equal(test.want.Time) || got.Value != test.want.Value || got.Dimensions["host"] != test.want.Dimensions["host"] || len(got.Dimensions) != len(test.want.Dimensions) {
+						t.Errorf("got %v; want %v", got, test.want)
+					}
+					queries := s.Queries()
+					if got, want := queries[len(queries)-1], test.execution.String(); got != want {
+						t.Errorf("got %s; want %s", got, want)
+					}
+				})
+			}
+			t.Run("Series", func(t *testing.T) {
+				e := execution(`data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value2') and filter('host', 'a')).publish()`)
+				s.SetResult(e, signalfxtest.Series{Metric: "counter0", Dimensions: dimensions("a"), Points: []signalfxtest.Point{
+					{Time: now.Add(-30 * time.Second), Value: 2},
+					{Time: now.Add(-90 * time.Second), Value: 1},
+					{Time: now.Add(-20 * time.Second), Value: 4},
+				}})
+				i := newTestImporter(t, s, Options{Transport: transport, Dimensions: map[string]string{"host": "a"}})
+				points, err := i.Series(v, []string{"value1", "value2"}, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := len(points), 3; got != want {
+					t.Fatalf("got %d; want %d", got, want)
+				}
+				for j, want := range []float64{1, 2, 4} {
+					if got := points[j].Value; got != want {
+						t.Errorf("got %v; want %v", got, want)
+					}
+				}
+				if want := now.Add(-20 * time.Second); !points[2].Time.Equal(want) {
+					t.Errorf("got %s; want %s", points[2].Time, want)
+				}
+				if got, want := points[2].Dimensions["host"], "a"; got != want {
+					t.Errorf("got %s; want %s", got, want)
+				}
+			})
+			s.SetResult(execution(`data('counter0', filter=filter('key1', 'value1') and filter('key2', 'value9')).publish()`))
+			for _, test := range []struct {
+				name        string
+				options     Options
+				labelValues []string
+			}{
+				{"No Datapoints", Options{}, []string{"value1", "value9"}},
+				{"Unexpected Program", Options{MetricPrefix: "unknown."}, []string{"value1", "value2"}},
+				{"Label Mismatch", Options{}, []string{"value1"}},
+			} {
+				t.Run(test.name, func(t *testing.T) {
+					test.options.Transport = transport
+					i := newTestImporter(t, s, test.options)
+					if _, err := i.Value(v, test.labelValues, now); err == nil {
+						t.Errorf("got nil; want error")
+					}
+				})
+			}
+			t.Run("Server Error", func(t *testing.T) {
+				s.SetStatus(http.StatusServiceUnavailable)
+				defer s.SetStatus(http.StatusOK)
+				i := newTestImporter(t, s, Options{Transport: transport})
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+					t.Errorf("got nil; want error")
+				}
+			})
+			t.Run("Token", func(t *testing.T) {
+				i := newTestImporter(t, s, Options{Transport: transport})
+				if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+					t.Fatal(err)
+				}
+				headers := s.Headers()
+				if got, want := headers[len(headers)-1].Get("X-SF-Token"), token; got != want {
+					t.Errorf("got %s; want %s", got, want)
+				}
+			})
+		})
+	}
+}
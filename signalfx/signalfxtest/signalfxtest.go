@@ -0,0 +1,262 @@
+package signalfxtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+	"github.com/gorilla/websocket"
+)
+
+// Server is a stand-in for SignalFx's SignalFlow API (over a WebSocket and the REST execute endpoint)
+// It returns the time series seeded for each exact Execution: the program and its start, stop and resolution
+// A WebSocket's executions are made against the execute endpoint with its token so both transports are recorded alike
+type Server struct {
+	*standin.Server
+	upgrader websocket.Upgrader
+}
+
+// NewServer creates and starts a new Server with no time series
+func NewServer() *Server {
+	s := &Server{Server: standin.NewServer(execution)}
+	s.Handle("/v2/signalflow/connect", http.HandlerFunc(s.connect))
+	return s
+}
+
+// Execution represents a request to execute a program over the window between Start and Stop
+// A Resolution of 0 lets SignalFlow choose
+type Execution struct {
+	Program    string
+	Start      time.Time
+	Stop       time.Time
+	Resolution time.Duration
+}
+
+// String returns the Execution's text e.g. data('counter0').publish() start=1546397940000 stop=1546398240000 resolution=0
+func (e Execution) String() string {
+	return fmt.Sprintf("%s start=%d stop=%d resolution=%d",
+		e.Program,
+		e.Start.UnixNano()/int64(time.Millisecond),
+		e.Stop.UnixNano()/int64(time.Millisecond),
+		e.Resolution/time.Millisecond,
+	)
+}
+
+// execution returns the text of a request to the execute endpoint: the program (its body) and its times
+func execution(r *http.Request) (string, error) {
+	if r.URL.Path != "/v2/signalflow/execute" {
+		return "", fmt.Errorf("Path '%s' not found", r.URL.Path)
+	}
+	if r.Method != http.MethodPost {
+		return "", fmt.Errorf("Method '%s' not allowed", r.Method)
+	}
+	params := r.URL.Query()
+	resolution := params.Get("resolution")
+	if resolution == "" {
+		resolution = "0"
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", errors.New("Missing program")
+	}
+	return fmt.Sprintf("%s start=%s stop=%s resolution=%s",
+		b,
+		params.Get("start"),
+		params.Get("stop"),
+		resolution,
+	), nil
+}
+
+// Point represents a value at a (logical) time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series represents a time series of an Execution's result
+// Dimensions are empty when an aggregate combines time series
+type Series struct {
+	Metric     string
+	Dimensions map[string]string
+	Points     []Point
+}
+
+// tsid returns the (base64) ID of the jth time series as it appears in messages
+func tsid(j int) string {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(j+1))
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+// datum represents a time series' value in a data message
+type datum struct {
+	TSID  string  `json:"tsId"`
+	Value float64 `json:"value"`
+}
+
+// data represents a data message
+type data struct {
+	LogicalTimestampMs int64   `json:"logicalTimestampMs"`
+	Data               []datum `json:"data"`
+}
+
+// SetResult seeds the time series that are returned for the Execution
+// They're sent as the execute endpoint sends them: a metadata message for each time series,
+// a data message for each logical time (oldest first) and then the end of the channel, as Server-Sent Events
+func (s *Server) SetResult(e Execution, series ...Series) {
+	var b bytes.Buffer
+	event := func(typ string, message interface{}) {
+		m, _ := json.Marshal(message)
+		fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", typ, m)
+	}
+	values := map[int64][]datum{}
+	for j, ts := range series {
+		properties := map[string]interface{}{"sf_metric": ts.Metric}
+		for k, v := range ts.Dimensions {
+			properties[k] = v
+		}
+		event("metadata", map[string]interface{}{"type": "metadata", "tsId": tsid(j), "properties": properties})
+		for _, p := range ts.Points {
+			ms := p.Time.UnixNano() / int64(time.Millisecond)
+			values[ms] = append(values[ms], datum{tsid(j), p.Value})
+		}
+	}
+	timestamps := make([]int64, 0, len(values))
+	for ms := range values {
+		timestamps = append(timestamps, ms)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	for _, ms := range timestamps {
+		event("data", data{ms, values[ms]})
+	}
+	event("control-message", map[string]interface{}{"type": "control-message", "event": "END_OF_CHANNEL"})
+	s.SetResponse(e.String(), "text/event-stream", b.String())
+}
+
+// request represents a WebSocket request
+type request struct {
+	Type       string `json:"type"`
+	Token      string `json:"token"`
+	Channel    string `json:"channel"`
+	Program    string `json:"program"`
+	Start      int64  `json:"start"`
+	Stop       int64  `json:"stop"`
+	Resolution int64  `json:"resolution"`
+}
+
+// connect serves a SignalFlow WebSocket connection: authenticate, then execute programs
+// Data messages are binary (version 2) as they are from SignalFx; other messages are JSON
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	token := ""
+	for {
+		var req request
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		switch req.Type {
+		case "authenticate":
+			token = req.Token
+			conn.WriteJSON(map[string]interface{}{"type": "authenticated", "orgId": "org", "userId": "user"})
+		case "execute":
+			if err := s.execute(conn, token, req); err != nil {
+				conn.WriteJSON(map[string]interface{}{"type": "error", "channel": req.Channel, "error": http.StatusBadRequest, "message": err.Error()})
+			}
+		default:
+			conn.WriteJSON(map[string]interface{}{"type": "error", "channel": req.Channel, "error": http.StatusBadRequest, "message": fmt.Sprintf("Unknown request type '%s'", req.Type)})
+		}
+	}
+}
+
+// execute makes the WebSocket request's execution against the execute endpoint and sends its messages on the request's channel
+func (s *Server) execute(conn *websocket.Conn, token string, req request) error {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(req.Start, 10))
+	params.Set("stop", strconv.FormatInt(req.Stop, 10))
+	if req.Resolution > 0 {
+		params.Set("resolution", strconv.FormatInt(req.Resolution, 10))
+	}
+	r, err := http.NewRequest(http.MethodPost, s.URL()+"/v2/signalflow/execute?"+params.Encode(), strings.NewReader(req.Program))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "text/plain")
+	r.Header.Set("X-SF-Token", token)
+	resp, err := s.Client().Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "data":
+			var d data
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &d); err != nil {
+				return err
+			}
+			b, err := d.binary(req.Channel)
+			if err != nil {
+				return err
+			}
+			conn.WriteMessage(websocket.BinaryMessage, b)
+		case strings.HasPrefix(line, "data: "):
+			m := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m); err != nil {
+				return err
+			}
+			m["channel"] = req.Channel
+			conn.WriteJSON(m)
+		}
+	}
+	return scanner.Err()
+}
+
+// binary encodes the data message in SignalFlow's (version 2) binary format
+func (d data) binary(channel string) ([]byte, error) {
+	var b bytes.Buffer
+	header := [20]byte{2, 5, 0, 0}
+	copy(header[4:], channel)
+	b.Write(header[:])
+	binary.Write(&b, binary.BigEndian, d.LogicalTimestampMs)
+	binary.Write(&b, binary.BigEndian, int64(0))
+	binary.Write(&b, binary.BigEndian, int32(len(d.Data)))
+	for _, v := range d.Data {
+		id, err := base64.RawURLEncoding.DecodeString(v.TSID)
+		if err != nil || len(id) != 8 {
+			return nil, fmt.Errorf("Invalid tsId '%s'", v.TSID)
+		}
+		b.WriteByte(2)
+		b.Write(id)
+		binary.Write(&b, binary.BigEndian, math.Float64bits(v.Value))
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package signalfxtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServer_SetResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	e := Execution{
+		Program: "data('counter0').publish()",
+		Start:   now.Add(-time.Minute),
+		Stop:    now,
+	}
+	s.SetResult(e, Series{
+		Metric:     "counter0",
+		Dimensions: map[string]string{"host": "a"},
+		Points:     []Point{{now, 1.5}},
+	})
+	t.Run("REST", func(t *testing.T) {
+		for _, test := range []struct {
+			name    string
+			program string
+			code    int
+			want    string
+		}{
+			{"Result", "data('counter0').publish()", http.StatusOK, "event: metadata\n" +
+				`data: {"properties":{"host":"a","sf_metric":"counter0"},"tsId":"AAAAAAAAAAE","type":"metadata"}` + "\n\n" +
+				"event: data\n" +
+				`data: {"logicalTimestampMs":1546398240000,"data":[{"tsId":"AAAAAAAAAAE","value":1.5}]}` + "\n\n" +
+				"event: control-message\n" +
+				`data: {"event":"END_OF_CHANNEL","type":"control-message"}` + "\n\n"},
+			{"Unexpected Program", "data('counter1').publish()", http.StatusBadRequest, ""},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				resp, err := s.Client().Post(s.URL()+"/v2/signalflow/execute?start=1546398180000&stop=1546398240000&immediate=true", "text/plain", strings.NewReader(test.program))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				b, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := resp.StatusCode; got != test.code {
+					t.Errorf("got %d; want %d", got, test.code)
+				}
+				if got := string(b); test.want != "" && got != test.want {
+					t.Errorf("got %s; want %s", got, test.want)
+				}
+			})
+		}
+	})
+	t.Run("WebSocket", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL(), "http")+"/v2/signalflow/connect", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		for _, req := range []map[string]interface{}{
+			{"type": "authenticate", "token": "token"},
+			{"type": "execute", "channel": "ch-1", "program": "data('counter0').publish()", "start": 1546398180000, "stop": 1546398240000},
+		} {
+			if err := conn.WriteJSON(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, want := range []struct {
+			typ  int
+			text string
+		}{
+			{websocket.TextMessage, `"authenticated"`},
+			{websocket.TextMessage, `"metadata"`},
+			{websocket.BinaryMessage, "ch-1"},
+			{websocket.TextMessage, `"END_OF_CHANNEL"`},
+		} {
+			typ, b, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != want.typ || !strings.Contains(string(b), want.text) {
+				t.Errorf("got %d %q; want %d including %s", typ, b, want.typ, want.text)
+			}
+		}
+	})
+	want := e.String() + "\n" + "data('counter1').publish() start=1546398180000 stop=1546398240000 resolution=0\n" + e.String()
+	if got := strings.Join(s.Queries(), "\n"); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	headers := s.Headers()
+	if got, want := headers[len(headers)-1].Get("X-SF-Token"), "token"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package stackdriver
+
+import (
+	"errors"
+	"time"
+
+	durationpb "github.com/golang/protobuf/ptypes/duration"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+// aggregation returns the Aggregation for ListTimeSeries requests or nil if none has been configured
+func (i *Importer) aggregation() (*monitoringpb.Aggregation, error) {
+	o := i.options
+	if o.AlignmentPeriod == 0 && o.Aligner == monitoringpb.Aggregation_ALIGN_NONE && o.Reducer == monitoringpb.Aggregation_REDUCE_NONE && len(o.GroupByFields) == 0 {
+		return nil, nil
+	}
+	if o.Aligner != monitoringpb.Aggregation_ALIGN_NONE && o.AlignmentPeriod < time.Second {
+		return nil, errors.New("An Aligner requires an AlignmentPeriod of at least 1s")
+	}
+	if o.Reducer != monitoringpb.Aggregation_REDUCE_NONE && o.Aligner == monitoringpb.Aggregation_ALIGN_NONE {
+		return nil, errors.New("A Reducer requires an Aligner")
+	}
+	if len(o.GroupByFields) > 0 && o.Reducer == monitoringpb.Aggregation_REDUCE_NONE {
+		return nil, errors.New("GroupByFields require a Reducer")
+	}
+	a := &monitoringpb.Aggregation{
+		PerSeriesAligner:   o.Aligner,
+		CrossSeriesReducer: o.Reducer,
+		GroupByFields:      o.GroupByFields,
+	}
+	if o.AlignmentPeriod != 0 {
+		a.AlignmentPeriod = &durationpb.Duration{
+			Seconds: int64(o.AlignmentPeriod / time.Second),
+		}
+	}
+	return a, nil
+}
+
+// window returns the duration of the interval that's read
+// This is at least 1 minute and must include at least one alignment period
+func (i *Importer) window() time.Duration {
+	window := time.Minute
+	if i.options.AlignmentPeriod > window {
+		window = i.options.AlignmentPeriod
+	}
+	return window
+}
@@ -0,0 +1,72 @@
+package stackdriver
+
+import (
+	"testing"
+	"time"
+
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func TestImporter_aggregation(t *testing.T) {
+	t.Run("None", func(t *testing.T) {
+		i, _ := NewImporter(Options{})
+		a, err := i.aggregation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != nil {
+			t.Errorf("got %v; want nil", a)
+		}
+	})
+	t.Run("Aligner and Reducer", func(t *testing.T) {
+		i, _ := NewImporter(Options{
+			AlignmentPeriod: 2 * time.Minute,
+			Aligner:         monitoringpb.Aggregation_ALIGN_DELTA,
+			Reducer:         monitoringpb.Aggregation_REDUCE_SUM,
+			GroupByFields:   []string{"metric.label.key1"},
+		})
+		a, err := i.aggregation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := a.GetAlignmentPeriod().GetSeconds(), int64(120); got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := a.GetPerSeriesAligner(), monitoringpb.Aggregation_ALIGN_DELTA; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := a.GetCrossSeriesReducer(), monitoringpb.Aggregation_REDUCE_SUM; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := len(a.GetGroupByFields()), 1; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, o := range []Options{
+			{Aligner: monitoringpb.Aggregation_ALIGN_RATE},
+			{AlignmentPeriod: time.Minute, Reducer: monitoringpb.Aggregation_REDUCE_MEAN},
+			{AlignmentPeriod: time.Minute, Aligner: monitoringpb.Aggregation_ALIGN_MEAN, GroupByFields: []string{"metric.label.key1"}},
+		} {
+			i, _ := NewImporter(o)
+			if _, err := i.aggregation(); err == nil {
+				t.Errorf("%v got nil; want error", o)
+			}
+		}
+	})
+}
+func TestImporter_window(t *testing.T) {
+	for _, test := range []struct {
+		period time.Duration
+		want   time.Duration
+	}{
+		{0, time.Minute},
+		{30 * time.Second, time.Minute},
+		{5 * time.Minute, 5 * time.Minute},
+	} {
+		i, _ := NewImporter(Options{AlignmentPeriod: test.period})
+		if got := i.window(); got != test.want {
+			t.Errorf("got %v; want %v", got, test.want)
+		}
+	}
+}
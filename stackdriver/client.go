@@ -0,0 +1,78 @@
+package stackdriver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3"
+	monitoringv2 "cloud.google.com/go/monitoring/apiv3/v2"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// monitoringReadScope is the OAuth scope needed to read from Cloud Monitoring
+const monitoringReadScope = "https://www.googleapis.com/auth/monitoring.read"
+
+var errProjectID = errors.New("Google Cloud Project ID is required; specify using Options.ProjectID or environment variable 'PROJECT'")
+
+// metricClient returns the Importer's Cloud Monitoring client, creating it the first time it's needed
+// Unless MonitoringClientOptions says otherwise, Application Default Credentials are used
+// Commonly credentials are provided using environment variable GOOGLE_APPLICATION_CREDENTIALS
+func (i *Importer) metricClient() (*monitoring.MetricClient, error) {
+	if i.projectID == "" {
+		return nil, errProjectID
+	}
+	i.clientOnce.Do(func() {
+		i.client, i.clientErr = monitoring.NewMetricClient(context.Background(), i.options.MonitoringClientOptions...)
+	})
+	return i.client, i.clientErr
+}
+
+// queryClient returns the Importer's Cloud Monitoring client for MQL queries, creating it the first time it's needed
+func (i *Importer) queryClient() (*monitoringv2.QueryClient, error) {
+	if i.projectID == "" {
+		return nil, errProjectID
+	}
+	i.querierOnce.Do(func() {
+		i.querier, i.querierErr = monitoringv2.NewQueryClient(context.Background(), i.options.MonitoringClientOptions...)
+	})
+	return i.querier, i.querierErr
+}
+
+// prometheusClient returns the HTTP client for PromQL queries, creating it the first time it's needed
+func (i *Importer) prometheusClient() (*http.Client, error) {
+	if i.options.HTTPClient != nil {
+		return i.options.HTTPClient, nil
+	}
+	if i.projectID == "" {
+		return nil, errProjectID
+	}
+	i.prometheusOnce.Do(func() {
+		o := append([]option.ClientOption{option.WithScopes(monitoringReadScope)}, i.options.MonitoringClientOptions...)
+		i.prometheus, _, i.prometheusErr = htransport.NewClient(context.Background(), o...)
+	})
+	return i.prometheus, i.prometheusErr
+}
+
+// Close closes the Importer's connections to Cloud Monitoring
+func (i *Importer) Close() error {
+	// Ensure clients aren't created after the Importer is closed
+	closed := errors.New("Importer is closed")
+	i.clientOnce.Do(func() {
+		i.clientErr = closed
+	})
+	i.querierOnce.Do(func() {
+		i.querierErr = closed
+	})
+	var err error
+	if i.client != nil {
+		err = i.client.Close()
+	}
+	if i.querier != nil {
+		if qerr := i.querier.Close(); err == nil {
+			err = qerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package stackdriver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	"google.golang.org/api/iterator"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Descriptor returns the MetricDescriptor for the View's metric type from the first of the Importer's projects that has one
+// Descriptors are cached once found
+func (i *Importer) Descriptor(ctx context.Context, v *view.View) (*metricpb.MetricDescriptor, error) {
+	metricType := i.metricType(v)
+	for _, project := range i.projects {
+		d, err := i.descriptor(ctx, project, metricType)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("No metric descriptor for '%s'; check the View's name and the MetricPrefix", metricType)
+}
+
+// descriptor returns the project's MetricDescriptor for the metric type
+func (i *Importer) descriptor(ctx context.Context, project, metricType string) (*metricpb.MetricDescriptor, error) {
+	name := fmt.Sprintf("projects/%s/metricDescriptors/%s", project, metricType)
+
+	i.mu.Lock()
+	d, ok := i.descriptors[name]
+	i.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	client, err := i.metricClient()
+	if err != nil {
+		return nil, err
+	}
+	d, err = client.GetMetricDescriptor(ctx, &monitoringpb.GetMetricDescriptorRequest{
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	i.descriptors[name] = d
+	i.mu.Unlock()
+	return d, nil
+}
+
+// Descriptors returns the MetricDescriptors of every OpenCensus metric (custom.googleapis.com/opencensus/...) in each of the Importer's projects
+// A metric type has a descriptor in each project that has it; the descriptor's Name identifies its project
+func (i *Importer) Descriptors(ctx context.Context) ([]*metricpb.MetricDescriptor, error) {
+	e := Comparison{
+		Selector: Selector{Object: MetricType},
+		Operator: Equal,
+		Value:    StartsWith(defaultDomain + "/"),
+	}
+	client, err := i.metricClient()
+	if err != nil {
+		return nil, err
+	}
+	descriptors := []*metricpb.MetricDescriptor{}
+	for _, project := range i.projects {
+		it := client.ListMetricDescriptors(ctx, &monitoringpb.ListMetricDescriptorsRequest{
+			Name:   fmt.Sprintf("projects/%s", project),
+			Filter: e.String(),
+		})
+		for {
+			d, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			descriptors = append(descriptors, d)
+		}
+	}
+	return descriptors, nil
+}
+
+// ValidateView checks that the View (and the Importer's Options) are consistent with the View's MetricDescriptor
+func (i *Importer) ValidateView(ctx context.Context, v *view.View) error {
+	d, err := i.Descriptor(ctx, v)
+	if err != nil {
+		return err
+	}
+	return validateDescriptor(d, v, i.options.Aligner)
+}
+
+// validateSeries checks the View against the MetricDescriptor of each project that the time-series came from
+// Projects' descriptors for a metric type may differ e.g. if the View was changed between deployments
+// A ScopingProject's descriptor (checked by ValidateView) covers its monitored projects
+func (i *Importer) validateSeries(ctx context.Context, v *view.View, series []*monitoringpb.TimeSeries) error {
+	if i.options.ScopingProject != "" {
+		return nil
+	}
+	metricType := i.metricType(v)
+	seen := map[string]bool{}
+	for _, ts := range series {
+		project := ProjectID(ts)
+		if project == "" || seen[project] {
+			continue
+		}
+		seen[project] = true
+		d, err := i.descriptor(ctx, project, metricType)
+		if err != nil {
+			return fmt.Errorf("Unable to get the metric descriptor for '%s' from project '%s': %s", metricType, project, err)
+		}
+		if err := validateDescriptor(d, v, i.options.Aligner); err != nil {
+			return fmt.Errorf("Project '%s': %s", project, err)
+		}
+	}
+	return nil
+}
+
+// validateDescriptor checks the descriptor's label keys, metric kind and value type
+// The descriptor may have label keys that the View doesn't (e.g. the exporter's opencensus_task)
+func validateDescriptor(d *metricpb.MetricDescriptor, v *view.View, aligner monitoringpb.Aggregation_Aligner) error {
+	keys := map[string]bool{}
+	for _, label := range d.GetLabels() {
+		keys[label.GetKey()] = true
+	}
+	for _, labelName := range v.LabelNames {
+		if !keys[labelName] {
+			return fmt.Errorf("label %s not present on descriptor '%s'", labelName, d.GetType())
+		}
+	}
+
+	switch kind := d.GetMetricKind(); kind {
+	case metricpb.MetricDescriptor_GAUGE:
+		switch aligner {
+		case monitoringpb.Aggregation_ALIGN_DELTA, monitoringpb.Aggregation_ALIGN_RATE:
+			return fmt.Errorf("Aligner %s requires a DELTA or CUMULATIVE metric; '%s' is %s", aligner, d.GetType(), kind)
+		}
+	case metricpb.MetricDescriptor_DELTA, metricpb.MetricDescriptor_CUMULATIVE:
+	default:
+		return fmt.Errorf("Metric kind %s of '%s' is not supported", kind, d.GetType())
+	}
+
+	switch valueType := d.GetValueType(); valueType {
+	case metricpb.MetricDescriptor_BOOL, metricpb.MetricDescriptor_INT64, metricpb.MetricDescriptor_DOUBLE, metricpb.MetricDescriptor_STRING, metricpb.MetricDescriptor_DISTRIBUTION:
+	default:
+		return &ValueTypeError{valueType, fmt.Sprintf("metric '%s' is not supported", d.GetType())}
+	}
+	return nil
+}
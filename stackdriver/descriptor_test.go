@@ -0,0 +1,63 @@
+package stackdriver
+
+import (
+	"testing"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	labelpb "google.golang.org/genproto/googleapis/api/label"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func Test_validateDescriptor(t *testing.T) {
+	d := &metricpb.MetricDescriptor{
+		Type: "custom.googleapis.com/opencensus/counter0",
+		Labels: []*labelpb.LabelDescriptor{
+			{Key: "key1"},
+			{Key: "opencensus_task"},
+		},
+		MetricKind: metricpb.MetricDescriptor_CUMULATIVE,
+		ValueType:  metricpb.MetricDescriptor_DOUBLE,
+	}
+	t.Run("Valid", func(t *testing.T) {
+		v := &view.View{
+			Name:       "counter0",
+			LabelNames: []string{"key1"},
+		}
+		if err := validateDescriptor(d, v, monitoringpb.Aggregation_ALIGN_DELTA); err != nil {
+			t.Errorf("got %s; want nil", err)
+		}
+	})
+	t.Run("Missing Label", func(t *testing.T) {
+		v := &view.View{
+			Name:       "counter0",
+			LabelNames: []string{"key1", "key2"},
+		}
+		err := validateDescriptor(d, v, monitoringpb.Aggregation_ALIGN_NONE)
+		if err == nil {
+			t.Fatal("got nil; want error")
+		}
+		if got, want := err.Error(), "label key2 not present on descriptor 'custom.googleapis.com/opencensus/counter0'"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Aligner for Gauge", func(t *testing.T) {
+		gauge := &metricpb.MetricDescriptor{
+			MetricKind: metricpb.MetricDescriptor_GAUGE,
+			ValueType:  metricpb.MetricDescriptor_INT64,
+		}
+		if err := validateDescriptor(gauge, &view.View{}, monitoringpb.Aggregation_ALIGN_RATE); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unsupported Value Type", func(t *testing.T) {
+		money := &metricpb.MetricDescriptor{
+			MetricKind: metricpb.MetricDescriptor_GAUGE,
+			ValueType:  metricpb.MetricDescriptor_MONEY,
+		}
+		err := validateDescriptor(money, &view.View{}, monitoringpb.Aggregation_ALIGN_NONE)
+		if _, ok := err.(*ValueTypeError); !ok {
+			t.Errorf("got %v; want *ValueTypeError", err)
+		}
+	})
+}
@@ -0,0 +1,422 @@
+package stackdriver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr represents a Stackdriver (Cloud Monitoring) filter expression
+// See: https://cloud.google.com/monitoring/api/v3/filters
+type Expr interface {
+	// String returns the expression in canonical form
+	String() string
+	expr()
+}
+
+// And represents the conjunction of expressions
+type And []Expr
+
+// Or represents the disjunction of expressions
+type Or []Expr
+
+// Not represents the negation of an expression
+type Not struct {
+	Expr Expr
+}
+
+// Comparison represents a "<selector> <operator> <value>" expression
+type Comparison struct {
+	Selector Selector
+	Operator Operator
+	Value    Value
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+// String returns the expressions joined by AND
+func (a And) String() string {
+	return join(a, " AND ")
+}
+
+// String returns the expressions joined by OR
+func (o Or) String() string {
+	return join(o, " OR ")
+}
+
+// String returns the expression preceded by NOT
+func (n Not) String() string {
+	return "NOT " + group(n.Expr)
+}
+
+// String returns the comparison as "<selector><operator><value>"
+func (c Comparison) String() string {
+	return c.Selector.String() + string(c.Operator) + c.Value.String()
+}
+
+// join returns the expressions separated by sep; nested And|Or expressions are parenthesized
+func join(ee []Expr, sep string) string {
+	ss := make([]string, len(ee))
+	for i, e := range ee {
+		ss[i] = group(e)
+	}
+	return strings.Join(ss, sep)
+}
+
+// group parenthesizes And|Or expressions (with more than one term) so that precedence is unambiguous
+func group(e Expr) string {
+	switch e := e.(type) {
+	case And:
+		if len(e) > 1 {
+			return "(" + e.String() + ")"
+		}
+	case Or:
+		if len(e) > 1 {
+			return "(" + e.String() + ")"
+		}
+	}
+	return e.String()
+}
+
+// Operator represents a comparison operator
+type Operator string
+
+// Operators
+const (
+	Equal              Operator = "="
+	NotEqual           Operator = "!="
+	LessThan           Operator = "<"
+	LessThanOrEqual    Operator = "<="
+	GreaterThan        Operator = ">"
+	GreaterThanOrEqual Operator = ">="
+)
+
+// Selector objects
+const (
+	ResourceType       = "resource.type"
+	ResourceLabels     = "resource.labels"
+	MetricType         = "metric.type"
+	MetricLabels       = "metric.labels"
+	SystemLabels       = "metadata.system_labels"
+	UserLabels         = "metadata.user_labels"
+	Project            = "project"
+	GroupID            = "group.id"
+	resourceLabelAlias = "resource.label"
+	metricLabelAlias   = "metric.label"
+)
+
+// Selector represents the left-hand side of a comparison e.g. resource.type, metric.labels.[key]
+// Key is only used by the label selectors
+type Selector struct {
+	Object string
+	Key    string
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// String returns the selector with its key (quoted if necessary)
+func (s Selector) String() string {
+	if s.Key == "" {
+		return s.Object
+	}
+	key := s.Key
+	if !identifier.MatchString(key) {
+		key = strconv.Quote(key)
+	}
+	return s.Object + "." + key
+}
+
+// labelled returns true if the selector's object requires a key
+func (s Selector) labelled() bool {
+	switch s.Object {
+	case ResourceLabels, MetricLabels, SystemLabels, UserLabels:
+		return true
+	}
+	return false
+}
+
+// Value represents the right-hand side of a comparison
+type Value interface {
+	String() string
+	value()
+}
+
+// StringValue represents a quoted string
+type StringValue string
+
+// NumberValue represents a number
+type NumberValue float64
+
+// BoolValue represents true|false
+type BoolValue bool
+
+// Function represents one of the filter functions applied to string arguments
+type Function struct {
+	Name string
+	Args []string
+}
+
+func (StringValue) value() {}
+func (NumberValue) value() {}
+func (BoolValue) value()   {}
+func (Function) value()    {}
+
+// String returns the string quoted
+func (s StringValue) String() string {
+	return strconv.Quote(string(s))
+}
+
+// String returns the number in its shortest form
+func (n NumberValue) String() string {
+	return strconv.FormatFloat(float64(n), 'g', -1, 64)
+}
+
+// String returns true|false
+func (b BoolValue) String() string {
+	return strconv.FormatBool(bool(b))
+}
+
+// String returns the function applied to its quoted arguments
+func (f Function) String() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = strconv.Quote(arg)
+	}
+	return f.Name + "(" + strings.Join(args, ",") + ")"
+}
+
+// Filter functions
+const (
+	startsWith     = "starts_with"
+	endsWith       = "ends_with"
+	hasSubstring   = "has_substring"
+	oneOf          = "one_of"
+	regexFullMatch = "monitoring.regex.full_match"
+)
+
+// StartsWith returns a starts_with("prefix") function
+func StartsWith(prefix string) Function {
+	return Function{Name: startsWith, Args: []string{prefix}}
+}
+
+// EndsWith returns an ends_with("suffix") function
+func EndsWith(suffix string) Function {
+	return Function{Name: endsWith, Args: []string{suffix}}
+}
+
+// HasSubstring returns a has_substring("substring") function
+func HasSubstring(substring string) Function {
+	return Function{Name: hasSubstring, Args: []string{substring}}
+}
+
+// OneOf returns a one_of("value",...) function
+func OneOf(values ...string) Function {
+	return Function{Name: oneOf, Args: values}
+}
+
+// RegexFullMatch returns a monitoring.regex.full_match("regex") function
+func RegexFullMatch(regex string) Function {
+	return Function{Name: regexFullMatch, Args: []string{regex}}
+}
+
+// Validate checks that the expression is one that Stackdriver will accept
+func Validate(e Expr) error {
+	switch e := e.(type) {
+	case And:
+		return validateAll(e)
+	case Or:
+		return validateAll(e)
+	case Not:
+		if e.Expr == nil {
+			return fmt.Errorf("NOT requires an expression")
+		}
+		return Validate(e.Expr)
+	case Comparison:
+		return validateComparison(e)
+	default:
+		return fmt.Errorf("Unexpected expression %T", e)
+	}
+}
+
+// validateAll validates each of the expressions in turn
+func validateAll(ee []Expr) error {
+	if len(ee) == 0 {
+		return fmt.Errorf("AND|OR requires at least one expression")
+	}
+	for _, e := range ee {
+		if err := Validate(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateComparison checks the selector, operator and value of a comparison are consistent
+func validateComparison(c Comparison) error {
+	switch c.Selector.Object {
+	case ResourceType, MetricType, Project, GroupID:
+		if c.Selector.Key != "" {
+			return fmt.Errorf("Selector '%s' does not take a key", c.Selector.Object)
+		}
+	case ResourceLabels, MetricLabels, SystemLabels, UserLabels:
+		if c.Selector.Key == "" {
+			return fmt.Errorf("Selector '%s' requires a key", c.Selector.Object)
+		}
+	default:
+		return fmt.Errorf("Unknown selector '%s'", c.Selector)
+	}
+	switch c.Operator {
+	case Equal, NotEqual:
+	case LessThan, LessThanOrEqual, GreaterThan, GreaterThanOrEqual:
+		if _, ok := c.Value.(NumberValue); !ok {
+			return fmt.Errorf("Operator '%s' requires a number, got '%s'", c.Operator, c.Value)
+		}
+		if !c.Selector.labelled() {
+			return fmt.Errorf("Operator '%s' may only be used with label selectors, got '%s'", c.Operator, c.Selector)
+		}
+	default:
+		return fmt.Errorf("Unknown operator '%s'", c.Operator)
+	}
+	switch v := c.Value.(type) {
+	case StringValue:
+	case NumberValue, BoolValue:
+		if !c.Selector.labelled() {
+			return fmt.Errorf("Selector '%s' requires a string, got '%s'", c.Selector, v)
+		}
+	case Function:
+		switch v.Name {
+		case startsWith, endsWith, hasSubstring, regexFullMatch:
+			if len(v.Args) != 1 {
+				return fmt.Errorf("Function '%s' requires one argument, got %d", v.Name, len(v.Args))
+			}
+		case oneOf:
+			if len(v.Args) == 0 {
+				return fmt.Errorf("Function '%s' requires at least one argument", v.Name)
+			}
+		default:
+			return fmt.Errorf("Unknown function '%s'", v.Name)
+		}
+		if v.Name == regexFullMatch {
+			if _, err := regexp.Compile(v.Args[0]); err != nil {
+				return fmt.Errorf("Function '%s' has an invalid regex: %s", v.Name, err)
+			}
+		}
+	case nil:
+		return fmt.Errorf("Selector '%s' has no value", c.Selector)
+	default:
+		return fmt.Errorf("Unexpected value %T", v)
+	}
+	return nil
+}
+
+// Match evaluates the expression, using lookup to find the value (if any) of each selector
+// e.g. to determine whether a time-series is selected by a filter
+func Match(e Expr, lookup func(s Selector) (string, bool)) (bool, error) {
+	switch e := e.(type) {
+	case And:
+		for _, e := range e {
+			ok, err := Match(e, lookup)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case Or:
+		for _, e := range e {
+			ok, err := Match(e, lookup)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case Not:
+		ok, err := Match(e.Expr, lookup)
+		return !ok, err
+	case Comparison:
+		value, present := lookup(e.Selector)
+		ok, err := compare(value, present, e.Operator, e.Value)
+		if err != nil {
+			return false, err
+		}
+		return ok, nil
+	default:
+		return false, fmt.Errorf("Unexpected expression %T", e)
+	}
+}
+
+// compare evaluates "<value> <operator> <v>"; values that aren't present only satisfy "!="
+func compare(value string, present bool, op Operator, v Value) (bool, error) {
+	if !present {
+		return op == NotEqual, nil
+	}
+	var equal bool
+	switch v := v.(type) {
+	case StringValue:
+		equal = value == string(v)
+	case BoolValue:
+		equal = value == v.String()
+	case Function:
+		ok, err := apply(value, v)
+		if err != nil {
+			return false, err
+		}
+		equal = ok
+	case NumberValue:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false, nil
+		}
+		switch op {
+		case LessThan:
+			return f < float64(v), nil
+		case LessThanOrEqual:
+			return f <= float64(v), nil
+		case GreaterThan:
+			return f > float64(v), nil
+		case GreaterThanOrEqual:
+			return f >= float64(v), nil
+		}
+		equal = f == float64(v)
+	default:
+		return false, fmt.Errorf("Unexpected value %T", v)
+	}
+	switch op {
+	case Equal:
+		return equal, nil
+	case NotEqual:
+		return !equal, nil
+	default:
+		return false, fmt.Errorf("Operator '%s' requires a number, got '%s'", op, v)
+	}
+}
+
+// apply evaluates the function for the value
+func apply(value string, f Function) (bool, error) {
+	switch f.Name {
+	case startsWith:
+		return strings.HasPrefix(value, f.Args[0]), nil
+	case endsWith:
+		return strings.HasSuffix(value, f.Args[0]), nil
+	case hasSubstring:
+		return strings.Contains(value, f.Args[0]), nil
+	case oneOf:
+		for _, arg := range f.Args {
+			if value == arg {
+				return true, nil
+			}
+		}
+		return false, nil
+	case regexFullMatch:
+		re, err := regexp.Compile("^(?:" + f.Args[0] + ")$")
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(value), nil
+	default:
+		return false, fmt.Errorf("Unknown function '%s'", f.Name)
+	}
+}
@@ -0,0 +1,133 @@
+package stackdriver
+
+import (
+	"testing"
+)
+
+func TestExpr_String(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		e    Expr
+		want string
+	}{
+		{
+			"Comparison",
+			Comparison{Selector{Object: ResourceType}, Equal, StringValue("global")},
+			"resource.type=\"global\"",
+		},
+		{
+			"Quoted Key",
+			Comparison{Selector{Object: MetricLabels, Key: "key.1"}, NotEqual, StringValue("value1")},
+			"metric.labels.\"key.1\"!=\"value1\"",
+		},
+		{
+			"Number",
+			Comparison{Selector{Object: MetricLabels, Key: "size"}, GreaterThanOrEqual, NumberValue(1.5)},
+			"metric.labels.size>=1.5",
+		},
+		{
+			"Function",
+			Comparison{Selector{Object: MetricType}, Equal, StartsWith("custom.googleapis.com/opencensus/")},
+			"metric.type=starts_with(\"custom.googleapis.com/opencensus/\")",
+		},
+		{
+			"One Of",
+			Comparison{Selector{Object: ResourceLabels, Key: "zone"}, Equal, OneOf("us-west1-a", "us-west1-b")},
+			"resource.labels.zone=one_of(\"us-west1-a\",\"us-west1-b\")",
+		},
+		{
+			"Nested",
+			And{
+				Comparison{Selector{Object: ResourceType}, Equal, StringValue("global")},
+				Or{
+					Comparison{Selector{Object: MetricLabels, Key: "key1"}, Equal, StringValue("a")},
+					Not{Comparison{Selector{Object: MetricLabels, Key: "key1"}, Equal, HasSubstring("b")}},
+				},
+			},
+			"resource.type=\"global\" AND (metric.labels.key1=\"a\" OR NOT metric.labels.key1=has_substring(\"b\"))",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.e.String(); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+func Test_Validate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		for _, e := range []Expr{
+			Comparison{Selector{Object: Project}, Equal, StringValue("my-project")},
+			Comparison{Selector{Object: SystemLabels, Key: "name"}, Equal, RegexFullMatch("gke-.*")},
+			Comparison{Selector{Object: UserLabels, Key: "count"}, LessThan, NumberValue(10)},
+			Comparison{Selector{Object: MetricLabels, Key: "ok"}, Equal, BoolValue(true)},
+		} {
+			if err := Validate(e); err != nil {
+				t.Errorf("'%s' got %s; want nil", e, err)
+			}
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, e := range []Expr{
+			Comparison{Selector{Object: "metric.kind"}, Equal, StringValue("X")},
+			Comparison{Selector{Object: MetricType, Key: "X"}, Equal, StringValue("X")},
+			Comparison{Selector{Object: MetricLabels}, Equal, StringValue("X")},
+			Comparison{Selector{Object: MetricType}, GreaterThan, NumberValue(1)},
+			Comparison{Selector{Object: MetricLabels, Key: "X"}, GreaterThan, StringValue("1")},
+			Comparison{Selector{Object: ResourceType}, Equal, NumberValue(1)},
+			Comparison{Selector{Object: MetricType}, Equal, Function{Name: "contains", Args: []string{"X"}}},
+			Comparison{Selector{Object: MetricType}, Equal, Function{Name: startsWith, Args: []string{"X", "Y"}}},
+			Comparison{Selector{Object: MetricType}, Equal, OneOf()},
+			Comparison{Selector{Object: MetricType}, Equal, RegexFullMatch("(")},
+			And{},
+			Not{},
+		} {
+			if err := Validate(e); err == nil {
+				t.Errorf("'%v' got nil; want error", e)
+			}
+		}
+	})
+}
+func Test_Match(t *testing.T) {
+	values := map[string]string{
+		"resource.type":        "global",
+		"metric.type":          "custom.googleapis.com/opencensus/counter0",
+		"metric.labels.key1":   "value1",
+		"metric.labels.size":   "10",
+		"resource.labels.zone": "us-west1-a",
+	}
+	lookup := func(s Selector) (string, bool) {
+		value, ok := values[s.String()]
+		return value, ok
+	}
+	for _, test := range []struct {
+		filter string
+		want   bool
+	}{
+		{"resource.type=\"global\"", true},
+		{"resource.type!=\"global\"", false},
+		{"metric.type=starts_with(\"custom.googleapis.com/opencensus/\")", true},
+		{"metric.type=ends_with(\"counter1\")", false},
+		{"metric.type=has_substring(\"opencensus\")", true},
+		{"resource.labels.zone=one_of(\"us-east1-b\",\"us-west1-a\")", true},
+		{"resource.labels.zone=monitoring.regex.full_match(\"us-.*\")", true},
+		{"resource.labels.zone=monitoring.regex.full_match(\"us\")", false},
+		{"metric.labels.size>5 AND metric.labels.size<=10", true},
+		{"metric.labels.size>10", false},
+		{"metric.labels.key2=\"value2\"", false},
+		{"metric.labels.key2!=\"value2\"", true},
+		{"metric.labels.key1=\"X\" OR NOT resource.type=\"gce_instance\"", true},
+	} {
+		e, err := Parse(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Match(e, lookup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("'%s' got %t; want %t", test.filter, got, test.want)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package stackdriver
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
-
-	"github.com/golang/glog"
 )
 
 // Filter represents a Stackdriver Filter string
@@ -25,27 +25,33 @@ func (f *Filter) add(s string) {
 }
 
 // AddResourceType optionally adds a resource.type string to the Filter
-func (f *Filter) AddResourceType(t string) {
+// Stackdriver filters may only contain one resource.type; adding another is an error
+func (f *Filter) AddResourceType(t string) error {
 	const (
 		resourceType = "resource.type"
 	)
 	if strings.Contains(f.String(), resourceType) {
-		glog.Fatalf("Stackdriver filters may only contain one '%s'", resourceType)
+		return fmt.Errorf("Stackdriver filters may only contain one '%s'", resourceType)
 	}
 	f.add(fmt.Sprintf("%s=\"%s\"", resourceType, t))
+	return nil
 }
 
 // AddMetricType optionally adds a metric.type corresponding to an OpenCensus custom metric to the Filter
-func (f *Filter) AddMetricType(t string) {
+// Metric types without a domain (e.g. external.googleapis.com) are prefixed with "custom.googleapis.com/opencensus"
+// Stackdriver filters may only contain one metric.type; adding another is an error
+func (f *Filter) AddMetricType(t string) error {
 	const (
-		metricPath = "custom.googleapis.com/opencensus"
 		metricType = "metric.type"
 	)
 	if strings.Contains(f.String(), metricType) {
-		glog.Fatalf("Stackdriver filters may only contain one '%s'", metricType)
+		return fmt.Errorf("Stackdriver filters may only contain one '%s'", metricType)
 	}
-	f.add(fmt.Sprintf("%s=\"%s/%s\"", metricType, metricPath, t))
-
+	if !hasDomain(t) {
+		t = path.Join(defaultDomain, t)
+	}
+	f.add(fmt.Sprintf("%s=\"%s\"", metricType, t))
+	return nil
 }
 
 // AddLabels optionally adds a set (as a map) of metric.label.[key]=[value] to the Filter
@@ -54,12 +60,29 @@ func (f *Filter) AddLabels(m map[string]string) {
 	const (
 		metricLabel = "metric.label"
 	)
-	labels := []string{}
-	for label, value := range m {
-		metricLabel := fmt.Sprintf("%s.\"%s\"=\"%s\"", metricLabel, label, value)
-		labels = append(labels, metricLabel)
+	f.add(labels(metricLabel, m))
+}
+
+// AddResourceLabels optionally adds a set (as a map) of resource.label.[key]=[value] to the Filter
+func (f *Filter) AddResourceLabels(m map[string]string) {
+	const (
+		resourceLabel = "resource.label"
+	)
+	f.add(labels(resourceLabel, m))
+}
+
+// labels returns the set (as a map) as [prefix].[key]=[value] strings, ordered by key
+func labels(prefix string, m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
-	f.add(strings.Join(labels, " "))
+	sort.Strings(keys)
+	ss := make([]string, len(keys))
+	for i, key := range keys {
+		ss[i] = fmt.Sprintf("%s.\"%s\"=\"%s\"", prefix, key, m[key])
+	}
+	return strings.Join(ss, " ")
 }
 
 // Empty returns true if the Filter is empty
@@ -71,3 +94,24 @@ func (f *Filter) Empty() bool {
 func (f *Filter) String() string {
 	return (string)(*f)
 }
+
+// ParseFilter parses and validates a Stackdriver filter string, returning it as a Filter in canonical form
+func ParseFilter(s string) (*Filter, error) {
+	e, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	f := NewFilter()
+	f.AddExpr(e)
+	return f, nil
+}
+
+// AddExpr adds an expression to the Filter; it's combined (implicitly AND) with what's already in the Filter
+func (f *Filter) AddExpr(e Expr) {
+	f.add(group(e))
+}
+
+// Expr parses the Filter into an expression
+func (f *Filter) Expr() (Expr, error) {
+	return Parse(f.String())
+}
@@ -41,6 +41,11 @@ func TestFilter_AddResourceType(t *testing.T) {
 	if got, want := f.String(), "resource.type=\"X\""; got != want {
 		t.Errorf("[addResourceType] got=\"%s\" want=\"%s\"", got, want)
 	}
+	t.Run("Repeated", func(t *testing.T) {
+		if err := f.AddResourceType("Y"); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
 }
 func TestFilter_AddMetricType(t *testing.T) {
 	f := NewFilter()
@@ -48,6 +53,18 @@ func TestFilter_AddMetricType(t *testing.T) {
 	if got, want := f.String(), "metric.type=\"custom.googleapis.com/opencensus/X\""; got != want {
 		t.Errorf("[addMetricType] got=\"%s\" want=\"%s\"", got, want)
 	}
+	t.Run("Repeated", func(t *testing.T) {
+		if err := f.AddMetricType("Y"); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("With Domain", func(t *testing.T) {
+		f := NewFilter()
+		f.AddMetricType("external.googleapis.com/X")
+		if got, want := f.String(), "metric.type=\"external.googleapis.com/X\""; got != want {
+			t.Errorf("[addMetricType] got=\"%s\" want=\"%s\"", got, want)
+		}
+	})
 }
 func TestFilter_AddLabels(t *testing.T) {
 	f := NewFilter()
@@ -78,6 +95,33 @@ func TestFilter_AddLabels(t *testing.T) {
 		}
 	})
 }
+func TestFilter_AddResourceLabels(t *testing.T) {
+	f := NewFilter()
+
+	t.Run("No Labels", func(t *testing.T) {
+		// Nil set of labels should leave Filter unchanged
+		f.AddResourceLabels(nil)
+		if got, want := f.String(), ""; got != want {
+			t.Errorf("[addResourceLabels] got=\"%s\" want=\"%s\"", got, want)
+		}
+	})
+	t.Run("Some Labels", func(t *testing.T) {
+		m := map[string]string{
+			"instance_id": "12345",
+			"zone":        "us-west1-a",
+		}
+		f.AddResourceLabels(m)
+		for key, value := range m {
+			// Ordering is not relevant so using 'Contains' to find string existence
+			if got, want := strings.Contains(
+				f.String(),
+				fmt.Sprintf("resource.label.\"%s\"=\"%s\"", key, value),
+			), true; got != want {
+				t.Errorf("[addResourceLabels] Unable to find 'resource.label.\"%s\"=\"%s\"'", key, value)
+			}
+		}
+	})
+}
 func TestFilter_Empty(t *testing.T) {
 	t.Run("Empty", func(t *testing.T) {
 		f := NewFilter()
@@ -0,0 +1,49 @@
+package stackdriver
+
+import (
+	"path"
+	"strings"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// defaultDomain is the prefix of metric types that have no domain, as used by the Stackdriver Exporter
+var defaultDomain = path.Join("custom.googleapis.com", "opencensus")
+
+// domains are the metric type domains that the Stackdriver Exporter leaves unchanged
+// e.g. external.googleapis.com, workload.googleapis.com
+var domains = []string{"googleapis.com", "kubernetes.io", "istio.io", "knative.dev"}
+
+// hasDomain returns true if the metric type already includes one of the domains
+func hasDomain(name string) bool {
+	for _, domain := range domains {
+		if strings.Contains(name, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricType returns the Stackdriver metric type for the View, mapping it in the same way as the Stackdriver Exporter
+// GetMetricType takes precedence; ExportView ignores the prefixes so they only apply with ExportMetrics
+// GetMetricPrefix takes precedence over MetricPrefix
+func (i *Importer) metricType(v *view.View) string {
+	if i.options.GetMetricType != nil {
+		return i.options.GetMetricType(v)
+	}
+	name := v.Name
+	if !i.options.ExportMetrics {
+		return path.Join(defaultDomain, name)
+	}
+	prefix := i.options.MetricPrefix
+	if i.options.GetMetricPrefix != nil {
+		prefix = i.options.GetMetricPrefix(name)
+	}
+	if prefix != "" {
+		name = path.Join(prefix, name)
+	}
+	if !hasDomain(name) {
+		name = path.Join(defaultDomain, name)
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package stackdriver
+
+import (
+	"testing"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+func Test_hasDomain(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"counter0", false},
+		{"namespace/counter0", false},
+		{"custom.googleapis.com/opencensus/counter0", true},
+		{"external.googleapis.com/prometheus/counter0", true},
+		{"workload.googleapis.com/counter0", true},
+		{"kubernetes.io/container/cpu", true},
+	} {
+		if got := hasDomain(test.name); got != test.want {
+			t.Errorf("'%s' got %t; want %t", test.name, got, test.want)
+		}
+	}
+}
+func TestImporter_metricType(t *testing.T) {
+	v := &view.View{
+		Name: "counter0",
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{
+			"Default",
+			Options{},
+			"custom.googleapis.com/opencensus/counter0",
+		},
+		{
+			"MetricPrefix ExportView",
+			Options{MetricPrefix: "namespace"},
+			"custom.googleapis.com/opencensus/counter0",
+		},
+		{
+			"MetricPrefix",
+			Options{MetricPrefix: "namespace", ExportMetrics: true},
+			"custom.googleapis.com/opencensus/namespace/counter0",
+		},
+		{
+			"MetricPrefix with Domain",
+			Options{MetricPrefix: "workload.googleapis.com/", ExportMetrics: true},
+			"workload.googleapis.com/counter0",
+		},
+		{
+			"GetMetricPrefix",
+			Options{
+				ExportMetrics: true,
+				MetricPrefix:  "ignored",
+				GetMetricPrefix: func(name string) string {
+					return "external.googleapis.com/" + name[:len(name)-1]
+				},
+			},
+			"external.googleapis.com/counter/counter0",
+		},
+		{
+			"GetMetricType",
+			Options{
+				ExportMetrics: true,
+				GetMetricPrefix: func(name string) string {
+					return "ignored"
+				},
+				GetMetricType: func(v *view.View) string {
+					return "custom.googleapis.com/" + v.Name
+				},
+			},
+			"custom.googleapis.com/counter0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i, _ := NewImporter(test.options)
+			if got := i.metricType(v); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package stackdriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// token types
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenDot
+)
+
+// token represents a lexical token in a filter
+type token struct {
+	kind  int
+	text  string
+	value string
+	pos   int
+}
+
+// lex splits a filter into tokens
+func lex(s string) ([]token, error) {
+	tokens := []token{}
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '.':
+			tokens = append(tokens, token{kind: tokenDot, text: ".", pos: i})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenOperator, text: "=", pos: i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("[lex] Unexpected '!' at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		case r == '"':
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("[lex] Unterminated string at %d", i)
+			}
+			text := string(rs[i : j+1])
+			tokens = append(tokens, token{kind: tokenString, text: text, value: unquote(text), pos: i})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for ; j < len(rs) && (unicode.IsDigit(rs[j]) || strings.ContainsRune(".eE+-", rs[j])); j++ {
+			}
+			text := string(rs[i:j])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("[lex] Invalid number '%s' at %d", text, i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: text, pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for ; j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '-'); j++ {
+			}
+			text := string(rs[i:j])
+			tokens = append(tokens, token{kind: tokenIdent, text: text, value: text, pos: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("[lex] Unexpected '%c' at %d", r, i)
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(rs)})
+	return tokens, nil
+}
+
+// unquote removes the quotes (and escapes) from a string token
+// Escapes that Go doesn't recognize (e.g. "\d" in a regex) are kept verbatim
+func unquote(text string) string {
+	if s, err := strconv.Unquote(text); err == nil {
+		return s
+	}
+	inner := text[1 : len(text)-1]
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(inner)
+}
+
+// parser is a recursive descent parser for filters
+// Precedence (highest first) is NOT, AND (explicit or implied by juxtaposition), OR
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses and validates a Stackdriver filter
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, fmt.Errorf("[Parse] Filter is empty")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("[Parse] Unexpected '%s' at %d", t.text, t.pos)
+	}
+	if err := Validate(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword returns true if the next token is the (upper-case) keyword
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && t.text == k
+}
+
+// expect consumes the next token, which must be of the kind specified
+func (p *parser) expect(kind int, description string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		if t.kind == tokenEOF {
+			return t, fmt.Errorf("[Parse] Expected %s at end of filter", description)
+		}
+		return t, fmt.Errorf("[Parse] Expected %s at %d, got '%s'", description, t.pos, t.text)
+	}
+	return t, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := Or{e}
+	for p.keyword("OR") {
+		p.next()
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	and := And{e}
+	for {
+		if p.keyword("AND") {
+			p.next()
+		} else if t := p.peek(); t.kind == tokenEOF || t.kind == tokenRightParen || p.keyword("OR") {
+			break
+		}
+		// Otherwise juxtaposition implies AND
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("NOT") {
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+	if p.peek().kind == tokenLeftParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen, "')'"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	s, err := p.parseSelector()
+	if err != nil {
+		return nil, err
+	}
+	op, err := p.expect(tokenOperator, "an operator")
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return Comparison{
+		Selector: s,
+		Operator: Operator(op.text),
+		Value:    v,
+	}, nil
+}
+
+// parseSelector parses a dotted selector; label keys may be identifiers or quoted strings
+func (p *parser) parseSelector() (Selector, error) {
+	t, err := p.expect(tokenIdent, "a selector")
+	if err != nil {
+		return Selector{}, err
+	}
+	parts := []string{t.value}
+	for p.peek().kind == tokenDot {
+		p.next()
+		t := p.next()
+		if t.kind != tokenIdent && t.kind != tokenString {
+			return Selector{}, fmt.Errorf("[Parse] Expected a selector part at %d, got '%s'", t.pos, t.text)
+		}
+		parts = append(parts, t.value)
+	}
+	if len(parts) == 3 {
+		switch object := parts[0] + "." + parts[1]; object {
+		case resourceLabelAlias, ResourceLabels:
+			return Selector{Object: ResourceLabels, Key: parts[2]}, nil
+		case metricLabelAlias, MetricLabels:
+			return Selector{Object: MetricLabels, Key: parts[2]}, nil
+		case SystemLabels, UserLabels:
+			return Selector{Object: object, Key: parts[2]}, nil
+		}
+	}
+	// Validate rejects unknown selectors
+	return Selector{Object: strings.Join(parts, ".")}, nil
+}
+
+// parseValue parses a string, number, boolean or function
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return StringValue(t.value), nil
+	case tokenNumber:
+		f, _ := strconv.ParseFloat(t.value, 64)
+		return NumberValue(f), nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return BoolValue(true), nil
+		case "false":
+			return BoolValue(false), nil
+		}
+		name := t.text
+		for p.peek().kind == tokenDot {
+			p.next()
+			t, err := p.expect(tokenIdent, "a function name")
+			if err != nil {
+				return nil, err
+			}
+			name += "." + t.text
+		}
+		return p.parseArgs(name)
+	case tokenEOF:
+		return nil, fmt.Errorf("[Parse] Expected a value at end of filter")
+	default:
+		return nil, fmt.Errorf("[Parse] Expected a value at %d, got '%s'", t.pos, t.text)
+	}
+}
+
+// parseArgs parses a function's parenthesized, comma-separated, string arguments
+func (p *parser) parseArgs(name string) (Value, error) {
+	if _, err := p.expect(tokenLeftParen, "'(' after "+name); err != nil {
+		return nil, err
+	}
+	f := Function{Name: name, Args: []string{}}
+	if p.peek().kind == tokenRightParen {
+		p.next()
+		return f, nil
+	}
+	for {
+		t, err := p.expect(tokenString, "a string argument")
+		if err != nil {
+			return nil, err
+		}
+		f.Args = append(f.Args, t.value)
+		if p.peek().kind == tokenComma {
+			p.next()
+			continue
+		}
+		if _, err := p.expect(tokenRightParen, "')'"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+}
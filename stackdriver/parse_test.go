@@ -0,0 +1,135 @@
+package stackdriver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Run("Comparison", func(t *testing.T) {
+		got, err := Parse("metric.label.\"key1\" = \"value1\"")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Comparison{Selector{Object: MetricLabels, Key: "key1"}, Equal, StringValue("value1")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Implicit AND", func(t *testing.T) {
+		got, err := Parse("resource.type=\"global\" metric.type=\"custom.googleapis.com/opencensus/X\"")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if and, ok := got.(And); !ok || len(and) != 2 {
+			t.Errorf("got %v; want And of 2 expressions", got)
+		}
+	})
+	t.Run("Precedence", func(t *testing.T) {
+		got, err := Parse("metric.labels.a=\"1\" OR metric.labels.b=\"2\" AND NOT metric.labels.c=\"3\"")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Or{
+			Comparison{Selector{Object: MetricLabels, Key: "a"}, Equal, StringValue("1")},
+			And{
+				Comparison{Selector{Object: MetricLabels, Key: "b"}, Equal, StringValue("2")},
+				Not{Comparison{Selector{Object: MetricLabels, Key: "c"}, Equal, StringValue("3")}},
+			},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Functions", func(t *testing.T) {
+		got, err := Parse("metric.type = monitoring.regex.full_match(\"custom.*\") resource.label.zone = one_of(\"a\", \"b\")")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := And{
+			Comparison{Selector{Object: MetricType}, Equal, RegexFullMatch("custom.*")},
+			Comparison{Selector{Object: ResourceLabels, Key: "zone"}, Equal, OneOf("a", "b")},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Numbers and Booleans", func(t *testing.T) {
+		got, err := Parse("metadata.user_labels.size >= -2.5 AND metric.labels.ok = false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := And{
+			Comparison{Selector{Object: UserLabels, Key: "size"}, GreaterThanOrEqual, NumberValue(-2.5)},
+			Comparison{Selector{Object: MetricLabels, Key: "ok"}, Equal, BoolValue(false)},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, s := range []string{
+			"",
+			"resource.type",
+			"resource.type=",
+			"resource.type=\"global",
+			"resource.type!\"global\"",
+			"(resource.type=\"global\"",
+			"resource.type=\"global\")",
+			"resource.type=starts_with(X)",
+			"metric.kind=\"GAUGE\"",
+			"resource.type=\"global\" AND",
+			"resource.type=\"global\" OR OR metric.type=\"X\"",
+		} {
+			if _, err := Parse(s); err == nil {
+				t.Errorf("'%s' got nil; want error", s)
+			}
+		}
+	})
+}
+func Test_Parse_RoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"resource.type=\"global\"",
+		"metric.labels.\"key-1\"=\"value \\\"1\\\"\"",
+		"resource.type=\"gce_instance\" AND (metric.labels.a=\"1\" OR NOT metric.labels.b=ends_with(\"x\"))",
+		"(metric.labels.a=\"1\" AND metric.labels.b=\"2\") OR project=\"p\"",
+		"metric.type=monitoring.regex.full_match(\"custom\\\\.googleapis\\\\.com/.*\")",
+		"NOT (metric.labels.a=\"1\" OR metric.labels.a=\"2\")",
+	} {
+		e, err := Parse(s)
+		if err != nil {
+			t.Errorf("'%s' got %s; want nil", s, err)
+			continue
+		}
+		if got := e.String(); got != s {
+			t.Errorf("got %s; want %s", got, s)
+		}
+	}
+}
+func Test_ParseFilter(t *testing.T) {
+	f, err := ParseFilter("resource.type = \"global\" metric.label.\"key1\" = \"value1\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.String(), "(resource.type=\"global\" AND metric.labels.key1=\"value1\")"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func TestFilter_AddExpr(t *testing.T) {
+	f := NewFilter()
+	f.AddResourceType("global")
+	f.AddExpr(Or{
+		Comparison{Selector{Object: MetricLabels, Key: "key1"}, Equal, StringValue("a")},
+		Comparison{Selector{Object: MetricLabels, Key: "key1"}, Equal, StringValue("b")},
+	})
+	if got, want := f.String(), "resource.type=\"global\" (metric.labels.key1=\"a\" OR metric.labels.key1=\"b\")"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	e, err := f.Expr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if and, ok := e.(And); !ok || len(and) != 2 {
+		t.Errorf("got %v; want And of 2 expressions", e)
+	}
+}
@@ -0,0 +1,100 @@
+package stackdriver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/api/iterator"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// projectLabel is the resource label with which Cloud Monitoring identifies a time-series' project
+const projectLabel = "project_id"
+
+// projects returns the projects that the Importer reads
+// A ScopingProject is read alone because its metrics scope includes its monitored projects
+// Otherwise ProjectID (or environment variable PROJECT) is read with any ProjectIDs, without duplicates
+func projects(o Options, env string) []string {
+	if o.ScopingProject != "" {
+		return []string{o.ScopingProject}
+	}
+	projectID := o.ProjectID
+	if projectID == "" {
+		projectID = env
+	}
+	result := []string{}
+	seen := map[string]bool{}
+	for _, p := range append([]string{projectID}, o.ProjectIDs...) {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// ProjectID returns the project that a time-series (returned by the Importer) came from
+func ProjectID(ts *monitoringpb.TimeSeries) string {
+	return ts.GetResource().GetLabels()[projectLabel]
+}
+
+// listTimeSeries reads the request's time-series from each of the Importer's projects concurrently
+// The time-series are returned in the order of the projects; if any project fails, its error is returned
+func (i *Importer) listTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) ([]*monitoringpb.TimeSeries, error) {
+	client, err := i.metricClient()
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]*monitoringpb.TimeSeries, len(i.projects))
+	errs := make([]error, len(i.projects))
+	var wg sync.WaitGroup
+	for j, project := range i.projects {
+		wg.Add(1)
+		go func(j int, project string) {
+			defer wg.Done()
+			r := proto.Clone(req).(*monitoringpb.ListTimeSeriesRequest)
+			r.Name = fmt.Sprintf("projects/%s", project)
+			it := client.ListTimeSeries(ctx, r)
+			for {
+				ts, err := it.Next()
+				if err == iterator.Done {
+					return
+				}
+				if err != nil {
+					errs[j] = fmt.Errorf("Unable to read time-series from project '%s': %s", project, err)
+					return
+				}
+				setProject(ts, project)
+				results[j] = append(results[j], ts)
+			}
+		}(j, project)
+	}
+	wg.Wait()
+
+	series := []*monitoringpb.TimeSeries{}
+	for j := range results {
+		if errs[j] != nil {
+			return nil, errs[j]
+		}
+		series = append(series, results[j]...)
+	}
+	return series, nil
+}
+
+// setProject records the project that was read on a time-series that doesn't already identify its project
+// Time-series read through a scoping project identify their (monitored) project
+func setProject(ts *monitoringpb.TimeSeries, project string) {
+	if ts.Resource == nil {
+		ts.Resource = &monitoredrespb.MonitoredResource{}
+	}
+	if ts.Resource.Labels == nil {
+		ts.Resource.Labels = map[string]string{}
+	}
+	if _, ok := ts.Resource.Labels[projectLabel]; !ok {
+		ts.Resource.Labels[projectLabel] = project
+	}
+}
@@ -0,0 +1,57 @@
+package stackdriver
+
+import (
+	"strings"
+	"testing"
+
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func Test_projects(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		o    Options
+		env  string
+		want string
+	}{
+		{"Environment", Options{}, "freddie", "freddie"},
+		{"ProjectID", Options{ProjectID: "a"}, "freddie", "a"},
+		{"ProjectIDs", Options{ProjectID: "a", ProjectIDs: []string{"b", "a", "c"}}, "", "a,b,c"},
+		{"ProjectIDs Only", Options{ProjectIDs: []string{"b", "c"}}, "", "b,c"},
+		{"ScopingProject", Options{ProjectID: "a", ScopingProject: "scope"}, "", "scope"},
+		{"None", Options{}, "", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := strings.Join(projects(test.o, test.env), ","); got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+func TestImporter_projectID(t *testing.T) {
+	i, _ := NewImporter(Options{ProjectIDs: []string{"b", "c"}})
+	if got, want := i.projectID, "b"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func Test_setProject(t *testing.T) {
+	t.Run("Unset", func(t *testing.T) {
+		ts := &monitoringpb.TimeSeries{}
+		setProject(ts, "a")
+		if got, want := ProjectID(ts), "a"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Monitored Project", func(t *testing.T) {
+		ts := &monitoringpb.TimeSeries{
+			Resource: &monitoredrespb.MonitoredResource{
+				Labels: map[string]string{"project_id": "b"},
+			},
+		}
+		setProject(ts, "scope")
+		if got, want := ProjectID(ts), "b"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
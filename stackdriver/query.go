@@ -0,0 +1,296 @@
+package stackdriver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	googlepb "github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/api/iterator"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+// QueryData is the data with which MQL and PromQL query templates are executed
+type QueryData struct {
+	// Name is the View's name
+	Name string
+	// MetricType is the View's Stackdriver metric type e.g. custom.googleapis.com/opencensus/[Name]
+	MetricType string
+	// Labels maps the View's label names to the label values of the read
+	Labels map[string]string
+	// Within is an MQL "within" table operation for the Importer's window ending at the time of the read
+	Within string
+}
+
+// prometheusURL is the base URL of Cloud Monitoring's Prometheus-compatible API
+const prometheusURL = "https://monitoring.googleapis.com/v1/projects/%s/location/global/prometheus"
+
+// query returns the View's query data for the label values and time of the read
+func (i *Importer) query(v *view.View, labelValues []string, t time.Time) (QueryData, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return QueryData{}, errors.New("Inconsistency between labels and values")
+	}
+	labels := map[string]string{}
+	for j, labelName := range v.LabelNames {
+		labels[labelName] = labelValues[j]
+	}
+	return QueryData{
+		Name:       v.Name,
+		MetricType: i.metricType(v),
+		Labels:     labels,
+		Within:     fmt.Sprintf("within %ds, d'%s'", int64(i.window()/time.Second), t.UTC().Format("2006/01/02-15:04:05")),
+	}, nil
+}
+
+// execute executes the query template with the query data
+func execute(text string, data QueryData) (string, error) {
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// queryMQL reads the time-series returned by the MQL query template
+func (i *Importer) queryMQL(ctx context.Context, data QueryData) ([]*monitoringpb.TimeSeries, error) {
+	q, err := execute(i.options.MQL, data)
+	if err != nil {
+		return nil, err
+	}
+	client, err := i.queryClient()
+	if err != nil {
+		return nil, err
+	}
+	it := client.QueryTimeSeries(ctx, &monitoringpb.QueryTimeSeriesRequest{
+		Name:     fmt.Sprintf("projects/%s", i.projectID),
+		Query:    q,
+		PageSize: i.options.PageSize,
+	})
+	series := []*monitoringpb.TimeSeries{}
+	for {
+		d, err := it.Next()
+		if err == iterator.Done {
+			return series, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp, _ := it.Response.(*monitoringpb.QueryTimeSeriesResponse)
+		ts, err := fromTimeSeriesData(resp.GetTimeSeriesDescriptor(), d, data.MetricType)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, ts)
+	}
+}
+
+// fromTimeSeriesData converts MQL time-series data into a TimeSeries
+// Only the first of the point's values is used; label keys prefixed "metric." and "resource." become metric and resource labels
+func fromTimeSeriesData(d *monitoringpb.TimeSeriesDescriptor, data *monitoringpb.TimeSeriesData, metricType string) (*monitoringpb.TimeSeries, error) {
+	if len(d.GetPointDescriptors()) == 0 {
+		return nil, errors.New("MQL query result has no point descriptors")
+	}
+	if len(d.GetLabelDescriptors()) != len(data.GetLabelValues()) {
+		return nil, errors.New("Inconsistency between MQL label descriptors and values")
+	}
+	ts := &monitoringpb.TimeSeries{
+		Metric: &metricpb.Metric{
+			Type:   metricType,
+			Labels: map[string]string{},
+		},
+		Resource: &monitoredrespb.MonitoredResource{
+			Labels: map[string]string{},
+		},
+		MetricKind: d.GetPointDescriptors()[0].GetMetricKind(),
+		ValueType:  d.GetPointDescriptors()[0].GetValueType(),
+	}
+	for j, label := range d.GetLabelDescriptors() {
+		value := labelValue(data.GetLabelValues()[j])
+		switch key := label.GetKey(); {
+		case strings.HasPrefix(key, "resource."):
+			ts.Resource.Labels[strings.TrimPrefix(key, "resource.")] = value
+		default:
+			ts.Metric.Labels[strings.TrimPrefix(key, "metric.")] = value
+		}
+	}
+	for _, p := range data.GetPointData() {
+		if len(p.GetValues()) == 0 {
+			continue
+		}
+		ts.Points = append(ts.Points, &monitoringpb.Point{
+			Interval: p.GetTimeInterval(),
+			Value:    p.GetValues()[0],
+		})
+	}
+	return ts, nil
+}
+
+// labelValue returns the MQL label value as a string
+func labelValue(v *monitoringpb.LabelValue) string {
+	switch v := v.GetValue().(type) {
+	case *monitoringpb.LabelValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *monitoringpb.LabelValue_Int64Value:
+		return strconv.FormatInt(v.Int64Value, 10)
+	case *monitoringpb.LabelValue_StringValue:
+		return v.StringValue
+	default:
+		return ""
+	}
+}
+
+// promResponse represents the Prometheus HTTP API's response to an instant query
+// See: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+type promResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// promSample represents a Prometheus [time,"value"] pair
+type promSample [2]interface{}
+
+// promSeries represents a Prometheus vector element (Value) or matrix series (Values)
+type promSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  *promSample       `json:"value"`
+	Values []promSample      `json:"values"`
+}
+
+// queryPromQL reads the time-series returned by the PromQL query template, evaluated at the time of the read
+func (i *Importer) queryPromQL(ctx context.Context, data QueryData, t time.Time) ([]*monitoringpb.TimeSeries, error) {
+	q, err := execute(i.options.PromQL, data)
+	if err != nil {
+		return nil, err
+	}
+	client, err := i.prometheusClient()
+	if err != nil {
+		return nil, err
+	}
+	base := i.options.PrometheusURL
+	if base == "" {
+		base = fmt.Sprintf(prometheusURL, i.projectID)
+	}
+	form := url.Values{}
+	form.Set("query", q)
+	form.Set("time", strconv.FormatInt(t.Unix(), 10))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+"/api/v1/query", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("Unable to decode PromQL response (%s): %s", resp.Status, err)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("PromQL query failed (%s): %s: %s", resp.Status, r.ErrorType, r.Error)
+	}
+	return fromPromResult(r.Data.ResultType, r.Data.Result, data.MetricType)
+}
+
+// fromPromResult converts a Prometheus vector, matrix or scalar result into DOUBLE time-series
+// The metric type is the series' __name__ if it has one
+func fromPromResult(resultType string, result json.RawMessage, metricType string) ([]*monitoringpb.TimeSeries, error) {
+	var ss []promSeries
+	switch resultType {
+	case "vector", "matrix":
+		if err := json.Unmarshal(result, &ss); err != nil {
+			return nil, err
+		}
+	case "scalar":
+		var sample promSample
+		if err := json.Unmarshal(result, &sample); err != nil {
+			return nil, err
+		}
+		ss = []promSeries{{Value: &sample}}
+	default:
+		return nil, fmt.Errorf("PromQL result type '%s' is not supported", resultType)
+	}
+	series := []*monitoringpb.TimeSeries{}
+	for _, s := range ss {
+		ts := &monitoringpb.TimeSeries{
+			Metric: &metricpb.Metric{
+				Type:   metricType,
+				Labels: map[string]string{},
+			},
+			MetricKind: metricpb.MetricDescriptor_GAUGE,
+			ValueType:  metricpb.MetricDescriptor_DOUBLE,
+		}
+		for key, value := range s.Metric {
+			if key == "__name__" {
+				ts.Metric.Type = value
+				continue
+			}
+			ts.Metric.Labels[key] = value
+		}
+		samples := s.Values
+		if s.Value != nil {
+			samples = append(samples, *s.Value)
+		}
+		// Prometheus orders samples oldest first; the Importer expects newest first
+		for j := len(samples) - 1; j >= 0; j-- {
+			p, err := fromPromSample(samples[j])
+			if err != nil {
+				return nil, err
+			}
+			ts.Points = append(ts.Points, p)
+		}
+		series = append(series, ts)
+	}
+	return series, nil
+}
+
+// fromPromSample converts a Prometheus [time,"value"] pair into a DOUBLE point
+func fromPromSample(s promSample) (*monitoringpb.Point, error) {
+	seconds, ok := s[0].(float64)
+	if !ok {
+		return nil, fmt.Errorf("Invalid PromQL sample time '%v'", s[0])
+	}
+	text, ok := s[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("Invalid PromQL sample value '%v'", s[1])
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid PromQL sample value '%s'", text)
+	}
+	whole, frac := math.Modf(seconds)
+	return &monitoringpb.Point{
+		Interval: &monitoringpb.TimeInterval{
+			EndTime: &googlepb.Timestamp{
+				Seconds: int64(whole),
+				Nanos:   int32(frac * 1e9),
+			},
+		},
+		Value: &monitoringpb.TypedValue{
+			Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: value},
+		},
+	}, nil
+}
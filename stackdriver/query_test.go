@@ -0,0 +1,166 @@
+package stackdriver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	googlepb "github.com/golang/protobuf/ptypes/timestamp"
+	labelpb "google.golang.org/genproto/googleapis/api/label"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func TestImporter_query(t *testing.T) {
+	i, _ := NewImporter(Options{})
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	t.Run("Data", func(t *testing.T) {
+		data, err := i.query(v, []string{"value1", "value2"}, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := data.MetricType, "custom.googleapis.com/opencensus/counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := data.Labels["key2"], "value2"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := data.Within, "within 60s, d'2019/01/02-03:04:05'"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Inconsistent Labels", func(t *testing.T) {
+		if _, err := i.query(v, []string{"value1"}, time.Now()); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func Test_execute(t *testing.T) {
+	data := QueryData{
+		Name:       "counter0",
+		MetricType: "custom.googleapis.com/opencensus/counter0",
+		Labels:     map[string]string{"key1": "value1"},
+		Within:     "within 60s",
+	}
+	t.Run("MQL", func(t *testing.T) {
+		got, err := execute("fetch global::{{.MetricType}} | filter metric.key1 == '{{.Labels.key1}}' | {{.Within}}", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "fetch global::custom.googleapis.com/opencensus/counter0 | filter metric.key1 == 'value1' | within 60s"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("PromQL", func(t *testing.T) {
+		got, err := execute(`sum(rate({{.Name}}{key1="{{.Labels.key1}}"}[5m]))`, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `sum(rate(counter0{key1="value1"}[5m]))`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Missing Label", func(t *testing.T) {
+		if _, err := execute("{{.Labels.key9}}", data); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func Test_fromTimeSeriesData(t *testing.T) {
+	d := &monitoringpb.TimeSeriesDescriptor{
+		LabelDescriptors: []*labelpb.LabelDescriptor{
+			{Key: "resource.project_id"},
+			{Key: "metric.key1"},
+		},
+		PointDescriptors: []*monitoringpb.TimeSeriesDescriptor_ValueDescriptor{
+			{Key: "value.counter0", ValueType: metricpb.MetricDescriptor_DOUBLE, MetricKind: metricpb.MetricDescriptor_GAUGE},
+		},
+	}
+	data := &monitoringpb.TimeSeriesData{
+		LabelValues: []*monitoringpb.LabelValue{
+			{Value: &monitoringpb.LabelValue_StringValue{StringValue: "freddie"}},
+			{Value: &monitoringpb.LabelValue_StringValue{StringValue: "value1"}},
+		},
+		PointData: []*monitoringpb.TimeSeriesData_PointData{{
+			Values: []*monitoringpb.TypedValue{
+				{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: 42}},
+			},
+			TimeInterval: &monitoringpb.TimeInterval{EndTime: &googlepb.Timestamp{Seconds: 60}},
+		}},
+	}
+	ts, err := fromTimeSeriesData(d, data, "custom.googleapis.com/opencensus/counter0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ts.GetResource().GetLabels()["project_id"], "freddie"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := ts.GetMetric().GetLabels()["key1"], "value1"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := ts.GetValueType(), metricpb.MetricDescriptor_DOUBLE; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := ts.GetPoints()[0].GetValue().GetDoubleValue(), 42.0; got != want {
+		t.Errorf("got %f; want %f", got, want)
+	}
+	t.Run("No Point Descriptors", func(t *testing.T) {
+		if _, err := fromTimeSeriesData(&monitoringpb.TimeSeriesDescriptor{}, data, ""); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func Test_fromPromResult(t *testing.T) {
+	t.Run("Vector", func(t *testing.T) {
+		result := json.RawMessage(`[{"metric":{"__name__":"counter0","key1":"value1"},"value":[1546398245.5,"42"]}]`)
+		series, err := fromPromResult("vector", result, "custom.googleapis.com/opencensus/counter0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(series), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		ts := series[0]
+		if got, want := ts.GetMetric().GetType(), "counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := ts.GetMetric().GetLabels()["key1"], "value1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := ts.GetPoints()[0].GetInterval().GetEndTime().GetNanos(), int32(5e8); got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Matrix", func(t *testing.T) {
+		result := json.RawMessage(`[{"metric":{"key1":"value1"},"values":[[60,"1"],[120,"2"]]}]`)
+		series, err := fromPromResult("matrix", result, "custom.googleapis.com/opencensus/counter0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := series[0].GetMetric().GetType(), "custom.googleapis.com/opencensus/counter0"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		// Newest first
+		if got, want := series[0].GetPoints()[0].GetValue().GetDoubleValue(), 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Scalar", func(t *testing.T) {
+		series, err := fromPromResult("scalar", json.RawMessage(`[60,"0.5"]`), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := series[0].GetPoints()[0].GetValue().GetDoubleValue(), 0.5; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("String", func(t *testing.T) {
+		if _, err := fromPromResult("string", json.RawMessage(`[60,"X"]`), ""); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
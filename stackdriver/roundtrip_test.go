@@ -0,0 +1,607 @@
+package stackdriver_test
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	exporter "contrib.go.opencensus.io/exporter/stackdriver"
+	"github.com/dazwilkin/opencensus/stackdriver"
+	"github.com/dazwilkin/opencensus/stackdriver/stackdrivertest"
+	importer_view "github.com/dazwilkin/opencensus/stats/view"
+	googlepb "github.com/golang/protobuf/ptypes/timestamp"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	labelpb "google.golang.org/genproto/googleapis/api/label"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+const (
+	project = "freddie"
+)
+
+// export writes a Sum View's value (with the tags) to the Server using the Stackdriver Exporter
+// The value is written to the Options' ProjectID, by default project
+func export(t *testing.T, s *stackdrivertest.Server, o exporter.Options, name string, tags map[string]string, value float64, end time.Time) {
+	if o.ProjectID == "" {
+		o.ProjectID = project
+	}
+	o.MonitoringClientOptions = s.ClientOptions()
+	o.TraceClientOptions = s.ClientOptions()
+	e, err := exporter.NewExporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := &view.Row{
+		Data: &view.SumData{Value: value},
+	}
+	keys := []tag.Key{}
+	for k, v := range tags {
+		key, err := tag.NewKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		row.Tags = append(row.Tags, tag.Tag{Key: key, Value: v})
+	}
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        name,
+			Description: "Testing",
+			Measure:     stats.Float64(name, "Testing", "1"),
+			Aggregation: view.Sum(),
+			TagKeys:     keys,
+		},
+		Start: end.Add(-time.Minute),
+		End:   end,
+		Rows:  []*view.Row{row},
+	})
+	e.Flush()
+}
+
+// exportMetric writes a cumulative metric's value (with the labels) to the Server using the Stackdriver Exporter's ExportMetrics
+// Unlike ExportView, ExportMetrics applies the Exporter's MetricPrefix
+func exportMetric(t *testing.T, s *stackdrivertest.Server, o exporter.Options, name string, labels map[string]string, value float64, end time.Time) {
+	o.ProjectID = project
+	o.MonitoringClientOptions = s.ClientOptions()
+	o.TraceClientOptions = s.ClientOptions()
+	e, err := exporter.NewExporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &metricdata.TimeSeries{
+		Points:    []metricdata.Point{metricdata.NewFloat64Point(end, value)},
+		StartTime: end.Add(-time.Minute),
+	}
+	d := metricdata.Descriptor{
+		Name:        name,
+		Description: "Testing",
+		Unit:        metricdata.UnitDimensionless,
+		Type:        metricdata.TypeCumulativeFloat64,
+	}
+	for k, v := range labels {
+		d.LabelKeys = append(d.LabelKeys, metricdata.LabelKey{Key: k})
+		ts.LabelValues = append(ts.LabelValues, metricdata.NewLabelValue(v))
+	}
+	if err := e.ExportMetrics(context.Background(), []*metricdata.Metric{{
+		Descriptor: d,
+		TimeSeries: []*metricdata.TimeSeries{ts},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	// Metrics are bundled
+	e.Flush()
+}
+func TestImporter_Value(t *testing.T) {
+	s, err := stackdrivertest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	export(t, s, exporter.Options{}, "counter0", map[string]string{"key1": "value1", "key2": "value2"}, 42, now.Add(-10*time.Second))
+	export(t, s, exporter.Options{
+		GetMetricType: func(v *view.View) string {
+			return "custom.googleapis.com/namespace/" + v.Name
+		},
+	}, "counter1", map[string]string{"key1": "value1"}, 7, now.Add(-10*time.Second))
+	exportMetric(t, s, exporter.Options{MetricPrefix: "namespace"}, "counter2", map[string]string{"key1": "value1"}, 3, now.Add(-10*time.Second))
+	export(t, s, exporter.Options{}, "counter3", map[string]string{"key1": "value1", "key2": "a"}, 5, now.Add(-10*time.Second))
+	export(t, s, exporter.Options{}, "counter3", map[string]string{"key1": "value1", "key2": "b"}, 6, now.Add(-10*time.Second))
+
+	t.Run("Round Trip", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(&importer_view.View{
+			Name:       "counter0",
+			LabelNames: []string{"key1", "key2"},
+		}, []string{"value1", "value2"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 42.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("GetMetricType", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			GetMetricType: func(v *importer_view.View) string {
+				return "custom.googleapis.com/namespace/" + v.Name
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(&importer_view.View{
+			Name:       "counter1",
+			LabelNames: []string{"key1"},
+		}, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 7.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("MetricPrefix", func(t *testing.T) {
+		for _, test := range []struct {
+			name    string
+			options stackdriver.Options
+			view    string
+			want    float64
+		}{
+			// ExportView ignores the Exporter's MetricPrefix so the importer does too by default
+			{"ExportView", stackdriver.Options{MetricPrefix: "namespace"}, "counter0", 42},
+			{"ExportMetrics", stackdriver.Options{MetricPrefix: "namespace", ExportMetrics: true}, "counter2", 3},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				test.options.ProjectID = project
+				test.options.MonitoringClientOptions = s.ClientOptions()
+				i, err := stackdriver.NewImporter(test.options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer i.Close()
+				got, err := i.Value(&importer_view.View{
+					Name:       test.view,
+					LabelNames: []string{"key1"},
+				}, []string{"value1"}, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != test.want {
+					t.Errorf("got %f; want %f", got, test.want)
+				}
+			})
+		}
+		t.Run("Without ExportMetrics", func(t *testing.T) {
+			i, err := stackdriver.NewImporter(stackdriver.Options{
+				ProjectID:               project,
+				MonitoringClientOptions: s.ClientOptions(),
+				MetricPrefix:            "namespace",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer i.Close()
+			if _, err := i.Value(&importer_view.View{
+				Name:       "counter2",
+				LabelNames: []string{"key1"},
+			}, []string{"value1"}, now); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	})
+	t.Run("Label Mismatch", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		_, err = i.Value(&importer_view.View{
+			Name:       "counter0",
+			LabelNames: []string{"key1", "key2"},
+		}, []string{"value1", "X"}, now)
+		if err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Label Count", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		_, err = i.Value(&importer_view.View{
+			Name:       "counter0",
+			LabelNames: []string{"key1", "key2"},
+		}, []string{"value1"}, now)
+		if err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Unknown View", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		_, err = i.Value(&importer_view.View{Name: "counter9"}, nil, now)
+		if err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Aggregation", func(t *testing.T) {
+		// The deltas of the cumulative time-series (whose counts started within the period) are summed
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			AlignmentPeriod:         2 * time.Minute,
+			Aligner:                 monitoringpb.Aggregation_ALIGN_DELTA,
+			Reducer:                 monitoringpb.Aggregation_REDUCE_SUM,
+			GroupByFields:           []string{"metric.label.key1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(&importer_view.View{
+			Name:       "counter3",
+			LabelNames: []string{"key1"},
+		}, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 11.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+}
+func TestImporter_TimeSeries(t *testing.T) {
+	s, err := stackdrivertest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	export(t, s, exporter.Options{}, "counter0", map[string]string{"key1": "value1", "key2": "a"}, 1, now.Add(-30*time.Second))
+	export(t, s, exporter.Options{}, "counter0", map[string]string{"key1": "value1", "key2": "b"}, 2, now.Add(-10*time.Second))
+	export(t, s, exporter.Options{}, "counter0", map[string]string{"key1": "value1", "key2": "c"}, 3, now.Add(-20*time.Second))
+
+	v := &importer_view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}
+	t.Run("Pages", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			PageSize:                1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		before := len(s.Requests())
+		series, err := i.TimeSeries(context.Background(), v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(series), 3; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := len(s.Requests())-before, 3; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		for _, req := range s.Requests()[before:] {
+			if got, want := req.GetPageSize(), int32(1); got != want {
+				t.Errorf("got %d; want %d", got, want)
+			}
+		}
+	})
+	t.Run("Newest", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			PageSize:                2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Negative PageSize", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			PageSize:                -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		if _, err := i.Value(v, []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Query(t *testing.T) {
+	s, err := stackdrivertest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	v := &importer_view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}
+	t.Run("MQL", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:               project,
+			MonitoringClientOptions: s.ClientOptions(),
+			MQL:                     "fetch global::{{.MetricType}} | filter metric.key1 == '{{.Labels.key1}}'",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		s.SetMQLResult("fetch global::custom.googleapis.com/opencensus/counter0 | filter metric.key1 == 'value1'",
+			&monitoringpb.TimeSeriesDescriptor{
+				LabelDescriptors: []*labelpb.LabelDescriptor{{Key: "metric.key1"}},
+				PointDescriptors: []*monitoringpb.TimeSeriesDescriptor_ValueDescriptor{{
+					Key:        "value.counter0",
+					ValueType:  metricpb.MetricDescriptor_INT64,
+					MetricKind: metricpb.MetricDescriptor_GAUGE,
+				}},
+			},
+			&monitoringpb.TimeSeriesData{
+				LabelValues: []*monitoringpb.LabelValue{{Value: &monitoringpb.LabelValue_StringValue{StringValue: "value1"}}},
+				PointData: []*monitoringpb.TimeSeriesData_PointData{{
+					Values:       []*monitoringpb.TypedValue{{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: 42}}},
+					TimeInterval: &monitoringpb.TimeInterval{EndTime: &googlepb.Timestamp{Seconds: now.Unix()}},
+				}},
+			},
+		)
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 42.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if _, err := i.Value(v, []string{"value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("PromQL", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID:     project,
+			PromQL:        `{{.Name}}{key1="{{.Labels.key1}}"}`,
+			PrometheusURL: s.PrometheusURL(),
+			HTTPClient:    http.DefaultClient,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		s.SetPromQLResult(`counter0{key1="value1"}`,
+			stackdrivertest.Sample{Labels: map[string]string{"__name__": "counter0", "key1": "value1", "key2": "a"}, Time: now.Add(-time.Minute), Value: 1},
+			stackdrivertest.Sample{Labels: map[string]string{"__name__": "counter0", "key1": "value1", "key2": "b"}, Time: now, Value: 2},
+		)
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		queries := s.Queries()
+		if got, want := queries[len(queries)-1], `counter0{key1="value1"}`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("MQL and PromQL", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectID: project,
+			MQL:       "fetch global::{{.MetricType}}",
+			PromQL:    "{{.Name}}",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Value(v, []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Projects(t *testing.T) {
+	s, err := stackdrivertest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	tags := map[string]string{"key1": "value1"}
+	export(t, s, exporter.Options{ProjectID: "project-a"}, "counter0", tags, 1, now.Add(-30*time.Second))
+	export(t, s, exporter.Options{ProjectID: "project-b"}, "counter0", tags, 2, now.Add(-10*time.Second))
+	export(t, s, exporter.Options{ProjectID: "project-c"}, "counter0", tags, 3, now.Add(-20*time.Second))
+	export(t, s, exporter.Options{ProjectID: "project-b"}, "counter1", tags, 4, now.Add(-10*time.Second))
+	s.AddMonitoredProject("scope", "project-a")
+	s.AddMonitoredProject("scope", "project-c")
+
+	v := &importer_view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1"},
+	}
+	read := func(t *testing.T, o stackdriver.Options) []string {
+		o.MonitoringClientOptions = s.ClientOptions()
+		i, err := stackdriver.NewImporter(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		series, err := i.TimeSeries(context.Background(), v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects := []string{}
+		for _, ts := range series {
+			projects = append(projects, stackdriver.ProjectID(ts))
+		}
+		return projects
+	}
+	t.Run("ProjectIDs", func(t *testing.T) {
+		projects := read(t, stackdriver.Options{
+			ProjectID:  "project-a",
+			ProjectIDs: []string{"project-b", "project-a"},
+		})
+		if got, want := strings.Join(projects, ","), "project-a,project-b"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("ScopingProject", func(t *testing.T) {
+		projects := read(t, stackdriver.Options{
+			ScopingProject: "scope",
+		})
+		sort.Strings(projects)
+		if got, want := strings.Join(projects, ","), "project-a,project-c"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Newest", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectIDs:              []string{"project-a", "project-b", "project-c"},
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Unknown Project", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectIDs:              []string{"project-a", "project-x"},
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Descriptor in Second Project", func(t *testing.T) {
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectIDs:              []string{"project-a", "project-b"},
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		v := &importer_view.View{
+			Name:       "counter1",
+			LabelNames: []string{"key1"},
+		}
+		d, err := i.Descriptor(context.Background(), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d.GetName(), "projects/project-b/metricDescriptors/custom.googleapis.com/opencensus/counter1"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		got, err := i.Value(v, []string{"value1"}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 4.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("Inconsistent Descriptors", func(t *testing.T) {
+		// project-e's descriptor for counter0 doesn't have the View's label
+		metricType := "custom.googleapis.com/opencensus/counter0"
+		s.AddMetricDescriptor("project-e", &metricpb.MetricDescriptor{
+			Type:       metricType,
+			MetricKind: metricpb.MetricDescriptor_CUMULATIVE,
+			ValueType:  metricpb.MetricDescriptor_DOUBLE,
+		})
+		s.AddTimeSeries("project-e", &monitoringpb.TimeSeries{
+			Metric:   &metricpb.Metric{Type: metricType, Labels: tags},
+			Resource: &monitoredrespb.MonitoredResource{Type: "global"},
+			Points: []*monitoringpb.Point{{
+				Interval: &monitoringpb.TimeInterval{EndTime: &googlepb.Timestamp{Seconds: now.Add(-10 * time.Second).Unix()}},
+				Value:    &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: 5}},
+			}},
+		})
+		i, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectIDs:              []string{"project-a", "project-e"},
+			MonitoringClientOptions: s.ClientOptions(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer i.Close()
+		if _, err := i.Value(v, []string{"value1"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Queries", func(t *testing.T) {
+		_, err := stackdriver.NewImporter(stackdriver.Options{
+			ProjectIDs: []string{"project-a", "project-b"},
+			MQL:        "fetch global::{{.MetricType}}",
+		})
+		if err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
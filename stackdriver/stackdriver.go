@@ -3,41 +3,67 @@ package stackdriver
 import (
 	"context"
 	"errors"
-	"fmt"
+	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3"
+	monitoringv2 "cloud.google.com/go/monitoring/apiv3/v2"
+	"contrib.go.opencensus.io/exporter/stackdriver/monitoredresource"
 	"github.com/dazwilkin/opencensus/stats/view"
 	"github.com/golang/glog"
 	googlepb "github.com/golang/protobuf/ptypes/timestamp"
-	"google.golang.org/api/iterator"
-	"google.golang.org/genproto/googleapis/api/metric"
+	"google.golang.org/api/option"
 	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
 	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
 )
 
-var (
-	client    *monitoring.MetricClient
-	projectID string
-)
-
 // Importer represents the inverse of an OpenCensus Exporter
 // It gets values for measurements from the service
 // For Stackdriver, we'll use ADCs but need a robot with >= Monitoring Viewer
 type Importer struct {
-	name    string
-	options Options
+	name     string
+	options  Options
+	projects []string
+	// projectID is the first of the projects; it's used for MQL and PromQL queries
+	projectID string
+
+	clientOnce sync.Once
+	client     *monitoring.MetricClient
+	clientErr  error
+
+	querierOnce sync.Once
+	querier     *monitoringv2.QueryClient
+	querierErr  error
+
+	prometheusOnce sync.Once
+	prometheus     *http.Client
+	prometheusErr  error
+
+	mu          sync.Mutex
+	descriptors map[string]*metricpb.MetricDescriptor
 }
 
 // NewImporter creates a new importer using the Options provided
+// The ProjectID defaults to the value of environment variable PROJECT
+// The connection to Cloud Monitoring is made when the Importer is first used
 func NewImporter(o Options) (*Importer, error) {
-	if o.MetricPrefix != "" {
-		glog.Infof("[NewImporter] MetricPrefix (%s) was provided but this only affects the metric's displayed name *not* its type", o.MetricPrefix)
+	projects := projects(o, os.Getenv("PROJECT"))
+	projectID := ""
+	if len(projects) > 0 {
+		projectID = projects[0]
+	}
+	if len(projects) > 1 && (o.MQL != "" || o.PromQL != "") {
+		return nil, errors.New("MQL and PromQL queries read a single project; use a ScopingProject to query several projects")
 	}
 	return &Importer{
-		name:    "stackdriver",
-		options: o,
+		name:        "stackdriver",
+		options:     o,
+		projects:    projects,
+		projectID:   projectID,
+		descriptors: make(map[string]*metricpb.MetricDescriptor),
 	}, nil
 }
 
@@ -47,7 +73,77 @@ func (i *Importer) Name() string {
 }
 
 // Value returns the Importer's value for the View, with the label values and the time specified
+// Every time-series that matches is read and the value of the newest point across them is returned
 func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	series, err := i.TimeSeries(context.TODO(), v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	ts, p, err := newest(series)
+	if err != nil {
+		return 0.0, err
+	}
+	return getFloat64Value(ts.GetValueType(), p, i.options.DistributionField)
+}
+
+// TimeSeries returns every time-series (across all pages and projects) for the View, with the label values, in the window ending at the time specified
+// When the Importer is configured with an MQL or PromQL query template, the time-series are those returned by the query
+func (i *Importer) TimeSeries(ctx context.Context, v *view.View, labelValues []string, t time.Time) ([]*monitoringpb.TimeSeries, error) {
+	if i.options.MQL != "" || i.options.PromQL != "" {
+		return i.queryTimeSeries(ctx, v, labelValues, t)
+	}
+	req, err := i.request(v, labelValues, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the View against its MetricDescriptor for a more precise error than "No timeseries match the filter"
+	if err := i.ValidateView(ctx, v); err != nil {
+		return nil, err
+	}
+
+	series, err := i.listTimeSeries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		// There are no results
+		return nil, errors.New("No timeseries match the filter")
+	}
+	if err := i.validateSeries(ctx, v, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// queryTimeSeries returns the time-series returned by the Importer's MQL or PromQL query template
+// Queries may combine metrics so the View isn't validated against a MetricDescriptor
+func (i *Importer) queryTimeSeries(ctx context.Context, v *view.View, labelValues []string, t time.Time) ([]*monitoringpb.TimeSeries, error) {
+	if i.options.MQL != "" && i.options.PromQL != "" {
+		return nil, errors.New("Only one of MQL and PromQL may be specified")
+	}
+	data, err := i.query(v, labelValues, t)
+	if err != nil {
+		return nil, err
+	}
+	var series []*monitoringpb.TimeSeries
+	if i.options.MQL != "" {
+		series, err = i.queryMQL(ctx, data)
+	} else {
+		series, err = i.queryPromQL(ctx, data, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, errors.New("No timeseries match the query")
+	}
+	return series, nil
+}
+
+// request returns the ListTimeSeries request for the View, with the label values, in the window ending at the time specified
+// The request's Name is set for each of the projects that's read
+func (i *Importer) request(v *view.View, labelValues []string, t time.Time) (*monitoringpb.ListTimeSeriesRequest, error) {
 	// Private functions
 	createInterval := func(start, end time.Time) *monitoringpb.TimeInterval {
 		return &monitoringpb.TimeInterval{
@@ -59,96 +155,128 @@ func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float
 			},
 		}
 	}
-	getFloat64Value := func(t metric.MetricDescriptor_ValueType, p *monitoringpb.Point) (float64, error) {
-		switch t {
-		case metricpb.MetricDescriptor_DISTRIBUTION:
-			dist := p.GetValue().GetDistributionValue()
-			count := dist.GetCount()
-			mean := dist.GetMean()
-			return float64(count) * mean, nil
-		case metricpb.MetricDescriptor_DOUBLE:
-			return p.GetValue().GetDoubleValue(), nil
-		case metricpb.MetricDescriptor_INT64:
-			return float64(p.GetValue().GetInt64Value()), nil
-		default:
-			//TODO(dazwilkin) There are more types to enumerate
-			return 0.0, nil
-		}
-	}
 	mapLabelsValues := func(labels, values []string) map[string]string {
 		m := map[string]string{}
-		// Only proceed if there
-		// - are labels and values to map
-		// - is no discrepancy between the set of labels and values
-		if labels == nil && values == nil {
-			return m
-		}
-		if len(labels) != len(values) {
-			glog.Fatal("Inconsistency between labels and values")
-		}
 		for i, label := range labels {
 			m[label] = values[i]
 		}
 		return m
 	}
 
+	if len(v.LabelNames) != len(labelValues) {
+		return nil, errors.New("Inconsistency between labels and values")
+	}
+
 	f := NewFilter()
-	f.AddResourceType("global")
+	resourceType, resourceLabels := i.resource()
+	if err := f.AddResourceType(resourceType); err != nil {
+		return nil, err
+	}
+	f.AddResourceLabels(resourceLabels)
 
-	//TODO(dazwilkin) Prefixes the displayed name but not the metric type
-	f.AddMetricType(func(v *view.View) string {
-		name := v.Name
-		// if i.options.MetricPrefix != "" {
-		// 	name = i.options.MetricPrefix + "/" + name
-		// }
-		return name
-	}(v))
+	if err := f.AddMetricType(i.metricType(v)); err != nil {
+		return nil, err
+	}
 
 	// Convert Labels[],Values[]-->map(Label=Value)
 	f.AddLabels(mapLabelsValues(v.LabelNames, labelValues))
 
-	fmt.Println(f.String())
-	req := &monitoringpb.ListTimeSeriesRequest{
-		Name:     fmt.Sprintf("projects/%s", projectID),
-		Filter:   f.String(),
-		Interval: createInterval(t.Add(time.Minute*-1), t),
-	}
-	it := client.ListTimeSeries(context.TODO(), req)
-
-	// We only want the most-recent entry in the timeseries
-	resp, err := it.Next()
-	if err == iterator.Done {
-		// There are no results
-		return 0.0, errors.New("No timeseries match the filter")
-	}
+	aggregation, err := i.aggregation()
 	if err != nil {
-		// Something untoward
-		return 0.0, err
+		return nil, err
+	}
+	if i.options.PageSize < 0 {
+		return nil, errors.New("PageSize must not be negative")
 	}
 
-	// And only the most recent point from the most recent entry
-	return getFloat64Value(resp.GetValueType(), resp.Points[0])
+	glog.V(1).Info(f.String())
+	return &monitoringpb.ListTimeSeriesRequest{
+		Filter:      f.String(),
+		Interval:    createInterval(t.Add(-i.window()), t),
+		Aggregation: aggregation,
+		PageSize:    i.options.PageSize,
+	}, nil
 }
 
-// Options represents the configuration of an OpenCensus Importer
-type Options struct {
-	MetricPrefix string
+// newest returns the most recent point (and its time-series) across every time-series
+func newest(series []*monitoringpb.TimeSeries) (*monitoringpb.TimeSeries, *monitoringpb.Point, error) {
+	var (
+		result *monitoringpb.TimeSeries
+		point  *monitoringpb.Point
+	)
+	for _, ts := range series {
+		// Points are usually ordered newest first but don't depend upon it
+		for _, p := range ts.GetPoints() {
+			if point == nil || p.GetInterval().GetEndTime().AsTime().After(point.GetInterval().GetEndTime().AsTime()) {
+				result, point = ts, p
+			}
+		}
+	}
+	if point == nil {
+		return nil, nil, errors.New("No points in the timeseries")
+	}
+	return result, point, nil
 }
 
-//TODO(dazwilkin) Instead of package init should this by a type func or helper?
-func init() {
-	ctx := context.Background()
-
-	var err error
-	// Assumes Application Default Credentials
-	// Commonly credentials are provided using environment variable GOOGLE_APPLICATION_CREDENTIALS
-	client, err = monitoring.NewMetricClient(ctx)
-	if err != nil {
-		glog.Fatal(err)
+// resource returns the type and labels of the monitored resource the Importer reads from
+// As with the Stackdriver Exporter, MonitoredResource takes precedence over Resource and the default is "global"
+func (i *Importer) resource() (string, map[string]string) {
+	if i.options.MonitoredResource != nil {
+		return i.options.MonitoredResource.MonitoredResource()
 	}
-
-	projectID = os.Getenv("PROJECT")
-	if projectID == "" {
-		glog.Fatal("Google Cloud Project ID is required; specify using environment variable 'PROJECT'")
+	if i.options.Resource != nil {
+		return i.options.Resource.GetType(), i.options.Resource.GetLabels()
 	}
+	return "global", nil
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// ProjectID is the Google Cloud Project that's read; defaults to environment variable PROJECT
+	ProjectID string
+	// ProjectIDs are further projects that are read (concurrently) with ProjectID
+	// Use ProjectID to determine which project a time-series came from
+	// MQL and PromQL queries can't be used with ProjectIDs; use a ScopingProject instead
+	ProjectIDs []string
+	// ScopingProject is the scoping project of a metrics scope; when set, it's read instead of ProjectID and ProjectIDs
+	// Reads include the time-series of every project monitored by the metrics scope
+	ScopingProject string
+	// MonitoringClientOptions are passed to the Cloud Monitoring client e.g. credentials or a test server's connection
+	MonitoringClientOptions []option.ClientOption
+	// MetricPrefix, GetMetricPrefix and GetMetricType should match the Stackdriver Exporter's Options
+	// By default, metric types are "custom.googleapis.com/opencensus/" + view.Name
+	// The Exporter's ExportView (used by view.RegisterExporter) only applies GetMetricType
+	// MetricPrefix and GetMetricPrefix are only applied when ExportMetrics is true
+	MetricPrefix    string
+	GetMetricPrefix func(name string) string
+	GetMetricType   func(v *view.View) string
+	// ExportMetrics is true when the Exporter writes with ExportMetrics (or StartMetricsExporter)
+	// That path maps names to "custom.googleapis.com/opencensus/[MetricPrefix/]" + name (or GetMetricPrefix's prefix)
+	ExportMetrics bool
+	// AlignmentPeriod, Aligner, Reducer and GroupByFields aggregate the time-series that are read
+	// e.g. use ALIGN_DELTA to convert cumulative points to deltas and REDUCE_SUM to combine time-series
+	// By default, points are read without aggregation
+	AlignmentPeriod time.Duration
+	Aligner         monitoringpb.Aggregation_Aligner
+	Reducer         monitoringpb.Aggregation_Reducer
+	GroupByFields   []string
+	// PageSize is the number of time-series requested per page; all pages are read
+	// By default, Cloud Monitoring chooses the page size
+	PageSize int32
+	// MQL and PromQL are query templates that are used instead of a filter; at most one may be specified
+	// They're executed with a QueryData e.g. fetch global::{{.MetricType}} | filter metric.key1 == '{{.Labels.key1}}' | {{.Within}}
+	// PromQL queries are evaluated at the time of the read using Cloud Monitoring's Prometheus-compatible API
+	MQL    string
+	PromQL string
+	// PrometheusURL overrides the base URL of the Prometheus-compatible API
+	// By default, https://monitoring.googleapis.com/v1/projects/[ProjectID]/location/global/prometheus
+	PrometheusURL string
+	// HTTPClient is used for PromQL queries; by default, the client uses MonitoringClientOptions' credentials
+	HTTPClient *http.Client
+	// DistributionField determines which part of a DISTRIBUTION point is returned; defaults to the sum
+	DistributionField DistributionField
+	// Resource and MonitoredResource should match the Stackdriver Exporter's Options
+	// Use monitoredresource.Autodetect() to detect the resource (e.g. gce_instance, k8s_container) in the same way as the exporter
+	Resource          *monitoredrespb.MonitoredResource
+	MonitoredResource monitoredresource.Interface
 }
@@ -2,6 +2,10 @@ package stackdriver
 
 import (
 	"testing"
+
+	googlepb "github.com/golang/protobuf/ptypes/timestamp"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
 )
 
 const (
@@ -34,6 +38,96 @@ func TestView_Name(t *testing.T) {
 		t.Errorf("got %s; want %s", got, want)
 	}
 }
-func TestView_Value(t *testing.T) {
 
+// monitoredResource implements monitoredresource.Interface
+type monitoredResource struct {
+	resType string
+	labels  map[string]string
+}
+
+func (m *monitoredResource) MonitoredResource() (string, map[string]string) {
+	return m.resType, m.labels
+}
+func TestImporter_resource(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		i, _ := NewImporter(Options{})
+		resType, labels := i.resource()
+		if got, want := resType, "global"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := len(labels), 0; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Resource", func(t *testing.T) {
+		i, _ := NewImporter(Options{
+			Resource: &monitoredrespb.MonitoredResource{
+				Type: "gce_instance",
+				Labels: map[string]string{
+					"instance_id": "12345",
+				},
+			},
+		})
+		resType, labels := i.resource()
+		if got, want := resType, "gce_instance"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := labels["instance_id"], "12345"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("MonitoredResource overrides Resource", func(t *testing.T) {
+		i, _ := NewImporter(Options{
+			Resource: &monitoredrespb.MonitoredResource{
+				Type: "gce_instance",
+			},
+			MonitoredResource: &monitoredResource{
+				resType: "k8s_container",
+				labels: map[string]string{
+					"container_name": "app",
+				},
+			},
+		})
+		resType, labels := i.resource()
+		if got, want := resType, "k8s_container"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := labels["container_name"], "app"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func Test_newest(t *testing.T) {
+	point := func(seconds int64, value float64) *monitoringpb.Point {
+		return &monitoringpb.Point{
+			Interval: &monitoringpb.TimeInterval{
+				EndTime: &googlepb.Timestamp{Seconds: seconds},
+			},
+			Value: &monitoringpb.TypedValue{
+				Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: value},
+			},
+		}
+	}
+	t.Run("Across Series", func(t *testing.T) {
+		series := []*monitoringpb.TimeSeries{
+			{Points: []*monitoringpb.Point{point(20, 2), point(10, 1)}},
+			{Points: []*monitoringpb.Point{point(30, 3)}},
+			{Points: []*monitoringpb.Point{point(5, 0), point(25, 4)}},
+		}
+		ts, p, err := newest(series)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts != series[1] {
+			t.Error("got the wrong time-series")
+		}
+		if got, want := p.GetValue().GetDoubleValue(), 3.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+	})
+	t.Run("No Points", func(t *testing.T) {
+		if _, _, err := newest([]*monitoringpb.TimeSeries{{}}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
 }
@@ -0,0 +1,201 @@
+package stackdrivertest
+
+import (
+	"strings"
+	"time"
+
+	timestamppb "github.com/golang/protobuf/ptypes/timestamp"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkAggregation returns an error unless the Server supports the Aggregation
+// Only ALIGN_DELTA (of CUMULATIVE and DELTA metrics) and REDUCE_SUM are supported
+func checkAggregation(a *monitoringpb.Aggregation) error {
+	switch a.GetPerSeriesAligner() {
+	case monitoringpb.Aggregation_ALIGN_NONE, monitoringpb.Aggregation_ALIGN_DELTA:
+	default:
+		return status.Errorf(codes.Unimplemented, "Aligner %s is not supported", a.GetPerSeriesAligner())
+	}
+	switch a.GetCrossSeriesReducer() {
+	case monitoringpb.Aggregation_REDUCE_NONE, monitoringpb.Aggregation_REDUCE_SUM:
+	default:
+		return status.Errorf(codes.Unimplemented, "Reducer %s is not supported", a.GetCrossSeriesReducer())
+	}
+	if a.GetPerSeriesAligner() != monitoringpb.Aggregation_ALIGN_NONE && a.GetAlignmentPeriod().GetSeconds() <= 0 {
+		return status.Error(codes.InvalidArgument, "An Aligner requires an AlignmentPeriod")
+	}
+	if a.GetCrossSeriesReducer() != monitoringpb.Aggregation_REDUCE_NONE && a.GetPerSeriesAligner() == monitoringpb.Aggregation_ALIGN_NONE {
+		return status.Error(codes.InvalidArgument, "A Reducer requires an Aligner")
+	}
+	for _, field := range a.GetGroupByFields() {
+		if _, _, ok := groupByField(field); !ok {
+			return status.Errorf(codes.Unimplemented, "GroupByField '%s' is not supported", field)
+		}
+	}
+	return nil
+}
+
+// align returns the time-series' points as deltas over alignment periods that end at the end of the interval
+// Periods without points are omitted; the points are newest first
+func align(ts *monitoringpb.TimeSeries, period time.Duration, start, end time.Time) ([]*monitoringpb.Point, error) {
+	kind := ts.GetMetricKind()
+	if kind != metricpb.MetricDescriptor_CUMULATIVE && kind != metricpb.MetricDescriptor_DELTA {
+		return nil, status.Errorf(codes.InvalidArgument, "ALIGN_DELTA requires a CUMULATIVE or DELTA metric, got %s", kind)
+	}
+	valueType := ts.GetValueType()
+	if valueType != metricpb.MetricDescriptor_INT64 && valueType != metricpb.MetricDescriptor_DOUBLE {
+		return nil, status.Errorf(codes.Unimplemented, "ALIGN_DELTA of %s values is not supported", valueType)
+	}
+	points := ts.GetPoints()
+	result := []*monitoringpb.Point{}
+	for periodEnd := end; periodEnd.After(start); periodEnd = periodEnd.Add(-period) {
+		periodStart := periodEnd.Add(-period)
+		var (
+			delta float64
+			found bool
+		)
+		if kind == metricpb.MetricDescriptor_DELTA {
+			for _, p := range points {
+				if t := p.GetInterval().GetEndTime().AsTime(); t.After(periodStart) && !t.After(periodEnd) {
+					delta += number(p.GetValue())
+					found = true
+				}
+			}
+		} else {
+			// The change in a cumulative value is measured from the newest point before the period (or from zero)
+			var newest, before *monitoringpb.Point
+			for _, p := range points {
+				t := p.GetInterval().GetEndTime().AsTime()
+				if newest == nil && t.After(periodStart) && !t.After(periodEnd) {
+					newest = p
+				}
+				if before == nil && !t.After(periodStart) {
+					before = p
+				}
+			}
+			if newest != nil {
+				delta = number(newest.GetValue())
+				if before != nil && before.GetInterval().GetStartTime().AsTime().Equal(newest.GetInterval().GetStartTime().AsTime()) {
+					delta -= number(before.GetValue())
+				}
+				found = true
+			}
+		}
+		if found {
+			result = append(result, point(valueType, periodStart, periodEnd, delta))
+		}
+	}
+	return result, nil
+}
+
+// reduce returns the sum of the (aligned) time-series grouped by the fields
+// As with Cloud Monitoring, each result only has the labels that it's grouped by
+func reduce(series []*monitoringpb.TimeSeries, fields []string) []*monitoringpb.TimeSeries {
+	result := []*monitoringpb.TimeSeries{}
+	groups := map[string]*monitoringpb.TimeSeries{}
+	for _, ts := range series {
+		group := &monitoringpb.TimeSeries{
+			Metric: &metricpb.Metric{
+				Type:   ts.GetMetric().GetType(),
+				Labels: map[string]string{},
+			},
+			Resource: &monitoredrespb.MonitoredResource{
+				Type:   ts.GetResource().GetType(),
+				Labels: map[string]string{},
+			},
+			MetricKind: ts.GetMetricKind(),
+			ValueType:  ts.GetValueType(),
+		}
+		for _, field := range fields {
+			resource, key, _ := groupByField(field)
+			if resource {
+				if value, ok := ts.GetResource().GetLabels()[key]; ok {
+					group.Resource.Labels[key] = value
+				}
+			} else if value, ok := ts.GetMetric().GetLabels()[key]; ok {
+				group.Metric.Labels[key] = value
+			}
+		}
+		k := labels(group.Metric.Labels) + "|" + labels(group.Resource.Labels)
+		if existing, ok := groups[k]; ok {
+			group = existing
+		} else {
+			groups[k] = group
+			result = append(result, group)
+		}
+		// Aligned points have the same intervals so they're summed by end time
+		for _, p := range ts.GetPoints() {
+			var sum *monitoringpb.Point
+			for _, q := range group.Points {
+				if q.GetInterval().GetEndTime().GetSeconds() == p.GetInterval().GetEndTime().GetSeconds() {
+					sum = q
+				}
+			}
+			if sum == nil {
+				sum = proto.Clone(p).(*monitoringpb.Point)
+				group.Points = append(group.Points, sum)
+				continue
+			}
+			sum.Value = typedValue(group.GetValueType(), number(sum.GetValue())+number(p.GetValue()))
+		}
+	}
+	for _, group := range result {
+		newestFirst(group.Points)
+	}
+	return result
+}
+
+// groupByField returns whether the field is a resource (rather than metric) label and the label's key
+func groupByField(field string) (bool, string, bool) {
+	for _, prefix := range []string{"metric.label.", "metric.labels."} {
+		if key := strings.TrimPrefix(field, prefix); key != field && key != "" {
+			return false, key, true
+		}
+	}
+	for _, prefix := range []string{"resource.label.", "resource.labels."} {
+		if key := strings.TrimPrefix(field, prefix); key != field && key != "" {
+			return true, key, true
+		}
+	}
+	return false, "", false
+}
+
+// number returns an INT64 or DOUBLE value as a float64
+func number(v *monitoringpb.TypedValue) float64 {
+	switch v := v.GetValue().(type) {
+	case *monitoringpb.TypedValue_Int64Value:
+		return float64(v.Int64Value)
+	case *monitoringpb.TypedValue_DoubleValue:
+		return v.DoubleValue
+	default:
+		return 0
+	}
+}
+
+// point creates a point of the value type with the interval
+func point(valueType metricpb.MetricDescriptor_ValueType, start, end time.Time, value float64) *monitoringpb.Point {
+	return &monitoringpb.Point{
+		Interval: &monitoringpb.TimeInterval{
+			StartTime: &timestamppb.Timestamp{Seconds: start.Unix()},
+			EndTime:   &timestamppb.Timestamp{Seconds: end.Unix()},
+		},
+		Value: typedValue(valueType, value),
+	}
+}
+
+// typedValue returns the value as an INT64 or DOUBLE TypedValue
+func typedValue(valueType metricpb.MetricDescriptor_ValueType, value float64) *monitoringpb.TypedValue {
+	if valueType == metricpb.MetricDescriptor_INT64 {
+		return &monitoringpb.TypedValue{
+			Value: &monitoringpb.TypedValue_Int64Value{Int64Value: int64(value)},
+		}
+	}
+	return &monitoringpb.TypedValue{
+		Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: value},
+	}
+}
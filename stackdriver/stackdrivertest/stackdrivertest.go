@@ -0,0 +1,624 @@
+package stackdrivertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stackdriver"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/api/option"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	bufSize = 1024 * 1024
+	// defaultPageSize is used when requests don't specify a page size
+	defaultPageSize = 100
+)
+
+// Server is an in-memory stand-in for Cloud Monitoring's MetricService
+// It implements enough of the API for the Stackdriver Exporter to write to it and the Stackdriver Importer to read from it
+// Point clients at it using ClientOptions()
+type Server struct {
+	monitoringpb.UnimplementedMetricServiceServer
+	monitoringpb.UnimplementedQueryServiceServer
+
+	listener   *bufconn.Listener
+	server     *grpc.Server
+	conn       *grpc.ClientConn
+	prometheus *httptest.Server
+
+	mu          sync.Mutex
+	descriptors map[string]*metricpb.MetricDescriptor
+	series      []*series
+	requests    []*monitoringpb.ListTimeSeriesRequest
+	monitored   map[string][]string
+	mql         map[string]*monitoringpb.QueryTimeSeriesResponse
+	promql      map[string][]Sample
+	queries     []string
+}
+
+// Sample represents a PromQL result: a series' labels (including __name__) and its value at a time
+type Sample struct {
+	Labels map[string]string
+	Time   time.Time
+	Value  float64
+}
+
+// series represents a time-series written to a project; points are kept newest first
+type series struct {
+	project    string
+	timeSeries *monitoringpb.TimeSeries
+}
+
+// NewServer creates and starts a new Server with no data
+func NewServer() (*Server, error) {
+	s := &Server{
+		listener:    bufconn.Listen(bufSize),
+		server:      grpc.NewServer(),
+		descriptors: make(map[string]*metricpb.MetricDescriptor),
+		monitored:   make(map[string][]string),
+		mql:         make(map[string]*monitoringpb.QueryTimeSeriesResponse),
+		promql:      make(map[string][]Sample),
+	}
+	monitoringpb.RegisterMetricServiceServer(s.server, s)
+	monitoringpb.RegisterQueryServiceServer(s.server, s)
+	go s.server.Serve(s.listener)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query", s.handlePromQL)
+	s.prometheus = httptest.NewServer(mux)
+
+	conn, err := s.dial()
+	if err != nil {
+		s.server.Stop()
+		return nil, err
+	}
+	s.conn = conn
+	return s, nil
+}
+
+// dial creates a new client connection to the Server
+func (s *Server) dial() (*grpc.ClientConn, error) {
+	return grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+	)
+}
+
+// Conn returns a client connection to the Server
+func (s *Server) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// ClientOptions returns the options that point a Cloud Monitoring client at the Server
+// Use these as the MonitoringClientOptions (and TraceClientOptions) of the Stackdriver Exporter and Importer
+// Each call uses a new connection because closing a client closes its connection
+func (s *Server) ClientOptions() []option.ClientOption {
+	conn, err := s.dial()
+	if err != nil {
+		// Dialing is non-blocking; errors are surfaced by the client's first request
+		return nil
+	}
+	return []option.ClientOption{
+		option.WithGRPCConn(conn),
+	}
+}
+
+// PrometheusURL returns the base URL of the Server's Prometheus-compatible API
+func (s *Server) PrometheusURL() string {
+	return s.prometheus.URL
+}
+
+// Close shuts down the Server
+func (s *Server) Close() {
+	s.conn.Close()
+	s.server.Stop()
+	s.prometheus.Close()
+}
+
+// Queries returns the MQL and PromQL queries the Server has received
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.queries...)
+}
+
+// SetMQLResult seeds the Server with the result of an MQL query
+// The Server doesn't interpret MQL; queries without a result fail with InvalidArgument
+func (s *Server) SetMQLResult(query string, d *monitoringpb.TimeSeriesDescriptor, data ...*monitoringpb.TimeSeriesData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mql[query] = &monitoringpb.QueryTimeSeriesResponse{
+		TimeSeriesDescriptor: d,
+		TimeSeriesData:       data,
+	}
+}
+
+// SetPromQLResult seeds the Server with the (vector) result of a PromQL query
+// The Server doesn't interpret PromQL; queries without a result fail with "bad_data"
+func (s *Server) SetPromQLResult(query string, samples ...Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promql[query] = samples
+}
+
+// QueryTimeSeries implements QueryServiceServer using the results seeded by SetMQLResult
+func (s *Server) QueryTimeSeries(ctx context.Context, req *monitoringpb.QueryTimeSeriesRequest) (*monitoringpb.QueryTimeSeriesResponse, error) {
+	if _, err := parseProject(req.GetName()); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, req.GetQuery())
+	resp, ok := s.mql[req.GetQuery()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unexpected query '%s'", req.GetQuery())
+	}
+	first, last, next, err := page(len(resp.GetTimeSeriesData()), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringpb.QueryTimeSeriesResponse{
+		TimeSeriesDescriptor: proto.Clone(resp.GetTimeSeriesDescriptor()).(*monitoringpb.TimeSeriesDescriptor),
+		TimeSeriesData:       resp.GetTimeSeriesData()[first:last],
+		NextPageToken:        next,
+	}, nil
+}
+
+// handlePromQL implements GET|POST /api/v1/query?query=[query]&time=[s] using the results seeded by SetPromQLResult
+func (s *Server) handlePromQL(w http.ResponseWriter, r *http.Request) {
+	query := r.FormValue("query")
+	w.Header().Set("Content-Type", "application/json")
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	samples, ok := s.promql[query]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":    "error",
+			"errorType": "bad_data",
+			"error":     fmt.Sprintf("Unexpected query '%s'", query),
+		})
+		return
+	}
+	type result struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]interface{}    `json:"value"`
+	}
+	results := []result{}
+	for _, sample := range samples {
+		results = append(results, result{
+			Metric: sample.Labels,
+			Value: [2]interface{}{
+				float64(sample.Time.UnixNano()) / 1e9,
+				strconv.FormatFloat(sample.Value, 'f', -1, 64),
+			},
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result":     results,
+		},
+	})
+}
+
+// Requests returns the ListTimeSeries requests the Server has received
+func (s *Server) Requests() []*monitoringpb.ListTimeSeriesRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*monitoringpb.ListTimeSeriesRequest{}, s.requests...)
+}
+
+// AddMetricDescriptor seeds the Server with a MetricDescriptor in the project
+func (s *Server) AddMetricDescriptor(project string, d *metricpb.MetricDescriptor) {
+	d = proto.Clone(d).(*metricpb.MetricDescriptor)
+	d.Name = descriptorName(project, d.Type)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.descriptors[d.Name] = d
+}
+
+// AddTimeSeries seeds the Server with a time-series (and its points) in the project
+func (s *Server) AddTimeSeries(project string, ts *monitoringpb.TimeSeries) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(project, ts)
+}
+
+// CreateMetricDescriptor implements MetricServiceServer
+func (s *Server) CreateMetricDescriptor(ctx context.Context, req *monitoringpb.CreateMetricDescriptorRequest) (*metricpb.MetricDescriptor, error) {
+	project, err := parseProject(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	d := req.GetMetricDescriptor()
+	if d.GetType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "MetricDescriptor requires a type")
+	}
+	s.AddMetricDescriptor(project, d)
+	return s.GetMetricDescriptor(ctx, &monitoringpb.GetMetricDescriptorRequest{
+		Name: descriptorName(project, d.GetType()),
+	})
+}
+
+// GetMetricDescriptor implements MetricServiceServer
+func (s *Server) GetMetricDescriptor(ctx context.Context, req *monitoringpb.GetMetricDescriptorRequest) (*metricpb.MetricDescriptor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.descriptors[req.GetName()]
+	if !ok {
+		// A scoping project's descriptors include those of its monitored projects
+		for _, project := range s.monitored[projectOf(req.GetName())] {
+			if d, ok = s.descriptors[descriptorName(project, metricTypeOf(req.GetName()))]; ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Could not find descriptor '%s'", req.GetName())
+	}
+	return proto.Clone(d).(*metricpb.MetricDescriptor), nil
+}
+
+// AddMonitoredProject adds a project to the metrics scope of the scoping project
+// Time-series and MetricDescriptors in the monitored project may then be read through the scoping project
+func (s *Server) AddMonitoredProject(scopingProject, project string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.monitored[scopingProject] = append(s.monitored[scopingProject], project)
+}
+
+// scoped returns true if the time-series' project is the project that's read or one that it monitors
+// The Server's lock must be held
+func (s *Server) scoped(project, seriesProject string) bool {
+	if project == seriesProject {
+		return true
+	}
+	for _, p := range s.monitored[project] {
+		if p == seriesProject {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMetricDescriptors implements MetricServiceServer; only metric.type may be used in the filter
+func (s *Server) ListMetricDescriptors(ctx context.Context, req *monitoringpb.ListMetricDescriptorsRequest) (*monitoringpb.ListMetricDescriptorsResponse, error) {
+	project, err := parseProject(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	var e stackdriver.Expr
+	if req.GetFilter() != "" {
+		if e, err = stackdriver.Parse(req.GetFilter()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	descriptors := []*metricpb.MetricDescriptor{}
+	for name, d := range s.descriptors {
+		if !strings.HasPrefix(name, "projects/"+project+"/") {
+			continue
+		}
+		if e != nil {
+			ok, err := stackdriver.Match(e, func(sel stackdriver.Selector) (string, bool) {
+				if sel.Object == stackdriver.MetricType {
+					return d.GetType(), true
+				}
+				return "", false
+			})
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			if !ok {
+				continue
+			}
+		}
+		descriptors = append(descriptors, proto.Clone(d).(*metricpb.MetricDescriptor))
+	}
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].GetType() < descriptors[j].GetType()
+	})
+	start, end, next, err := page(len(descriptors), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringpb.ListMetricDescriptorsResponse{
+		MetricDescriptors: descriptors[start:end],
+		NextPageToken:     next,
+	}, nil
+}
+
+// CreateTimeSeries implements MetricServiceServer
+// As with Cloud Monitoring, writing to a metric type without a descriptor creates one
+func (s *Server) CreateTimeSeries(ctx context.Context, req *monitoringpb.CreateTimeSeriesRequest) (*empty.Empty, error) {
+	project, err := parseProject(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	for _, ts := range req.GetTimeSeries() {
+		if ts.GetMetric().GetType() == "" {
+			return nil, status.Error(codes.InvalidArgument, "TimeSeries requires a metric type")
+		}
+		if len(ts.GetPoints()) != 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "TimeSeries must contain exactly one point, got %d", len(ts.GetPoints()))
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ts := range req.GetTimeSeries() {
+		s.add(project, ts)
+	}
+	return &empty.Empty{}, nil
+}
+
+// ListTimeSeries implements MetricServiceServer
+// Time-series are filtered and their points restricted to the interval
+// Of the aggregations, only ALIGN_DELTA and REDUCE_SUM (optionally grouped by metric or resource labels) are supported
+func (s *Server) ListTimeSeries(ctx context.Context, req *monitoringpb.ListTimeSeriesRequest) (*monitoringpb.ListTimeSeriesResponse, error) {
+	project, err := parseProject(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	e, err := stackdriver.Parse(req.GetFilter())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetInterval().GetEndTime() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Interval requires an end time")
+	}
+	a := req.GetAggregation()
+	if err := checkAggregation(a); err != nil {
+		return nil, err
+	}
+	end := req.GetInterval().GetEndTime().AsTime()
+	start := end
+	if req.GetInterval().GetStartTime() != nil {
+		start = req.GetInterval().GetStartTime().AsTime()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	result := []*monitoringpb.TimeSeries{}
+	for _, ss := range s.series {
+		if !s.scoped(project, ss.project) {
+			continue
+		}
+		ok, err := stackdriver.Match(e, ss.lookup)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if !ok {
+			continue
+		}
+		ts := proto.Clone(ss.timeSeries).(*monitoringpb.TimeSeries)
+		ts.Points = nil
+		// As with Cloud Monitoring, the time-series identifies its project
+		if ts.Resource == nil {
+			ts.Resource = &monitoredrespb.MonitoredResource{}
+		}
+		if ts.Resource.Labels == nil {
+			ts.Resource.Labels = map[string]string{}
+		}
+		ts.Resource.Labels["project_id"] = ss.project
+		if a.GetPerSeriesAligner() == monitoringpb.Aggregation_ALIGN_DELTA {
+			period := time.Duration(a.GetAlignmentPeriod().GetSeconds()) * time.Second
+			if ts.Points, err = align(ss.timeSeries, period, start, end); err != nil {
+				return nil, err
+			}
+			ts.MetricKind = metricpb.MetricDescriptor_DELTA
+		} else {
+			for _, p := range ss.timeSeries.GetPoints() {
+				if t := p.GetInterval().GetEndTime().AsTime(); !t.Before(start) && !t.After(end) {
+					ts.Points = append(ts.Points, proto.Clone(p).(*monitoringpb.Point))
+				}
+			}
+		}
+		if len(ts.Points) == 0 {
+			continue
+		}
+		result = append(result, ts)
+	}
+	if a.GetCrossSeriesReducer() == monitoringpb.Aggregation_REDUCE_SUM {
+		result = reduce(result, a.GetGroupByFields())
+	}
+	if req.GetView() == monitoringpb.ListTimeSeriesRequest_HEADERS {
+		for _, ts := range result {
+			ts.Points = nil
+		}
+	}
+	first, last, next, err := page(len(result), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringpb.ListTimeSeriesResponse{
+		TimeSeries:    result[first:last],
+		NextPageToken: next,
+	}, nil
+}
+
+// add appends the time-series' points to the matching series (creating it and its descriptor if necessary)
+// The Server's lock must be held
+func (s *Server) add(project string, ts *monitoringpb.TimeSeries) {
+	ts = proto.Clone(ts).(*monitoringpb.TimeSeries)
+	name := descriptorName(project, ts.GetMetric().GetType())
+	d, ok := s.descriptors[name]
+	if !ok {
+		d = &metricpb.MetricDescriptor{
+			Name:       name,
+			Type:       ts.GetMetric().GetType(),
+			MetricKind: ts.GetMetricKind(),
+			ValueType:  ts.GetValueType(),
+		}
+		if d.MetricKind == metricpb.MetricDescriptor_METRIC_KIND_UNSPECIFIED {
+			d.MetricKind = metricpb.MetricDescriptor_GAUGE
+		}
+		if d.ValueType == metricpb.MetricDescriptor_VALUE_TYPE_UNSPECIFIED && len(ts.GetPoints()) > 0 {
+			d.ValueType = valueType(ts.GetPoints()[0].GetValue())
+		}
+		s.descriptors[name] = d
+	}
+	// Cloud Monitoring returns the descriptor's kind and value type with every time-series
+	ts.MetricKind = d.GetMetricKind()
+	ts.ValueType = d.GetValueType()
+
+	k := key(project, ts)
+	for _, existing := range s.series {
+		if key(existing.project, existing.timeSeries) == k {
+			existing.timeSeries.Points = append(existing.timeSeries.Points, ts.GetPoints()...)
+			newestFirst(existing.timeSeries.Points)
+			return
+		}
+	}
+	newestFirst(ts.Points)
+	s.series = append(s.series, &series{
+		project:    project,
+		timeSeries: ts,
+	})
+}
+
+// lookup returns the value of a filter selector for the series
+func (s *series) lookup(sel stackdriver.Selector) (string, bool) {
+	switch sel.Object {
+	case stackdriver.Project:
+		return s.project, true
+	case stackdriver.ResourceType:
+		return s.timeSeries.GetResource().GetType(), true
+	case stackdriver.ResourceLabels:
+		value, ok := s.timeSeries.GetResource().GetLabels()[sel.Key]
+		return value, ok
+	case stackdriver.MetricType:
+		return s.timeSeries.GetMetric().GetType(), true
+	case stackdriver.MetricLabels:
+		value, ok := s.timeSeries.GetMetric().GetLabels()[sel.Key]
+		return value, ok
+	case stackdriver.UserLabels:
+		value, ok := s.timeSeries.GetMetadata().GetUserLabels()[sel.Key]
+		return value, ok
+	default:
+		return "", false
+	}
+}
+
+// key returns a string that identifies a time-series by its project, metric and resource
+func key(project string, ts *monitoringpb.TimeSeries) string {
+	return strings.Join([]string{
+		project,
+		ts.GetMetric().GetType(),
+		labels(ts.GetMetric().GetLabels()),
+		ts.GetResource().GetType(),
+		labels(ts.GetResource().GetLabels()),
+	}, "|")
+}
+
+// labels returns the labels in a canonical (sorted) form
+func labels(m map[string]string) string {
+	ss := make([]string, 0, len(m))
+	for key, value := range m {
+		ss = append(ss, key+"="+strconv.Quote(value))
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, ",")
+}
+
+// newestFirst sorts the points by their end time, most recent first
+func newestFirst(points []*monitoringpb.Point) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].GetInterval().GetEndTime().AsTime().After(points[j].GetInterval().GetEndTime().AsTime())
+	})
+}
+
+// valueType returns the value type of a point's value
+func valueType(v *monitoringpb.TypedValue) metricpb.MetricDescriptor_ValueType {
+	switch v.GetValue().(type) {
+	case *monitoringpb.TypedValue_BoolValue:
+		return metricpb.MetricDescriptor_BOOL
+	case *monitoringpb.TypedValue_Int64Value:
+		return metricpb.MetricDescriptor_INT64
+	case *monitoringpb.TypedValue_DoubleValue:
+		return metricpb.MetricDescriptor_DOUBLE
+	case *monitoringpb.TypedValue_StringValue:
+		return metricpb.MetricDescriptor_STRING
+	case *monitoringpb.TypedValue_DistributionValue:
+		return metricpb.MetricDescriptor_DISTRIBUTION
+	default:
+		return metricpb.MetricDescriptor_VALUE_TYPE_UNSPECIFIED
+	}
+}
+
+// parseProject returns the project ID from a "projects/[PROJECT_ID]" name
+func parseProject(name string) (string, error) {
+	project := strings.TrimPrefix(name, "projects/")
+	if project == name || project == "" || strings.Contains(project, "/") {
+		return "", status.Errorf(codes.InvalidArgument, "Name must be of the form 'projects/[PROJECT_ID]', got '%s'", name)
+	}
+	return project, nil
+}
+
+// projectOf returns the project ID from a "projects/[PROJECT_ID]/metricDescriptors/[TYPE]" name
+func projectOf(name string) string {
+	parts := strings.SplitN(name, "/", 4)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// metricTypeOf returns the metric type from a "projects/[PROJECT_ID]/metricDescriptors/[TYPE]" name
+func metricTypeOf(name string) string {
+	parts := strings.SplitN(name, "/", 4)
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+// descriptorName returns the name of the metric type's descriptor in the project
+func descriptorName(project, metricType string) string {
+	return fmt.Sprintf("projects/%s/metricDescriptors/%s", project, metricType)
+}
+
+// page returns the range of results for the page and the token for the next page (if any)
+// Page tokens are the offset of the page's first result
+func page(n int, size int32, token string) (int, int, string, error) {
+	start := 0
+	if token != "" {
+		var err error
+		if start, err = strconv.Atoi(token); err != nil || start < 0 || start > n {
+			return 0, 0, "", status.Errorf(codes.InvalidArgument, "Invalid page token '%s'", token)
+		}
+	}
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	end := start + int(size)
+	if end >= n {
+		return start, n, "", nil
+	}
+	return start, end, strconv.Itoa(end), nil
+}
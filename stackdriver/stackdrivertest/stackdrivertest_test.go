@@ -0,0 +1,296 @@
+package stackdrivertest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	durationpb "github.com/golang/protobuf/ptypes/duration"
+	timestamppb "github.com/golang/protobuf/ptypes/timestamp"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoredrespb "google.golang.org/genproto/googleapis/api/monitoredres"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	project    = "freddie"
+	metricType = "custom.googleapis.com/opencensus/counter0"
+)
+
+// timeSeries creates a global time-series for the metric with one DOUBLE point ending at t
+func timeSeries(labels map[string]string, t time.Time, value float64) *monitoringpb.TimeSeries {
+	return &monitoringpb.TimeSeries{
+		Metric: &metricpb.Metric{
+			Type:   metricType,
+			Labels: labels,
+		},
+		Resource: &monitoredrespb.MonitoredResource{
+			Type: "global",
+		},
+		Points: []*monitoringpb.Point{{
+			Interval: &monitoringpb.TimeInterval{
+				EndTime: &timestamppb.Timestamp{Seconds: t.Unix()},
+			},
+			Value: &monitoringpb.TypedValue{
+				Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: value},
+			},
+		}},
+	}
+}
+func newServer(t *testing.T) (*Server, monitoringpb.MetricServiceClient) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, monitoringpb.NewMetricServiceClient(s.Conn())
+}
+func TestServer_MetricDescriptor(t *testing.T) {
+	s, client := newServer(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := client.GetMetricDescriptor(ctx, &monitoringpb.GetMetricDescriptorRequest{
+			Name: descriptorName(project, metricType),
+		})
+		if got, want := status.Code(err), codes.NotFound; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Create", func(t *testing.T) {
+		_, err := client.CreateMetricDescriptor(ctx, &monitoringpb.CreateMetricDescriptorRequest{
+			Name: "projects/" + project,
+			MetricDescriptor: &metricpb.MetricDescriptor{
+				Type:       metricType,
+				MetricKind: metricpb.MetricDescriptor_CUMULATIVE,
+				ValueType:  metricpb.MetricDescriptor_DOUBLE,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := client.GetMetricDescriptor(ctx, &monitoringpb.GetMetricDescriptorRequest{
+			Name: descriptorName(project, metricType),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d.GetMetricKind(), metricpb.MetricDescriptor_CUMULATIVE; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("List", func(t *testing.T) {
+		resp, err := client.ListMetricDescriptors(ctx, &monitoringpb.ListMetricDescriptorsRequest{
+			Name:   "projects/" + project,
+			Filter: `metric.type=starts_with("custom.googleapis.com/opencensus/")`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(resp.GetMetricDescriptors()), 1; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+}
+func TestServer_TimeSeries(t *testing.T) {
+	s, client := newServer(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	labels := map[string]string{"key1": "value1"}
+	for j, value := range []float64{1, 2, 3} {
+		_, err := client.CreateTimeSeries(ctx, &monitoringpb.CreateTimeSeriesRequest{
+			Name:       "projects/" + project,
+			TimeSeries: []*monitoringpb.TimeSeries{timeSeries(labels, now.Add(time.Duration(j-3)*time.Minute), value)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.AddTimeSeries(project, timeSeries(map[string]string{"key1": "value2"}, now, 4))
+
+	list := func(filter string, start time.Time) *monitoringpb.ListTimeSeriesResponse {
+		resp, err := client.ListTimeSeries(ctx, &monitoringpb.ListTimeSeriesRequest{
+			Name:   "projects/" + project,
+			Filter: filter,
+			Interval: &monitoringpb.TimeInterval{
+				StartTime: &timestamppb.Timestamp{Seconds: start.Unix()},
+				EndTime:   &timestamppb.Timestamp{Seconds: now.Unix()},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	t.Run("Newest First", func(t *testing.T) {
+		resp := list(`metric.type="`+metricType+`" AND metric.labels.key1="value1"`, now.Add(-time.Hour))
+		if got, want := len(resp.GetTimeSeries()), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		ts := resp.GetTimeSeries()[0]
+		if got, want := len(ts.GetPoints()), 3; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		if got, want := ts.GetPoints()[0].GetValue().GetDoubleValue(), 3.0; got != want {
+			t.Errorf("got %f; want %f", got, want)
+		}
+		if got, want := ts.GetValueType(), metricpb.MetricDescriptor_DOUBLE; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Interval", func(t *testing.T) {
+		resp := list(`metric.type="`+metricType+`" AND metric.labels.key1="value1"`, now.Add(-90*time.Second))
+		if got, want := len(resp.GetTimeSeries()[0].GetPoints()), 1; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Filter", func(t *testing.T) {
+		resp := list(`metric.type="`+metricType+`"`, now.Add(-time.Hour))
+		if got, want := len(resp.GetTimeSeries()), 2; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		resp = list(`metric.type="`+metricType+`" AND resource.type="gce_instance"`, now.Add(-time.Hour))
+		if got, want := len(resp.GetTimeSeries()), 0; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Invalid Filter", func(t *testing.T) {
+		_, err := client.ListTimeSeries(ctx, &monitoringpb.ListTimeSeriesRequest{
+			Name:     "projects/" + project,
+			Filter:   `metric.type=`,
+			Interval: &monitoringpb.TimeInterval{EndTime: &timestamppb.Timestamp{Seconds: now.Unix()}},
+		})
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func TestServer_Aggregation(t *testing.T) {
+	s, client := newServer(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	s.AddMetricDescriptor(project, &metricpb.MetricDescriptor{
+		Type:       metricType,
+		MetricKind: metricpb.MetricDescriptor_CUMULATIVE,
+		ValueType:  metricpb.MetricDescriptor_DOUBLE,
+	})
+	for _, labelValue := range []string{"value1", "value2"} {
+		for j, value := range []float64{10, 12, 15} {
+			ts := timeSeries(map[string]string{"key1": labelValue}, now.Add(time.Duration(60*j-150)*time.Second), value)
+			if labelValue == "value2" {
+				ts.Points[0].Value.Value = &monitoringpb.TypedValue_DoubleValue{DoubleValue: value / 5}
+			}
+			// Cumulative points share the time their counts started
+			ts.Points[0].Interval.StartTime = &timestamppb.Timestamp{Seconds: now.Add(-time.Hour).Unix()}
+			s.AddTimeSeries(project, ts)
+		}
+	}
+
+	list := func(a *monitoringpb.Aggregation) (*monitoringpb.ListTimeSeriesResponse, error) {
+		return client.ListTimeSeries(ctx, &monitoringpb.ListTimeSeriesRequest{
+			Name:   "projects/" + project,
+			Filter: `metric.type="` + metricType + `"`,
+			Interval: &monitoringpb.TimeInterval{
+				StartTime: &timestamppb.Timestamp{Seconds: now.Add(-2 * time.Minute).Unix()},
+				EndTime:   &timestamppb.Timestamp{Seconds: now.Unix()},
+			},
+			Aggregation: a,
+		})
+	}
+	values := func(ts *monitoringpb.TimeSeries) []float64 {
+		result := []float64{}
+		for _, p := range ts.GetPoints() {
+			result = append(result, p.GetValue().GetDoubleValue())
+		}
+		return result
+	}
+	period := &durationpb.Duration{Seconds: 60}
+	t.Run("ALIGN_DELTA", func(t *testing.T) {
+		resp, err := list(&monitoringpb.Aggregation{
+			AlignmentPeriod:  period,
+			PerSeriesAligner: monitoringpb.Aggregation_ALIGN_DELTA,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(resp.GetTimeSeries()), 2; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		ts := resp.GetTimeSeries()[0]
+		if got, want := values(ts), []float64{3, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got, want := ts.GetMetricKind(), metricpb.MetricDescriptor_DELTA; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("REDUCE_SUM", func(t *testing.T) {
+		resp, err := list(&monitoringpb.Aggregation{
+			AlignmentPeriod:    period,
+			PerSeriesAligner:   monitoringpb.Aggregation_ALIGN_DELTA,
+			CrossSeriesReducer: monitoringpb.Aggregation_REDUCE_SUM,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(resp.GetTimeSeries()), 1; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+		ts := resp.GetTimeSeries()[0]
+		if got, want := values(ts), []float64{3.6, 2.4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+		if got := ts.GetMetric().GetLabels(); len(got) != 0 {
+			t.Errorf("got %v; want no labels", got)
+		}
+	})
+	t.Run("GroupByFields", func(t *testing.T) {
+		resp, err := list(&monitoringpb.Aggregation{
+			AlignmentPeriod:    period,
+			PerSeriesAligner:   monitoringpb.Aggregation_ALIGN_DELTA,
+			CrossSeriesReducer: monitoringpb.Aggregation_REDUCE_SUM,
+			GroupByFields:      []string{"metric.label.key1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(resp.GetTimeSeries()), 2; got != want {
+			t.Fatalf("got %d; want %d", got, want)
+		}
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := list(&monitoringpb.Aggregation{
+			AlignmentPeriod:  period,
+			PerSeriesAligner: monitoringpb.Aggregation_ALIGN_MEAN,
+		})
+		if got, want := status.Code(err), codes.Unimplemented; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
+func Test_page(t *testing.T) {
+	start, end, next, err := page(5, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 0 || end != 2 || next != "2" {
+		t.Errorf("got %d,%d,%s; want 0,2,2", start, end, next)
+	}
+	start, end, next, err = page(5, 2, "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 4 || end != 5 || next != "" {
+		t.Errorf("got %d,%d,%s; want 4,5,", start, end, next)
+	}
+	if _, _, _, err := page(5, 2, "X"); err == nil {
+		t.Errorf("got nil; want error")
+	}
+}
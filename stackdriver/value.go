@@ -0,0 +1,109 @@
+package stackdriver
+
+import (
+	"fmt"
+	"strconv"
+
+	distributionpb "google.golang.org/genproto/googleapis/api/distribution"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+// DistributionField determines which part of a DISTRIBUTION point is returned
+type DistributionField int
+
+// DistributionFields
+const (
+	// DistributionSum returns count * mean (the default)
+	DistributionSum DistributionField = iota
+	DistributionCount
+	DistributionMean
+	DistributionSumOfSquaredDeviation
+	// DistributionMin and DistributionMax require the distribution to include its range
+	DistributionMin
+	DistributionMax
+)
+
+// String returns the DistributionField's name
+func (f DistributionField) String() string {
+	switch f {
+	case DistributionSum:
+		return "sum"
+	case DistributionCount:
+		return "count"
+	case DistributionMean:
+		return "mean"
+	case DistributionSumOfSquaredDeviation:
+		return "sum_of_squared_deviation"
+	case DistributionMin:
+		return "min"
+	case DistributionMax:
+		return "max"
+	default:
+		return "unknown"
+	}
+}
+
+// ValueTypeError is returned when a point's value (or any value of a descriptor's type) can't be represented as a float64
+type ValueTypeError struct {
+	ValueType metricpb.MetricDescriptor_ValueType
+	Reason    string
+}
+
+// Error returns the value type and the reason it can't be represented
+func (e *ValueTypeError) Error() string {
+	return fmt.Sprintf("Unable to represent value type %s as float64: %s", e.ValueType, e.Reason)
+}
+
+// getFloat64Value returns the point's value as a float64
+// BOOL values are 1.0 (true) or 0.0 (false); STRING values must be numeric
+func getFloat64Value(t metricpb.MetricDescriptor_ValueType, p *monitoringpb.Point, field DistributionField) (float64, error) {
+	switch t {
+	case metricpb.MetricDescriptor_BOOL:
+		if p.GetValue().GetBoolValue() {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case metricpb.MetricDescriptor_INT64:
+		return float64(p.GetValue().GetInt64Value()), nil
+	case metricpb.MetricDescriptor_DOUBLE:
+		return p.GetValue().GetDoubleValue(), nil
+	case metricpb.MetricDescriptor_STRING:
+		s := p.GetValue().GetStringValue()
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0.0, &ValueTypeError{t, fmt.Sprintf("'%s' is not numeric", s)}
+		}
+		return f, nil
+	case metricpb.MetricDescriptor_DISTRIBUTION:
+		return getDistributionValue(p.GetValue().GetDistributionValue(), field)
+	case metricpb.MetricDescriptor_MONEY:
+		return 0.0, &ValueTypeError{t, "points do not carry MONEY values"}
+	default:
+		return 0.0, &ValueTypeError{t, "unknown value type"}
+	}
+}
+
+// getDistributionValue returns the field of the distribution
+func getDistributionValue(dist *distributionpb.Distribution, field DistributionField) (float64, error) {
+	switch field {
+	case DistributionSum:
+		return float64(dist.GetCount()) * dist.GetMean(), nil
+	case DistributionCount:
+		return float64(dist.GetCount()), nil
+	case DistributionMean:
+		return dist.GetMean(), nil
+	case DistributionSumOfSquaredDeviation:
+		return dist.GetSumOfSquaredDeviation(), nil
+	case DistributionMin, DistributionMax:
+		if dist.GetRange() == nil {
+			return 0.0, &ValueTypeError{metricpb.MetricDescriptor_DISTRIBUTION, fmt.Sprintf("distribution has no range for '%s'", field)}
+		}
+		if field == DistributionMin {
+			return dist.GetRange().GetMin(), nil
+		}
+		return dist.GetRange().GetMax(), nil
+	default:
+		return 0.0, &ValueTypeError{metricpb.MetricDescriptor_DISTRIBUTION, fmt.Sprintf("unknown distribution field %d", field)}
+	}
+}
@@ -0,0 +1,80 @@
+package stackdriver
+
+import (
+	"testing"
+
+	distributionpb "google.golang.org/genproto/googleapis/api/distribution"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func Test_getFloat64Value(t *testing.T) {
+	point := func(v *monitoringpb.TypedValue) *monitoringpb.Point {
+		return &monitoringpb.Point{Value: v}
+	}
+	dist := point(&monitoringpb.TypedValue{
+		Value: &monitoringpb.TypedValue_DistributionValue{
+			DistributionValue: &distributionpb.Distribution{
+				Count:                 4,
+				Mean:                  2.5,
+				SumOfSquaredDeviation: 5.0,
+				Range: &distributionpb.Distribution_Range{
+					Min: 1.0,
+					Max: 4.0,
+				},
+			},
+		},
+	})
+	for _, test := range []struct {
+		name      string
+		valueType metricpb.MetricDescriptor_ValueType
+		p         *monitoringpb.Point
+		field     DistributionField
+		want      float64
+	}{
+		{"BOOL true", metricpb.MetricDescriptor_BOOL, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_BoolValue{BoolValue: true}}), DistributionSum, 1.0},
+		{"BOOL false", metricpb.MetricDescriptor_BOOL, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_BoolValue{BoolValue: false}}), DistributionSum, 0.0},
+		{"INT64", metricpb.MetricDescriptor_INT64, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: 42}}), DistributionSum, 42.0},
+		{"DOUBLE", metricpb.MetricDescriptor_DOUBLE, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: 1.5}}), DistributionSum, 1.5},
+		{"STRING", metricpb.MetricDescriptor_STRING, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_StringValue{StringValue: "2.5"}}), DistributionSum, 2.5},
+		{"DISTRIBUTION sum", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionSum, 10.0},
+		{"DISTRIBUTION count", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionCount, 4.0},
+		{"DISTRIBUTION mean", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionMean, 2.5},
+		{"DISTRIBUTION sum_of_squared_deviation", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionSumOfSquaredDeviation, 5.0},
+		{"DISTRIBUTION min", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionMin, 1.0},
+		{"DISTRIBUTION max", metricpb.MetricDescriptor_DISTRIBUTION, dist, DistributionMax, 4.0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := getFloat64Value(test.valueType, test.p, test.field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %f; want %f", got, test.want)
+			}
+		})
+	}
+	t.Run("Errors", func(t *testing.T) {
+		noRange := point(&monitoringpb.TypedValue{
+			Value: &monitoringpb.TypedValue_DistributionValue{
+				DistributionValue: &distributionpb.Distribution{Count: 1},
+			},
+		})
+		for _, test := range []struct {
+			name      string
+			valueType metricpb.MetricDescriptor_ValueType
+			p         *monitoringpb.Point
+			field     DistributionField
+		}{
+			{"Non-numeric STRING", metricpb.MetricDescriptor_STRING, point(&monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_StringValue{StringValue: "X"}}), DistributionSum},
+			{"MONEY", metricpb.MetricDescriptor_MONEY, point(&monitoringpb.TypedValue{}), DistributionSum},
+			{"Unspecified", metricpb.MetricDescriptor_VALUE_TYPE_UNSPECIFIED, point(&monitoringpb.TypedValue{}), DistributionSum},
+			{"DISTRIBUTION without Range", metricpb.MetricDescriptor_DISTRIBUTION, noRange, DistributionMax},
+		} {
+			_, err := getFloat64Value(test.valueType, test.p, test.field)
+			if _, ok := err.(*ValueTypeError); !ok {
+				t.Errorf("%s got %v; want *ValueTypeError", test.name, err)
+			}
+		}
+	})
+}
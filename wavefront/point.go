@@ -0,0 +1,30 @@
+package wavefront
+
+import (
+	"math"
+	"time"
+)
+
+// Point represents a series' value at a time
+// Source and Tags identify the series; they're empty when an Aggregate combines series
+type Point struct {
+	Time   time.Time
+	Value  float64
+	Source string
+	Tags   map[string]string
+}
+
+// Points returns the Timeseries' data points
+func (ts Timeseries) Points() []Point {
+	points := make([]Point, 0, len(ts.Data))
+	for _, d := range ts.Data {
+		sec, frac := math.Modf(d[0])
+		points = append(points, Point{
+			Time:   time.Unix(int64(sec), int64(frac*float64(time.Second))),
+			Value:  d[1],
+			Source: ts.Host,
+			Tags:   ts.Tags,
+		})
+	}
+	return points
+}
@@ -0,0 +1,137 @@
+package wavefront
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TS represents a ts() expression that selects the series of a metric with a source and point tags
+type TS struct {
+	Metric string
+	Source string
+	Tags   [][2]string
+}
+
+// NewTS creates a TS for the metric; the name is sanitized as the Wavefront SDKs do
+func NewTS(metric string) *TS {
+	return &TS{
+		Metric: Sanitize(metric),
+	}
+}
+
+// SetSource restricts the TS to the source; an empty source matches every source
+func (ts *TS) SetSource(source string) {
+	ts.Source = source
+}
+
+// AddTag restricts the TS to series with the point tag; the key is sanitized as the Wavefront SDKs do
+func (ts *TS) AddTag(key, value string) {
+	ts.Tags = append(ts.Tags, [2]string{Sanitize(key), value})
+}
+
+// String returns the TS as a Wavefront Query Language expression e.g. ts("counter0", source="host" and key1="value1")
+func (ts *TS) String() string {
+	filters := []string{}
+	if ts.Source != "" {
+		filters = append(filters, "source="+Quote(ts.Source))
+	}
+	for _, tag := range ts.Tags {
+		filters = append(filters, tag[0]+"="+Quote(tag[1]))
+	}
+	if len(filters) == 0 {
+		return fmt.Sprintf("ts(%s)", Quote(ts.Metric))
+	}
+	return fmt.Sprintf("ts(%s, %s)", Quote(ts.Metric), strings.Join(filters, " and "))
+}
+
+// Quote returns the string as a double-quoted literal; wildcards (*) can't be escaped so they match as wildcards
+func Quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Sanitize replaces the characters that aren't permitted in metric names and point tag keys with "-"
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// Response represents the chart API's response
+type Response struct {
+	Query        string       `json:"query"`
+	Name         string       `json:"name"`
+	Timeseries   []Timeseries `json:"timeseries"`
+	Warnings     string       `json:"warnings"`
+	ErrorType    string       `json:"errorType"`
+	ErrorMessage string       `json:"errorMessage"`
+}
+
+// Timeseries represents a series in the chart API's response
+// Data are [timestamp, value] pairs with timestamps in seconds
+type Timeseries struct {
+	Label string            `json:"label"`
+	Host  string            `json:"host"`
+	Tags  map[string]string `json:"tags"`
+	Data  [][2]float64      `json:"data"`
+}
+
+// errorResponse represents the API's error response
+type errorResponse struct {
+	Status struct {
+		Result  string `json:"result"`
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"status"`
+}
+
+// chart sends the query to the chart API for the window between the times specified and returns the points of every series
+func (i *Importer) chart(q string, start, end time.Time) ([]Point, error) {
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("s", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("e", strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("g", i.options.Granularity)
+	params.Set("summarization", string(i.options.Summarization))
+	// Drop points outside the window rather than including the adjacent ones that charts use to draw lines
+	params.Set("strict", "true")
+	req, err := http.NewRequest(http.MethodGet, i.options.URL+"/api/v2/chart/api?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+i.options.Token)
+	resp, err := i.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e); err != nil || e.Status.Message == "" {
+			return nil, fmt.Errorf("Query failed (%s)", resp.Status)
+		}
+		return nil, fmt.Errorf("Query failed (%s): %s", resp.Status, e.Status.Message)
+	}
+	var r Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("Unable to decode response: %s", err)
+	}
+	if r.ErrorMessage != "" {
+		return nil, fmt.Errorf("Query failed: %s", r.ErrorMessage)
+	}
+	points := []Point{}
+	for _, ts := range r.Timeseries {
+		points = append(points, ts.Points()...)
+	}
+	return points, nil
+}
@@ -0,0 +1,51 @@
+package wavefront
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+)
+
+func TestTS_String(t *testing.T) {
+	ts := NewTS("counter0")
+	if got, want := ts.String(), `ts("counter0")`; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	ts.SetSource("host")
+	ts.AddTag("key 1", `say "hello"`)
+	if got, want := ts.String(), `ts("counter0", source="host" and key-1="say \"hello\"")`; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+func Test_Sanitize(t *testing.T) {
+	for _, test := range []struct {
+		s, want string
+	}{
+		{"opencensus.io/http/server/latency", "opencensus.io-http-server-latency"},
+		{"counter_0-total", "counter_0-total"},
+		{"bytes (in)", "bytes--in-"},
+	} {
+		if got := Sanitize(test.s); got != test.want {
+			t.Errorf("got %s; want %s", got, test.want)
+		}
+	}
+}
+func TestTimeseries_Points(t *testing.T) {
+	ts := Timeseries{
+		Label: "counter0",
+		Host:  "host",
+		Data:  [][2]float64{{1792411200, 1}, {1792411200.5, 2}},
+	}
+	points := ts.Points()
+	if got, want := len(points), 2; got != want {
+		t.Fatalf("got %d; want %d", got, want)
+	}
+	p := points[newest.Index(len(points), func(j int) time.Time { return points[j].Time })]
+	if want := time.Unix(1792411200, 500000000); !p.Time.Equal(want) {
+		t.Errorf("got %s; want %s", p.Time, want)
+	}
+	if got, want := p.Source, "host"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
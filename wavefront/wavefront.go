@@ -0,0 +1,187 @@
+package wavefront
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/newest"
+	"github.com/dazwilkin/opencensus/stats/view"
+)
+
+// Summarization determines how the chart API reduces the points in each granularity bucket to a value
+type Summarization string
+
+// Summarizations
+const (
+	Mean   Summarization = "MEAN"
+	Median Summarization = "MEDIAN"
+	Min    Summarization = "MIN"
+	Max    Summarization = "MAX"
+	Sum    Summarization = "SUM"
+	Count  Summarization = "COUNT"
+	Last   Summarization = "LAST"
+	First  Summarization = "FIRST"
+)
+
+const (
+	// defaultGranularity is the smallest bucket so that points are (mostly) returned unsummarized
+	defaultGranularity   = "s"
+	defaultSummarization = Last
+	defaultWindow        = 5 * time.Minute
+)
+
+// aggregates are the Wavefront Query Language functions that combine series e.g. those from different sources
+var aggregates = map[string]bool{
+	"sum": true, "avg": true, "min": true, "max": true, "count": true,
+	"rawsum": true, "rawavg": true, "rawmin": true, "rawmax": true, "rawcount": true,
+}
+
+// Importer represents the inverse of an OpenCensus Exporter
+// It gets values for measurements from Wavefront's (VMware Aria Operations for Applications) chart API
+// A View is a metric and its label names are point tags; series are further distinguished by their source
+type Importer struct {
+	name    string
+	options Options
+}
+
+// NewImporter creates a new importer using the Options provided
+// The URL and token default to the values of environment variables WAVEFRONT_URL and WAVEFRONT_TOKEN
+func NewImporter(o Options) (*Importer, error) {
+	if o.URL == "" {
+		o.URL = os.Getenv("WAVEFRONT_URL")
+	}
+	if o.Token == "" {
+		o.Token = os.Getenv("WAVEFRONT_TOKEN")
+	}
+	if o.URL == "" || o.Token == "" {
+		return nil, errors.New("Expect a Wavefront URL and an API token")
+	}
+	o.URL = strings.TrimSuffix(o.URL, "/")
+	switch o.Granularity {
+	case "":
+		o.Granularity = defaultGranularity
+	case "s", "m", "h", "d":
+	default:
+		return nil, fmt.Errorf("Unknown Granularity '%s'; expect one of s, m, h or d", o.Granularity)
+	}
+	switch o.Summarization {
+	case "":
+		o.Summarization = defaultSummarization
+	case Mean, Median, Min, Max, Sum, Count, Last, First:
+	default:
+		return nil, fmt.Errorf("Unknown Summarization '%s'", o.Summarization)
+	}
+	if o.Aggregate != "" && !aggregates[o.Aggregate] {
+		return nil, fmt.Errorf("Unknown Aggregate '%s'", o.Aggregate)
+	}
+	return &Importer{
+		name:    "wavefront",
+		options: o,
+	}, nil
+}
+
+// Name returns the Importer's name
+func (i *Importer) Name() string {
+	return i.name
+}
+
+// Value returns the Importer's value for the View, with the label values and the time specified
+func (i *Importer) Value(v *view.View, labelValues []string, t time.Time) (float64, error) {
+	p, err := i.Point(v, labelValues, t)
+	if err != nil {
+		return 0.0, err
+	}
+	return p.Value, nil
+}
+
+// Point returns the most recent Point in the window ending at the time specified for the View with the label values
+// When Source isn't set, series from every source match and the most recent of their points is returned
+func (i *Importer) Point(v *view.View, labelValues []string, t time.Time) (Point, error) {
+	q, err := i.Query(v, labelValues)
+	if err != nil {
+		return Point{}, err
+	}
+	window := i.options.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+	log.Println(q)
+	points, err := i.chart(q, t.Add(-window), t)
+	if err != nil {
+		return Point{}, err
+	}
+	j := newest.Index(len(points), func(j int) time.Time { return points[j].Time })
+	if j < 0 {
+		return Point{}, errors.New("No data points match the query")
+	}
+	return points[j], nil
+}
+
+// Query returns the Wavefront Query Language expression for the View with the label values
+// e.g. ts("counter0", source="host" and key1="value1")
+// Label values can't include "*"; it's a wildcard in the Query Language and can't be escaped
+func (i *Importer) Query(v *view.View, labelValues []string) (string, error) {
+	if len(v.LabelNames) != len(labelValues) {
+		return "", errors.New("Inconsistency between labels and values")
+	}
+	ts := NewTS(i.options.MetricPrefix + v.Name)
+	ts.SetSource(i.options.Source)
+	for j, labelName := range v.LabelNames {
+		if strings.Contains(labelValues[j], "*") {
+			return "", fmt.Errorf("Label value '%s' includes '*' which would match as a wildcard", labelValues[j])
+		}
+		ts.AddTag(labelName, labelValues[j])
+	}
+	// The exporter adds its application tags to every point
+	keys := make([]string, 0, len(i.options.Tags))
+	for k := range i.options.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ts.AddTag(k, i.options.Tags[k])
+	}
+	if i.options.Aggregate != "" {
+		return fmt.Sprintf("%s(%s)", i.options.Aggregate, ts), nil
+	}
+	return ts.String(), nil
+}
+
+// client returns the HTTP client used to make requests
+func (i *Importer) client() *http.Client {
+	if i.options.HTTPClient != nil {
+		return i.options.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Options represents the configuration of an OpenCensus Importer
+type Options struct {
+	// URL is the cluster's base URL (e.g. https://example.wavefront.com); defaults to environment variable WAVEFRONT_URL
+	URL string
+	// Token is an API token; defaults to environment variable WAVEFRONT_TOKEN
+	Token string
+	// MetricPrefix is prepended to the View's name to give the metric
+	MetricPrefix string
+	// Source restricts the query to the series of a source (the exporter's Source, often its hostname); defaults to any source
+	// Source and Tags values may include wildcards (*), unlike label values
+	Source string
+	// Tags are point tags that the exporter adds to every point e.g. application and service tags
+	Tags map[string]string
+	// Aggregate (e.g. sum or rawsum) combines the series that match the View's tags e.g. those from different sources
+	Aggregate string
+	// Granularity (s, m, h or d) is the size of the buckets the chart API returns; defaults to s
+	Granularity string
+	// Summarization reduces the points in each bucket to a value; defaults to Last
+	Summarization Summarization
+	// Window is the duration (ending at the time of the read) that's queried; defaults to 5 minutes
+	Window time.Duration
+	// HTTPClient overrides the HTTP client used to make requests
+	HTTPClient *http.Client
+}
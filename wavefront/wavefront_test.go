@@ -0,0 +1,246 @@
+package wavefront
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dazwilkin/opencensus/stats/view"
+	"github.com/dazwilkin/opencensus/wavefront/wavefronttest"
+)
+
+const token = "token"
+
+// newTestImporter creates an Importer that talks to a wavefronttest.Server
+func newTestImporter(t *testing.T, s *wavefronttest.Server, o Options) *Importer {
+	o.URL = s.URL()
+	o.Token = token
+	o.HTTPClient = s.Client()
+	i, err := NewImporter(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_NewImporter(t *testing.T) {
+	os.Unsetenv("WAVEFRONT_URL")
+	os.Unsetenv("WAVEFRONT_TOKEN")
+	t.Run("Defaults", func(t *testing.T) {
+		i, err := NewImporter(Options{URL: "https://example.wavefront.com/", Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := i.Name(), "wavefront"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.URL, "https://example.wavefront.com"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Granularity, "s"; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+		if got, want := i.options.Summarization, Last; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	t.Run("Environment", func(t *testing.T) {
+		os.Setenv("WAVEFRONT_URL", "https://example.wavefront.com")
+		os.Setenv("WAVEFRONT_TOKEN", token)
+		defer os.Unsetenv("WAVEFRONT_URL")
+		defer os.Unsetenv("WAVEFRONT_TOKEN")
+		if _, err := NewImporter(Options{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, test := range []struct {
+		name    string
+		options Options
+	}{
+		{"No URL", Options{Token: token}},
+		{"No Token", Options{URL: "https://example.wavefront.com"}},
+		{"Unknown Granularity", Options{URL: "https://example.wavefront.com", Token: token, Granularity: "w"}},
+		{"Unknown Summarization", Options{URL: "https://example.wavefront.com", Token: token, Summarization: "AVG"}},
+		{"Unknown Aggregate", Options{URL: "https://example.wavefront.com", Token: token, Aggregate: "median"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewImporter(test.options); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+}
+func TestImporter_Query(t *testing.T) {
+	v := &view.View{
+		Name:       "opencensus/counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"Default", Options{}, `ts("opencensus-counter0", key1="value1" and key2="value2")`},
+		{"Source", Options{Source: "host"}, `ts("opencensus-counter0", source="host" and key1="value1" and key2="value2")`},
+		{"Tags", Options{Tags: map[string]string{"service": "s", "application": "a"}}, `ts("opencensus-counter0", key1="value1" and key2="value2" and application="a" and service="s")`},
+		{"Aggregate", Options{MetricPrefix: "app.", Aggregate: "rawsum"}, `rawsum(ts("app.opencensus-counter0", key1="value1" and key2="value2"))`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.options.URL, test.options.Token = "https://example.wavefront.com", token
+			i, err := NewImporter(test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := i.Query(v, []string{"value1", "value2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %s; want %s", got, test.want)
+			}
+		})
+	}
+	t.Run("Wildcard", func(t *testing.T) {
+		i, err := NewImporter(Options{URL: "https://example.wavefront.com", Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Query(v, []string{"value*", "value2"}); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+}
+func TestImporter_Value(t *testing.T) {
+	s := wavefronttest.NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398240, 0)
+	chart := func(query string) wavefronttest.Chart {
+		return wavefronttest.Chart{
+			Query:         query,
+			Start:         now.Add(-5 * time.Minute),
+			End:           now,
+			Granularity:   "s",
+			Summarization: "LAST",
+		}
+	}
+	tags := map[string]string{"key1": "value1", "key2": "value2"}
+	v := &view.View{
+		Name:       "counter0",
+		LabelNames: []string{"key1", "key2"},
+	}
+	for _, test := range []struct {
+		name    string
+		options Options
+		chart   wavefronttest.Chart
+		series  []wavefronttest.Timeseries
+		want    Point
+	}{
+		{
+			"Newest",
+			Options{},
+			chart(`ts("counter0", key1="value1" and key2="value2")`),
+			[]wavefronttest.Timeseries{
+				{Label: "counter0", Host: "a", Tags: tags, Points: []wavefronttest.Point{
+					{Time: now.Add(-30 * time.Second), Value: 2},
+					{Time: now.Add(-20 * time.Second), Value: 4},
+				}},
+				{Label: "counter0", Host: "b", Tags: tags, Points: []wavefronttest.Point{
+					{Time: now.Add(-25 * time.Second), Value: 8},
+				}},
+			},
+			Point{Time: now.Add(-20 * time.Second), Value: 4, Source: "a", Tags: tags},
+		},
+		{
+			"Source",
+			Options{Source: "b"},
+			chart(`ts("counter0", source="b" and key1="value1" and key2="value2")`),
+			[]wavefronttest.Timeseries{
+				{Label: "counter0", Host: "b", Tags: tags, Points: []wavefronttest.Point{
+					{Time: now.Add(-25 * time.Second), Value: 8},
+				}},
+			},
+			Point{Time: now.Add(-25 * time.Second), Value: 8, Source: "b", Tags: tags},
+		},
+		{
+			"Aggregate",
+			Options{Aggregate: "rawsum", Tags: map[string]string{"application": "app"}},
+			chart(`rawsum(ts("counter0", key1="value1" and key2="value2" and application="app"))`),
+			[]wavefronttest.Timeseries{
+				{Label: "counter0", Points: []wavefronttest.Point{
+					{Time: now.Add(-20 * time.Second), Value: 140},
+				}},
+			},
+			Point{Time: now.Add(-20 * time.Second), Value: 140},
+		},
+		{
+			"Summarization",
+			Options{MetricPrefix: "prefix.", Granularity: "m", Summarization: Sum, Window: time.Minute},
+			wavefronttest.Chart{
+				Query:         `ts("prefix.counter0", key1="value1" and key2="value2")`,
+				Start:         now.Add(-time.Minute),
+				End:           now,
+				Granularity:   "m",
+				Summarization: "SUM",
+			},
+			[]wavefronttest.Timeseries{
+				{Label: "prefix.counter0", Host: "a", Tags: tags, Points: []wavefronttest.Point{
+					{Time: now.Add(-time.Minute), Value: 6},
+				}},
+			},
+			Point{Time: now.Add(-time.Minute), Value: 6, Source: "a", Tags: tags},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s.SetResult(test.chart, test.series...)
+			i := newTestImporter(t, s, test.options)
+			got, err := i.Point(v, []string{"value1", "value2"}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Time.Equal(test.want.Time) || got.Value != test.want.Value || got.Source != test.want.Source || len(got.Tags) != len(test.want.Tags) {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+			queries := s.Queries()
+			if got, want := queries[len(queries)-1], test.chart.String(); got != want {
+				t.Errorf("got %s; want %s", got, want)
+			}
+		})
+	}
+	s.SetResult(chart(`ts("counter0", key1="value1" and key2="value9")`))
+	for _, test := range []struct {
+		name        string
+		options     Options
+		labelValues []string
+	}{
+		{"No Data Points", Options{}, []string{"value1", "value9"}},
+		{"Unexpected Query", Options{MetricPrefix: "unknown."}, []string{"value1", "value2"}},
+		{"Label Mismatch", Options{}, []string{"value1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			i := newTestImporter(t, s, test.options)
+			if _, err := i.Value(v, test.labelValues, now); err == nil {
+				t.Errorf("got nil; want error")
+			}
+		})
+	}
+	t.Run("Server Error", func(t *testing.T) {
+		s.SetStatus(http.StatusServiceUnavailable)
+		defer s.SetStatus(http.StatusOK)
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err == nil {
+			t.Errorf("got nil; want error")
+		}
+	})
+	t.Run("Token", func(t *testing.T) {
+		i := newTestImporter(t, s, Options{})
+		if _, err := i.Value(v, []string{"value1", "value2"}, now); err != nil {
+			t.Fatal(err)
+		}
+		headers := s.Headers()
+		if got, want := headers[len(headers)-1].Get("Authorization"), "Bearer "+token; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+}
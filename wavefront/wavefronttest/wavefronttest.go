@@ -0,0 +1,107 @@
+package wavefronttest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dazwilkin/opencensus/internal/standin"
+)
+
+// Server is a stand-in for Wavefront's chart API (/api/v2/chart/api)
+// It returns the series seeded for each exact Chart request
+type Server struct {
+	*standin.Server
+}
+
+// NewServer creates and starts a new Server with no series
+func NewServer() *Server {
+	return &Server{standin.NewServer(chart)}
+}
+
+// Chart represents a chart API request: a query for the window between Start and End
+// with buckets of the Granularity summarized using the Summarization
+type Chart struct {
+	Query         string
+	Start         time.Time
+	End           time.Time
+	Granularity   string
+	Summarization string
+}
+
+// String returns the Chart's text e.g. ts("counter0") s=1546397945000 e=1546398245000 g=s summarization=LAST
+func (c Chart) String() string {
+	return fmt.Sprintf("%s s=%d e=%d g=%s summarization=%s",
+		c.Query,
+		c.Start.UnixNano()/int64(time.Millisecond),
+		c.End.UnixNano()/int64(time.Millisecond),
+		c.Granularity,
+		c.Summarization,
+	)
+}
+
+// chart returns the text of a chart API request; only strict requests (without points outside the window) are supported
+func chart(r *http.Request) (string, error) {
+	if r.URL.Path != "/api/v2/chart/api" {
+		return "", fmt.Errorf("Path '%s' not found", r.URL.Path)
+	}
+	params := r.URL.Query()
+	if params.Get("q") == "" {
+		return "", errors.New("Missing required parameter 'q'")
+	}
+	if params.Get("strict") != "true" {
+		return "", errors.New("Expect strict=true")
+	}
+	return fmt.Sprintf("%s s=%s e=%s g=%s summarization=%s",
+		params.Get("q"),
+		params.Get("s"),
+		params.Get("e"),
+		params.Get("g"),
+		params.Get("summarization"),
+	), nil
+}
+
+// Point represents a value at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Timeseries represents a series of the chart API's response
+// Host and Tags are empty when an aggregate combines series
+type Timeseries struct {
+	Label  string
+	Host   string
+	Tags   map[string]string
+	Points []Point
+}
+
+// SetResult seeds the series that are returned for the Chart
+func (s *Server) SetResult(c Chart, series ...Timeseries) {
+	type timeseries struct {
+		Label string            `json:"label"`
+		Host  string            `json:"host,omitempty"`
+		Tags  map[string]string `json:"tags,omitempty"`
+		Data  [][2]float64      `json:"data"`
+	}
+	result := struct {
+		Query      string       `json:"query"`
+		Name       string       `json:"name"`
+		Timeseries []timeseries `json:"timeseries"`
+	}{
+		Query:      c.Query,
+		Name:       c.Query,
+		Timeseries: []timeseries{},
+	}
+	for _, ts := range series {
+		data := [][2]float64{}
+		for _, p := range ts.Points {
+			data = append(data, [2]float64{float64(p.Time.Unix()), p.Value})
+		}
+		result.Timeseries = append(result.Timeseries, timeseries{ts.Label, ts.Host, ts.Tags, data})
+	}
+	b, _ := json.Marshal(result)
+	s.SetResponse(c.String(), "application/json", string(b))
+}
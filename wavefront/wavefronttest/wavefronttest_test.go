@@ -0,0 +1,79 @@
+package wavefronttest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_SetResult(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	now := time.Unix(1546398245, 0)
+	c := Chart{
+		Query:         `ts("counter0")`,
+		Start:         now.Add(-time.Minute),
+		End:           now,
+		Granularity:   "s",
+		Summarization: "LAST",
+	}
+	s.SetResult(c, Timeseries{
+		Label:  "counter0",
+		Host:   "a",
+		Tags:   map[string]string{"key1": "value1"},
+		Points: []Point{{now, 1.5}},
+	})
+	get := func(q, strict string) (int, string) {
+		params := url.Values{
+			"q":             {q},
+			"s":             {"1546398185000"},
+			"e":             {"1546398245000"},
+			"g":             {"s"},
+			"summarization": {"LAST"},
+			"strict":        {strict},
+		}
+		resp, err := s.Client().Get(s.URL() + "/api/v2/chart/api?" + params.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(b)
+	}
+	t.Run("Result", func(t *testing.T) {
+		code, body := get(`ts("counter0")`, "true")
+		if got, want := code, http.StatusOK; got != want {
+			t.Errorf("got %d; want %d", got, want)
+		}
+		if got, want := body, `{"query":"ts(\"counter0\")","name":"ts(\"counter0\")","timeseries":[{"label":"counter0","host":"a","tags":{"key1":"value1"},"data":[[1546398245,1.5]]}]}`; got != want {
+			t.Errorf("got %s; want %s", got, want)
+		}
+	})
+	for _, test := range []struct {
+		name   string
+		q      string
+		strict string
+	}{
+		{"Unexpected Query", `ts("counter1")`, "true"},
+		{"Not Strict", `ts("counter0")`, "false"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code, _ := get(test.q, test.strict)
+			if got, want := code, http.StatusBadRequest; got != want {
+				t.Errorf("got %d; want %d", got, want)
+			}
+		})
+	}
+	// Requests that aren't strict are rejected before they're recorded
+	want := c.String() + "\n" + `ts("counter1") s=1546398185000 e=1546398245000 g=s summarization=LAST`
+	if got := strings.Join(s.Queries(), "\n"); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}